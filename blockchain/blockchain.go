@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -756,6 +757,12 @@ func receiptByBlockNumberAndIndex(txn db.Transaction, bnIndex *txAndReceiptDBKey
 
 type StateCloser = func() error
 
+// discardOnce returns a StateCloser that discards txn on its first invocation only. Later invocations
+// return the result of the first one, so closing a state more than once is safe.
+func discardOnce(txn db.Transaction) StateCloser {
+	return sync.OnceValue(txn.Discard)
+}
+
 // HeadState returns a StateReader that provides a stable view to the latest state
 func (b *Blockchain) HeadState() (core.StateReader, StateCloser, error) {
 	b.listener.OnRead("HeadState")
@@ -769,7 +776,7 @@ func (b *Blockchain) HeadState() (core.StateReader, StateCloser, error) {
 		return nil, nil, utils.RunAndWrapOnError(txn.Discard, err)
 	}
 
-	return core.NewState(txn), txn.Discard, nil
+	return core.NewState(txn), discardOnce(txn), nil
 }
 
 // StateAtBlockNumber returns a StateReader that provides a stable view to the state at the given block number
@@ -785,7 +792,7 @@ func (b *Blockchain) StateAtBlockNumber(blockNumber uint64) (core.StateReader, S
 		return nil, nil, utils.RunAndWrapOnError(txn.Discard, err)
 	}
 
-	return core.NewStateSnapshot(core.NewState(txn), blockNumber), txn.Discard, nil
+	return core.NewStateSnapshot(core.NewState(txn), blockNumber), discardOnce(txn), nil
 }
 
 // StateAtBlockHash returns a StateReader that provides a stable view to the state at the given block hash
@@ -794,7 +801,7 @@ func (b *Blockchain) StateAtBlockHash(blockHash *felt.Felt) (core.StateReader, S
 	if blockHash.IsZero() {
 		txn := db.NewMemTransaction()
 		emptyState := core.NewState(txn)
-		return emptyState, txn.Discard, nil
+		return emptyState, discardOnce(txn), nil
 	}
 
 	txn, err := b.database.NewTransaction(false)
@@ -807,7 +814,7 @@ func (b *Blockchain) StateAtBlockHash(blockHash *felt.Felt) (core.StateReader, S
 		return nil, nil, utils.RunAndWrapOnError(txn.Discard, err)
 	}
 
-	return core.NewStateSnapshot(core.NewState(txn), header.Number), txn.Discard, nil
+	return core.NewStateSnapshot(core.NewState(txn), header.Number), discardOnce(txn), nil
 }
 
 // EventFilter returns an EventFilter object that is tied to a snapshot of the blockchain
@@ -1054,7 +1061,7 @@ func (b *Blockchain) PendingState() (core.StateReader, StateCloser, error) {
 		pending.StateUpdate.StateDiff,
 		pending.NewClasses,
 		core.NewState(txn),
-	), txn.Discard, nil
+	), discardOnce(txn), nil
 }
 
 func MakeStateDiffForEmptyBlock(bc Reader, blockNumber uint64) (*core.StateDiff, error) {
@@ -418,6 +418,44 @@ func TestState(t *testing.T) {
 	})
 }
 
+type discardCountingDB struct {
+	db.DB
+	discards int
+}
+
+func (d *discardCountingDB) NewTransaction(update bool) (db.Transaction, error) {
+	txn, err := d.DB.NewTransaction(update)
+	if err != nil {
+		return nil, err
+	}
+	return &discardCountingTxn{Transaction: txn, db: d}, nil
+}
+
+type discardCountingTxn struct {
+	db.Transaction
+	db *discardCountingDB
+}
+
+func (t *discardCountingTxn) Discard() error {
+	t.db.discards++
+	return t.Transaction.Discard()
+}
+
+func TestStateCloserIsIdempotent(t *testing.T) {
+	testDB := &discardCountingDB{DB: pebble.NewMemTest(t)}
+	require.NoError(t, testDB.DB.Update(func(txn db.Transaction) error {
+		return txn.Set(db.ChainHeight.Key(), core.MarshalBlockNumber(0))
+	}))
+	chain := blockchain.New(testDB, &utils.Mainnet)
+
+	_, closer, err := chain.HeadState()
+	require.NoError(t, err)
+
+	require.NoError(t, closer())
+	require.NoError(t, closer())
+	assert.Equal(t, 1, testDB.discards)
+}
+
 func TestEvents(t *testing.T) {
 	testDB := pebble.NewMemTest(t)
 	chain := blockchain.New(testDB, &utils.Goerli2)
@@ -55,6 +55,37 @@ func (p *PendingState) ContractStorage(addr, key *felt.Felt) (*felt.Felt, error)
 	return p.head.ContractStorage(addr, key)
 }
 
+func (p *PendingState) ContractStorageValues(addr *felt.Felt, keys []*felt.Felt) ([]*felt.Felt, error) {
+	_, deployed := p.stateDiff.DeployedContracts[*addr]
+	diffs := p.stateDiff.StorageDiffs[*addr]
+
+	values := make([]*felt.Felt, len(keys))
+	// keys that are not in the pending diff are read from the head state in one go
+	var headKeys []*felt.Felt
+	var headIndices []int
+	for i, key := range keys {
+		if value, found := diffs[*key]; found {
+			values[i] = value
+		} else if deployed {
+			values[i] = &felt.Felt{}
+		} else {
+			headKeys = append(headKeys, key)
+			headIndices = append(headIndices, i)
+		}
+	}
+
+	if len(headKeys) > 0 {
+		headValues, err := p.head.ContractStorageValues(addr, headKeys)
+		if err != nil {
+			return nil, err
+		}
+		for i, value := range headValues {
+			values[headIndices[i]] = value
+		}
+	}
+	return values, nil
+}
+
 func (p *PendingState) Class(classHash *felt.Felt) (*core.DeclaredClass, error) {
 	if class, found := p.newClasses[*classHash]; found {
 		return &core.DeclaredClass{
@@ -128,6 +128,33 @@ func TestPendingState(t *testing.T) {
 			assert.Equal(t, expectedValue, cV)
 		})
 	})
+	t.Run("ContractStorageValues", func(t *testing.T) {
+		t.Run("deployed in pending", func(t *testing.T) {
+			cV, cErr := state.ContractStorageValues(deployedAddr,
+				[]*felt.Felt{new(felt.Felt).SetUint64(0xDEADBEEF), new(felt.Felt).SetUint64(44)})
+			require.NoError(t, cErr)
+			assert.Equal(t, []*felt.Felt{&felt.Zero, new(felt.Felt).SetUint64(37)}, cV)
+		})
+		t.Run("from pending and head", func(t *testing.T) {
+			pendingKey := new(felt.Felt).SetUint64(1)
+			headKeys := []*felt.Felt{new(felt.Felt).SetUint64(2), new(felt.Felt).SetUint64(3)}
+			stateWithDiff := blockchain.NewPendingState(&core.StateDiff{
+				StorageDiffs: map[felt.Felt]map[felt.Felt]*felt.Felt{
+					*replacedAddr: {*pendingKey: new(felt.Felt).SetUint64(10)},
+				},
+			}, nil, mockState)
+
+			// keys missing from the pending diff are read from head in a single batch
+			mockState.EXPECT().ContractStorageValues(replacedAddr, headKeys).
+				Return([]*felt.Felt{new(felt.Felt).SetUint64(20), new(felt.Felt).SetUint64(30)}, nil)
+
+			cV, cErr := stateWithDiff.ContractStorageValues(replacedAddr, []*felt.Felt{headKeys[0], pendingKey, headKeys[1]})
+			require.NoError(t, cErr)
+			assert.Equal(t, []*felt.Felt{
+				new(felt.Felt).SetUint64(20), new(felt.Felt).SetUint64(10), new(felt.Felt).SetUint64(30),
+			}, cV)
+		})
+	})
 	t.Run("Class", func(t *testing.T) {
 		t.Run("from pending", func(t *testing.T) {
 			pC, pErr := state.Class(deployedClassHash)
@@ -82,6 +82,8 @@ const (
 	cnCoreContractAddressF = "cn-core-contract-address"
 	cnUnverifiableRangeF   = "cn-unverifiable-range"
 	callMaxStepsF          = "rpc-call-max-steps"
+	rpcMaxBatchSizeF       = "rpc-max-batch-size"
+	rpcMaxOpenStatesF      = "rpc-max-open-states"
 	corsEnableF            = "rpc-cors-enable"
 
 	defaultConfig                   = ""
@@ -116,6 +118,8 @@ const (
 	defaultCNL2ChainID              = ""
 	defaultCNCoreContractAddressStr = ""
 	defaultCallMaxSteps             = 4_000_000
+	defaultRPCMaxBatchSize          = 1000
+	defaultRPCMaxOpenStates         = 256
 	defaultGwTimeout                = 5 * time.Second
 	defaultCorsEnable               = false
 
@@ -165,6 +169,8 @@ const (
 	gwAPIKeyUsage        = "API key for gateway endpoints to avoid throttling" //nolint: gosec
 	gwTimeoutUsage       = "Timeout for requests made to the gateway"          //nolint: gosec
 	callMaxStepsUsage    = "Maximum number of steps to be executed in starknet_call requests"
+	rpcMaxBatchSizeUsage = "Maximum number of entries accepted by a single juno_ batch RPC request, 0 means no limit"
+	maxOpenStatesUsage   = "Maximum number of state readers held open by RPC requests at once, 0 means no limit"
 	corsEnableUsage      = "Enable CORS on RPC endpoints"
 )
 
@@ -265,6 +271,9 @@ func NewCmd(config *node.Config, run func(*cobra.Command, []string) error) *cobr
 			if len(unverifRange) != 2 || unverifRange[0] < 0 || unverifRange[1] < 0 {
 				return fmt.Errorf("invalid %s:%v, must be uint array of length 2 (e.g. `0,100`)", cnUnverifiableRangeF, unverifRange)
 			}
+			if err := utils.ValidateL2ChainID(v.GetString(cnL2ChainIDF)); err != nil {
+				return fmt.Errorf("invalid %s: %w", cnL2ChainIDF, err)
+			}
 
 			config.Network = utils.Network{
 				Name:                v.GetString(cnNameF),
@@ -343,6 +352,8 @@ func NewCmd(config *node.Config, run func(*cobra.Command, []string) error) *cobr
 	junoCmd.MarkFlagsRequiredTogether(cnNameF, cnFeederURLF, cnGatewayURLF, cnL1ChainIDF, cnL2ChainIDF, cnCoreContractAddressF, cnUnverifiableRangeF) //nolint:lll
 	junoCmd.MarkFlagsMutuallyExclusive(networkF, cnNameF)
 	junoCmd.Flags().Uint(callMaxStepsF, defaultCallMaxSteps, callMaxStepsUsage)
+	junoCmd.Flags().Uint(rpcMaxBatchSizeF, defaultRPCMaxBatchSize, rpcMaxBatchSizeUsage)
+	junoCmd.Flags().Uint(rpcMaxOpenStatesF, defaultRPCMaxOpenStates, maxOpenStatesUsage)
 	junoCmd.Flags().Duration(gwTimeoutF, defaultGwTimeout, gwTimeoutUsage)
 	junoCmd.Flags().Bool(corsEnableF, defaultCorsEnable, corsEnableUsage)
 	junoCmd.MarkFlagsMutuallyExclusive(p2pFeederNodeF, p2pPeersF)
@@ -64,6 +64,8 @@ func TestConfigPrecedence(t *testing.T) {
 	defaultMaxCacheSize := uint(8)
 	defaultMaxHandles := 1024
 	defaultCallMaxSteps := uint(4_000_000)
+	defaultRPCMaxBatchSize := uint(1000)
+	defaultRPCMaxOpenStates := uint(256)
 	defaultGwTimeout := 5 * time.Second
 
 	tests := map[string]struct {
@@ -110,6 +112,8 @@ func TestConfigPrecedence(t *testing.T) {
 				DBCacheSize:         defaultMaxCacheSize,
 				DBMaxHandles:        defaultMaxHandles,
 				RPCCallMaxSteps:     defaultCallMaxSteps,
+				RPCMaxBatchSize:     defaultRPCMaxBatchSize,
+				RPCMaxOpenStates:    defaultRPCMaxOpenStates,
 				GatewayTimeout:      defaultGwTimeout,
 			},
 		},
@@ -155,6 +159,8 @@ cn-unverifiable-range: [0,10]
 				DBCacheSize:         defaultMaxCacheSize,
 				DBMaxHandles:        defaultMaxHandles,
 				RPCCallMaxSteps:     defaultCallMaxSteps,
+				RPCMaxBatchSize:     defaultRPCMaxBatchSize,
+				RPCMaxOpenStates:    defaultRPCMaxOpenStates,
 				GatewayTimeout:      defaultGwTimeout,
 			},
 		},
@@ -187,6 +193,8 @@ cn-unverifiable-range: [0,10]
 				DBCacheSize:         defaultMaxCacheSize,
 				DBMaxHandles:        defaultMaxHandles,
 				RPCCallMaxSteps:     defaultCallMaxSteps,
+				RPCMaxBatchSize:     defaultRPCMaxBatchSize,
+				RPCMaxOpenStates:    defaultRPCMaxOpenStates,
 				GatewayTimeout:      defaultGwTimeout,
 			},
 		},
@@ -219,9 +227,20 @@ cn-unverifiable-range: [0,10]
 				DBCacheSize:         defaultMaxCacheSize,
 				DBMaxHandles:        defaultMaxHandles,
 				RPCCallMaxSteps:     defaultCallMaxSteps,
+				RPCMaxBatchSize:     defaultRPCMaxBatchSize,
+				RPCMaxOpenStates:    defaultRPCMaxOpenStates,
 				GatewayTimeout:      defaultGwTimeout,
 			},
 		},
+		"custom network l2 chain id does not fit in a felt": {
+			inputArgs: []string{
+				"--cn-name", "custom", "--cn-feeder-url", "awesome_feeder_url", "--cn-gateway-url", "awesome_gateway_url",
+				"--cn-l1-chain-id", "0x1", "--cn-l2-chain-id", "SN_AWESOME_CHAIN_ID_THAT_IS_FAR_TOO_LONG",
+				"--cn-unverifiable-range", "0,10",
+				"--cn-core-contract-address", "0xc662c410C0ECf747543f5bA90660f6ABeBD9C8c4",
+			},
+			expectErr: true,
+		},
 		"config file doesn't exist": {
 			inputArgs: []string{"--config", "config-file-test.yaml"},
 			expectErr: true,
@@ -256,6 +275,8 @@ cn-unverifiable-range: [0,10]
 				DBCacheSize:         defaultMaxCacheSize,
 				DBMaxHandles:        defaultMaxHandles,
 				RPCCallMaxSteps:     defaultCallMaxSteps,
+				RPCMaxBatchSize:     defaultRPCMaxBatchSize,
+				RPCMaxOpenStates:    defaultRPCMaxOpenStates,
 				GatewayTimeout:      defaultGwTimeout,
 			},
 		},
@@ -295,6 +316,8 @@ pprof: true
 				DBCacheSize:         defaultMaxCacheSize,
 				DBMaxHandles:        defaultMaxHandles,
 				RPCCallMaxSteps:     defaultCallMaxSteps,
+				RPCMaxBatchSize:     defaultRPCMaxBatchSize,
+				RPCMaxOpenStates:    defaultRPCMaxOpenStates,
 				GatewayTimeout:      defaultGwTimeout,
 			},
 		},
@@ -331,6 +354,8 @@ http-port: 4576
 				DBCacheSize:         defaultMaxCacheSize,
 				DBMaxHandles:        defaultMaxHandles,
 				RPCCallMaxSteps:     defaultCallMaxSteps,
+				RPCMaxBatchSize:     defaultRPCMaxBatchSize,
+				RPCMaxOpenStates:    defaultRPCMaxOpenStates,
 				GatewayTimeout:      defaultGwTimeout,
 			},
 		},
@@ -340,32 +365,34 @@ http-port: 4576
 				"--db-path", "/home/.juno", "--network", "goerli", "--pprof", "--db-cache-size", "8",
 			},
 			expectedConfig: &node.Config{
-				LogLevel:        utils.DEBUG,
-				HTTP:            defaultHTTP,
-				HTTPHost:        "0.0.0.0",
-				HTTPPort:        4576,
-				Websocket:       defaultWS,
-				WebsocketHost:   defaultHost,
-				WebsocketPort:   defaultWSPort,
-				GRPC:            defaultGRPC,
-				GRPCHost:        defaultHost,
-				GRPCPort:        defaultGRPCPort,
-				Metrics:         defaultMetrics,
-				MetricsHost:     defaultHost,
-				MetricsPort:     defaultMetricsPort,
-				DatabasePath:    "/home/.juno",
-				Network:         utils.Goerli,
-				Pprof:           true,
-				PprofHost:       defaultHost,
-				PprofPort:       defaultPprofPort,
-				Colour:          defaultColour,
-				MaxVMs:          defaultMaxVMs,
-				MaxVMQueue:      2 * defaultMaxVMs,
-				RPCMaxBlockScan: defaultRPCMaxBlockScan,
-				DBCacheSize:     defaultMaxCacheSize,
-				DBMaxHandles:    defaultMaxHandles,
-				RPCCallMaxSteps: defaultCallMaxSteps,
-				GatewayTimeout:  defaultGwTimeout,
+				LogLevel:         utils.DEBUG,
+				HTTP:             defaultHTTP,
+				HTTPHost:         "0.0.0.0",
+				HTTPPort:         4576,
+				Websocket:        defaultWS,
+				WebsocketHost:    defaultHost,
+				WebsocketPort:    defaultWSPort,
+				GRPC:             defaultGRPC,
+				GRPCHost:         defaultHost,
+				GRPCPort:         defaultGRPCPort,
+				Metrics:          defaultMetrics,
+				MetricsHost:      defaultHost,
+				MetricsPort:      defaultMetricsPort,
+				DatabasePath:     "/home/.juno",
+				Network:          utils.Goerli,
+				Pprof:            true,
+				PprofHost:        defaultHost,
+				PprofPort:        defaultPprofPort,
+				Colour:           defaultColour,
+				MaxVMs:           defaultMaxVMs,
+				MaxVMQueue:       2 * defaultMaxVMs,
+				RPCMaxBlockScan:  defaultRPCMaxBlockScan,
+				DBCacheSize:      defaultMaxCacheSize,
+				DBMaxHandles:     defaultMaxHandles,
+				RPCCallMaxSteps:  defaultCallMaxSteps,
+				RPCMaxBatchSize:  defaultRPCMaxBatchSize,
+				RPCMaxOpenStates: defaultRPCMaxOpenStates,
+				GatewayTimeout:   defaultGwTimeout,
 			},
 		},
 		"some flags without config file": {
@@ -400,6 +427,8 @@ http-port: 4576
 				DBCacheSize:         defaultMaxCacheSize,
 				DBMaxHandles:        defaultMaxHandles,
 				RPCCallMaxSteps:     defaultCallMaxSteps,
+				RPCMaxBatchSize:     defaultRPCMaxBatchSize,
+				RPCMaxOpenStates:    defaultRPCMaxOpenStates,
 				GatewayTimeout:      defaultGwTimeout,
 			},
 		},
@@ -430,7 +459,8 @@ db-cache-size: 8
 				"--log-level", "error", "--http", "--http-port", "4577", "--http-host", "127.0.0.1", "--ws", "--ws-port", "4577", "--ws-host", "127.0.0.1",
 				"--grpc", "--grpc-port", "4577", "--grpc-host", "127.0.0.1", "--metrics", "--metrics-port", "4577", "--metrics-host", "127.0.0.1",
 				"--db-path", "/home/flag/.juno", "--network", "integration", "--pprof", "--pending-poll-interval", time.Millisecond.String(),
-				"--db-cache-size", "9",
+				"--db-cache-size", "9", "--rpc-max-batch-size", "50",
+				"--rpc-max-open-states", "0",
 			},
 			expectedConfig: &node.Config{
 				LogLevel:            utils.ERROR,
@@ -459,6 +489,8 @@ db-cache-size: 8
 				DBCacheSize:         9,
 				DBMaxHandles:        defaultMaxHandles,
 				RPCCallMaxSteps:     defaultCallMaxSteps,
+				RPCMaxBatchSize:     50,
+				RPCMaxOpenStates:    0,
 				GatewayTimeout:      defaultGwTimeout,
 			},
 		},
@@ -497,6 +529,8 @@ network: goerli
 				DBCacheSize:         defaultMaxCacheSize,
 				DBMaxHandles:        defaultMaxHandles,
 				RPCCallMaxSteps:     defaultCallMaxSteps,
+				RPCMaxBatchSize:     defaultRPCMaxBatchSize,
+				RPCMaxOpenStates:    defaultRPCMaxOpenStates,
 				GatewayTimeout:      defaultGwTimeout,
 			},
 		},
@@ -531,6 +565,8 @@ network: goerli
 				DBCacheSize:         defaultMaxCacheSize,
 				DBMaxHandles:        defaultMaxHandles,
 				RPCCallMaxSteps:     defaultCallMaxSteps,
+				RPCMaxBatchSize:     defaultRPCMaxBatchSize,
+				RPCMaxOpenStates:    defaultRPCMaxOpenStates,
 				GatewayTimeout:      defaultGwTimeout,
 			},
 		},
@@ -563,6 +599,8 @@ network: goerli
 				DBCacheSize:         defaultMaxCacheSize,
 				DBMaxHandles:        defaultMaxHandles,
 				RPCCallMaxSteps:     defaultCallMaxSteps,
+				RPCMaxBatchSize:     defaultRPCMaxBatchSize,
+				RPCMaxOpenStates:    defaultRPCMaxOpenStates,
 				GatewayTimeout:      defaultGwTimeout,
 			},
 		},
@@ -596,6 +634,8 @@ network: goerli
 				DBCacheSize:         defaultMaxCacheSize,
 				DBMaxHandles:        defaultMaxHandles,
 				RPCCallMaxSteps:     defaultCallMaxSteps,
+				RPCMaxBatchSize:     defaultRPCMaxBatchSize,
+				RPCMaxOpenStates:    defaultRPCMaxOpenStates,
 				GatewayTimeout:      defaultGwTimeout,
 			},
 		},
@@ -630,6 +670,8 @@ network: goerli
 				GatewayAPIKey:       "apikey",
 				DBMaxHandles:        defaultMaxHandles,
 				RPCCallMaxSteps:     defaultCallMaxSteps,
+				RPCMaxBatchSize:     defaultRPCMaxBatchSize,
+				RPCMaxOpenStates:    defaultRPCMaxOpenStates,
 				GatewayTimeout:      defaultGwTimeout,
 			},
 		},
@@ -2,6 +2,7 @@ package core
 
 import (
 	"errors"
+	"math/big"
 
 	"github.com/NethermindEth/juno/core/crypto"
 	"github.com/NethermindEth/juno/core/felt"
@@ -78,6 +79,26 @@ func ContractAddress(callerAddress, classHash, salt *felt.Felt, constructorCallD
 	)
 }
 
+// storageAddressBound is the upper bound of storage addresses, 2**251 - 256.
+var storageAddressBound = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), contractStorageTrieHeight), big.NewInt(256))
+
+// ComputeStorageKey computes the storage key of a storage variable, or of an entry of a storage mapping when keys are
+// given, e.g. balances[addr] is ComputeStorageKey("balances", []*felt.Felt{addr}).
+// The key is sn_keccak(varName) chained with each key through Pedersen, reduced modulo 2**251 - 256.
+//
+// https://docs.starknet.io/architecture-and-concepts/smart-contracts/contract-storage/#storage_variables
+func ComputeStorageKey(varName string, keys []*felt.Felt) *felt.Felt {
+	// Writing to a keccak hash never returns an error
+	storageKey, _ := crypto.StarknetKeccak([]byte(varName))
+	for _, key := range keys {
+		storageKey = crypto.Pedersen(storageKey, key)
+	}
+
+	var keyBig big.Int
+	storageKey.BigInt(&keyBig)
+	return new(felt.Felt).SetBigInt(keyBig.Mod(&keyBig, storageAddressBound))
+}
+
 func deployed(addr *felt.Felt, txn db.Transaction) (bool, error) {
 	_, err := ContractClassHash(addr, txn)
 	if errors.Is(err, db.ErrKeyNotFound) {
@@ -179,6 +200,17 @@ func ContractStorage(addr, key *felt.Felt, txn db.Transaction) (*felt.Felt, erro
 	return cStorage.Get(key)
 }
 
+// ContractStorageValues returns the values of the given storage keys of a contract, in the order of the keys.
+// The storage trie is opened once and its leaves are read in a single ordered pass, keys that are not set
+// have a zero value.
+func ContractStorageValues(addr *felt.Felt, keys []*felt.Felt, txn db.Transaction) ([]*felt.Felt, error) {
+	cStorage, err := storage(addr, txn)
+	if err != nil {
+		return nil, err
+	}
+	return cStorage.GetMany(keys)
+}
+
 // ContractClassHash returns hash of the class that the contract at the given address instantiates.
 func ContractClassHash(addr *felt.Felt, txn db.Transaction) (*felt.Felt, error) {
 	key := db.ContractClassHash.Key(addr.Marshal())
@@ -5,6 +5,7 @@ import (
 
 	"github.com/NethermindEth/juno/core"
 	"github.com/NethermindEth/juno/core/felt"
+	"github.com/NethermindEth/juno/db"
 	"github.com/NethermindEth/juno/db/pebble"
 	"github.com/NethermindEth/juno/utils"
 	"github.com/stretchr/testify/assert"
@@ -51,6 +52,35 @@ func TestContractAddress(t *testing.T) {
 	}
 }
 
+func TestComputeStorageKey(t *testing.T) {
+	tests := map[string]struct {
+		varName string
+		keys    []*felt.Felt
+		want    *felt.Felt
+	}{
+		"ERC20_name": {
+			varName: "ERC20_name",
+			want:    utils.HexToFelt(t, "0x341c1bdfd89f69748aa00b5742b03adbffd79b8e80cab5c50d91cd8c2a79be1"),
+		},
+		"ERC20_decimals": {
+			varName: "ERC20_decimals",
+			want:    utils.HexToFelt(t, "0x1f0d4aa99431d246bac9b8e48c33e888245b15e9678f64f9bdfc8823dc8f979"),
+		},
+		// the ETH balance of the mainnet sequencer, updated in the storage diff of mainnet block 21656
+		"ERC20_balances mapping entry": {
+			varName: "ERC20_balances",
+			keys:    []*felt.Felt{utils.HexToFelt(t, "0x5dcd266a80b8a5f29f04d779c6b166b80150c24f2180a75e82427242dab20a9")},
+			want:    utils.HexToFelt(t, "0x729ef09ad5d9dbe354b74b49dfb772782f3e2933d380f5700fde500d8e47bd1"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.want, core.ComputeStorageKey(test.varName, test.keys))
+		})
+	}
+}
+
 func TestNewContract(t *testing.T) {
 	testDB := pebble.NewMemTest(t)
 
@@ -180,6 +210,88 @@ func TestUpdateStorageAndStorage(t *testing.T) {
 	})
 }
 
+func TestContractStorageValues(t *testing.T) {
+	testDB := pebble.NewMemTest(t)
+
+	txn, err := testDB.NewTransaction(true)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, txn.Discard())
+	})
+	addr := new(felt.Felt).SetUint64(44)
+
+	contract, err := core.DeployContract(addr, new(felt.Felt).SetUint64(37), txn)
+	require.NoError(t, err)
+	require.NoError(t, contract.UpdateStorage(map[felt.Felt]*felt.Felt{
+		*new(felt.Felt).SetUint64(1): new(felt.Felt).SetUint64(10),
+		*new(felt.Felt).SetUint64(3): new(felt.Felt).SetUint64(30),
+	}, NoopOnValueChanged))
+
+	keys := []*felt.Felt{
+		new(felt.Felt).SetUint64(1),
+		new(felt.Felt).SetUint64(2),
+		new(felt.Felt).SetUint64(3),
+		new(felt.Felt).SetUint64(4),
+	}
+	values, err := core.ContractStorageValues(addr, keys, txn)
+	require.NoError(t, err)
+	assert.Equal(t, []*felt.Felt{new(felt.Felt).SetUint64(10), &felt.Zero, new(felt.Felt).SetUint64(30), &felt.Zero}, values)
+
+	for i, key := range keys {
+		value, err := core.ContractStorage(addr, key, txn)
+		require.NoError(t, err)
+		assert.Equal(t, value, values[i])
+	}
+}
+
+func setupContractStorage(tb testing.TB, txn db.Transaction, addr *felt.Felt, numKeys uint64) []*felt.Felt {
+	tb.Helper()
+
+	contract, err := core.DeployContract(addr, new(felt.Felt).SetUint64(37), txn)
+	require.NoError(tb, err)
+
+	diff := make(map[felt.Felt]*felt.Felt, numKeys)
+	keys := make([]*felt.Felt, 0, numKeys)
+	for i := range numKeys {
+		key := new(felt.Felt).SetUint64(i)
+		diff[*key] = new(felt.Felt).SetUint64(i + 1)
+		keys = append(keys, key)
+	}
+	require.NoError(tb, contract.UpdateStorage(diff, NoopOnValueChanged))
+	return keys
+}
+
+func BenchmarkContractStorageValues(b *testing.B) {
+	testDB, err := pebble.NewMem()
+	require.NoError(b, err)
+	b.Cleanup(func() {
+		require.NoError(b, testDB.Close())
+	})
+
+	txn, err := testDB.NewTransaction(true)
+	require.NoError(b, err)
+	addr := new(felt.Felt).SetUint64(44)
+	keys := setupContractStorage(b, txn, addr, 256)
+
+	b.Run("ContractStorageValues", func(b *testing.B) {
+		for range b.N {
+			_, err := core.ContractStorageValues(addr, keys, txn)
+			require.NoError(b, err)
+		}
+	})
+
+	b.Run("ContractStorage", func(b *testing.B) {
+		for range b.N {
+			for _, key := range keys {
+				_, err := core.ContractStorage(addr, key, txn)
+				require.NoError(b, err)
+			}
+		}
+	})
+
+	require.NoError(b, txn.Discard())
+}
+
 func TestPurge(t *testing.T) {
 	testDB := pebble.NewMemTest(t)
 
@@ -245,3 +245,23 @@ func (z *Felt) Uint64() uint64 {
 func (z *Felt) Clone() *Felt {
 	return &Felt{val: z.val}
 }
+
+// SlicesEqual reports whether a and b hold the same felts in the same order.
+// Two nil entries at the same position are considered equal.
+func SlicesEqual(a, b []*Felt) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] == nil || b[i] == nil {
+			if a[i] != b[i] {
+				return false
+			}
+			continue
+		}
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
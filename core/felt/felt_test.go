@@ -65,3 +65,29 @@ func TestShortString(t *testing.T) {
 		assert.Equal(t, "0x1234...6789", f.ShortString())
 	})
 }
+
+func TestSlicesEqual(t *testing.T) {
+	one := new(felt.Felt).SetUint64(1)
+	two := new(felt.Felt).SetUint64(2)
+
+	tests := map[string]struct {
+		a, b  []*felt.Felt
+		equal bool
+	}{
+		"both nil":          {a: nil, b: nil, equal: true},
+		"nil and empty":     {a: nil, b: []*felt.Felt{}, equal: true},
+		"equal":             {a: []*felt.Felt{one, two}, b: []*felt.Felt{new(felt.Felt).SetUint64(1), two}, equal: true},
+		"different order":   {a: []*felt.Felt{one, two}, b: []*felt.Felt{two, one}, equal: false},
+		"different values":  {a: []*felt.Felt{one, one}, b: []*felt.Felt{one, two}, equal: false},
+		"different lengths": {a: []*felt.Felt{one}, b: []*felt.Felt{one, two}, equal: false},
+		"nil entries":       {a: []*felt.Felt{nil, one}, b: []*felt.Felt{nil, one}, equal: true},
+		"nil against value": {a: []*felt.Felt{nil}, b: []*felt.Felt{&felt.Zero}, equal: false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.equal, felt.SlicesEqual(test.a, test.b))
+			assert.Equal(t, test.equal, felt.SlicesEqual(test.b, test.a))
+		})
+	}
+}
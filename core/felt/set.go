@@ -0,0 +1,47 @@
+package felt
+
+import "sync"
+
+// Set is a set of felts that is safe for concurrent use
+type Set struct {
+	mu    sync.RWMutex
+	felts map[Felt]struct{}
+}
+
+// NewSet returns a set holding the given felts
+func NewSet(felts ...*Felt) *Set {
+	s := &Set{felts: make(map[Felt]struct{}, len(felts))}
+	for _, f := range felts {
+		s.felts[*f] = struct{}{}
+	}
+	return s
+}
+
+// Add inserts f into the set and reports whether it was not already present
+func (s *Set) Add(f *Felt) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.felts[*f]; found {
+		return false
+	}
+	s.felts[*f] = struct{}{}
+	return true
+}
+
+// Contains reports whether f is in the set
+func (s *Set) Contains(f *Felt) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, found := s.felts[*f]
+	return found
+}
+
+// Len returns the number of felts in the set
+func (s *Set) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.felts)
+}
@@ -0,0 +1,54 @@
+package felt_test
+
+import (
+	"testing"
+
+	"github.com/NethermindEth/juno/core/felt"
+	"github.com/sourcegraph/conc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSet(t *testing.T) {
+	one := new(felt.Felt).SetUint64(1)
+	two := new(felt.Felt).SetUint64(2)
+
+	t.Run("empty", func(t *testing.T) {
+		set := felt.NewSet()
+		assert.Zero(t, set.Len())
+		assert.False(t, set.Contains(&felt.Zero))
+	})
+
+	t.Run("initial felts", func(t *testing.T) {
+		set := felt.NewSet(one, two, new(felt.Felt).SetUint64(1))
+		assert.Equal(t, 2, set.Len())
+		assert.True(t, set.Contains(one))
+		assert.True(t, set.Contains(two))
+		assert.False(t, set.Contains(&felt.Zero))
+	})
+
+	t.Run("add deduplicates by value", func(t *testing.T) {
+		set := felt.NewSet()
+		assert.True(t, set.Add(one))
+		assert.False(t, set.Add(new(felt.Felt).SetUint64(1)))
+		assert.True(t, set.Add(two))
+		assert.Equal(t, 2, set.Len())
+	})
+
+	t.Run("concurrent adds", func(t *testing.T) {
+		const workers, feltsPerWorker = 8, 100
+
+		set := felt.NewSet()
+		var wg conc.WaitGroup
+		for range workers {
+			// every worker adds the same felts, so only one add per felt succeeds
+			wg.Go(func() {
+				for i := range uint64(feltsPerWorker) {
+					set.Add(new(felt.Felt).SetUint64(i))
+				}
+			})
+		}
+		wg.Wait()
+
+		assert.Equal(t, feltsPerWorker, set.Len())
+	})
+}
@@ -39,6 +39,8 @@ type StateReader interface {
 	ContractClassHash(addr *felt.Felt) (*felt.Felt, error)
 	ContractNonce(addr *felt.Felt) (*felt.Felt, error)
 	ContractStorage(addr, key *felt.Felt) (*felt.Felt, error)
+	// ContractStorageValues returns the values of the given storage keys of a contract, in the order of the keys
+	ContractStorageValues(addr *felt.Felt, keys []*felt.Felt) ([]*felt.Felt, error)
 	Class(classHash *felt.Felt) (*DeclaredClass, error)
 }
 
@@ -85,6 +87,11 @@ func (s *State) ContractStorage(addr, key *felt.Felt) (*felt.Felt, error) {
 	return ContractStorage(addr, key, s.txn)
 }
 
+// ContractStorageValues returns the values of the given storage keys of a contract, opening its storage trie once.
+func (s *State) ContractStorageValues(addr *felt.Felt, keys []*felt.Felt) ([]*felt.Felt, error) {
+	return ContractStorageValues(addr, keys, s.txn)
+}
+
 // Root returns the state commitment.
 func (s *State) Root() (*felt.Felt, error) {
 	var storageRoot, classesRoot *felt.Felt
@@ -2,11 +2,16 @@ package core
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/NethermindEth/juno/core/felt"
 	"github.com/NethermindEth/juno/db"
 )
 
+// ErrContractNotDeployedYet is returned by historical states for contracts that are only deployed after the
+// block of the snapshot. It wraps db.ErrKeyNotFound, so the contract is still reported as not found.
+var ErrContractNotDeployedYet = fmt.Errorf("contract is deployed after the requested block: %w", db.ErrKeyNotFound)
+
 type stateSnapshot struct {
 	blockNumber uint64
 	state       StateHistoryReader
@@ -64,6 +69,40 @@ func (s *stateSnapshot) ContractStorage(addr, key *felt.Felt) (*felt.Felt, error
 	return val, nil
 }
 
+func (s *stateSnapshot) ContractStorageValues(addr *felt.Felt, keys []*felt.Felt) ([]*felt.Felt, error) {
+	if err := s.checkDeployed(addr); err != nil {
+		return nil, err
+	}
+
+	values := make([]*felt.Felt, len(keys))
+	// keys that were not changed since the snapshot are read from the head state in one go
+	var headKeys []*felt.Felt
+	var headIndices []int
+	for i, key := range keys {
+		val, err := s.state.ContractStorageAt(addr, key, s.blockNumber)
+		if err != nil {
+			if errors.Is(err, ErrCheckHeadState) {
+				headKeys = append(headKeys, key)
+				headIndices = append(headIndices, i)
+				continue
+			}
+			return nil, err
+		}
+		values[i] = val
+	}
+
+	if len(headKeys) > 0 {
+		headValues, err := s.state.ContractStorageValues(addr, headKeys)
+		if err != nil {
+			return nil, err
+		}
+		for i, val := range headValues {
+			values[headIndices[i]] = val
+		}
+	}
+	return values, nil
+}
+
 func (s *stateSnapshot) checkDeployed(addr *felt.Felt) error {
 	isDeployed, err := s.state.ContractIsAlreadyDeployedAt(addr, s.blockNumber)
 	if err != nil {
@@ -71,6 +110,12 @@ func (s *stateSnapshot) checkDeployed(addr *felt.Felt) error {
 	}
 
 	if !isDeployed {
+		// the deployment height is only set for deployed contracts, so tell apart the ones deployed later
+		if _, err = s.state.ContractClassHash(addr); err == nil {
+			return ErrContractNotDeployedYet
+		} else if !errors.Is(err, db.ErrKeyNotFound) {
+			return err
+		}
 		return db.ErrKeyNotFound
 	}
 	return nil
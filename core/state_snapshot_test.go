@@ -59,6 +59,19 @@ func TestStateSnapshot(t *testing.T) {
 			return doHeadReq(loc)
 		},
 	).AnyTimes()
+	mockState.EXPECT().ContractStorageValues(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(addr *felt.Felt, locs []*felt.Felt) ([]*felt.Felt, error) {
+			values := make([]*felt.Felt, 0, len(locs))
+			for _, loc := range locs {
+				value, err := doHeadReq(loc)
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, value)
+			}
+			return values, nil
+		},
+	).AnyTimes()
 
 	addr, err := new(felt.Felt).SetRandom()
 	require.NoError(t, err)
@@ -101,6 +114,17 @@ func TestStateSnapshot(t *testing.T) {
 				got, err := test.snapshot.ContractStorage(addr, addr)
 				test.checker(t, got, err)
 			})
+			t.Run("storage values", func(t *testing.T) {
+				got, err := test.snapshot.ContractStorageValues(addr, []*felt.Felt{addr, addr})
+				if err != nil {
+					test.checker(t, nil, err)
+					return
+				}
+				require.Len(t, got, 2)
+				for _, value := range got {
+					test.checker(t, value, err)
+				}
+			})
 		})
 	}
 
@@ -117,6 +141,26 @@ func TestStateSnapshot(t *testing.T) {
 			_, err := snapshotAfterChange.ContractStorage(&felt.Zero, &felt.Zero)
 			require.EqualError(t, err, "some error")
 		})
+		t.Run("storage values", func(t *testing.T) {
+			_, err := snapshotAfterChange.ContractStorageValues(addr, []*felt.Felt{addr, &felt.Zero})
+			require.EqualError(t, err, "some error")
+		})
+	})
+
+	t.Run("contract is deployed after the snapshot", func(t *testing.T) {
+		_, snapshotErr := snapshotBeforeDeployment.ContractNonce(addr)
+		require.ErrorIs(t, snapshotErr, core.ErrContractNotDeployedYet)
+	})
+
+	t.Run("contract is not deployed at head either", func(t *testing.T) {
+		err = db.ErrKeyNotFound
+		t.Cleanup(func() {
+			err = nil
+		})
+
+		_, snapshotErr := snapshotBeforeDeployment.ContractNonce(addr)
+		require.ErrorIs(t, snapshotErr, db.ErrKeyNotFound)
+		require.NotErrorIs(t, snapshotErr, core.ErrContractNotDeployedYet)
 	})
 
 	declareHeight := deployedHeight
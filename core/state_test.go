@@ -689,3 +689,21 @@ func TestRevertDeclaredClasses(t *testing.T) {
 	_, err = state.Class(sierraHash)
 	require.ErrorIs(t, err, db.ErrKeyNotFound)
 }
+
+func TestStateContractStorageValues(t *testing.T) {
+	testDB := pebble.NewMemTest(t)
+	txn, err := testDB.NewTransaction(true)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, txn.Discard())
+	})
+
+	addr := new(felt.Felt).SetUint64(44)
+	keys := setupContractStorage(t, txn, addr, 3)
+	state := core.NewState(txn)
+
+	unsetKey := new(felt.Felt).SetUint64(100)
+	values, err := state.ContractStorageValues(addr, []*felt.Felt{keys[2], unsetKey, keys[0]})
+	require.NoError(t, err)
+	assert.Equal(t, []*felt.Felt{new(felt.Felt).SetUint64(3), &felt.Zero, new(felt.Felt).SetUint64(1)}, values)
+}
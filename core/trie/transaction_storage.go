@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/NethermindEth/juno/db"
+	"github.com/NethermindEth/juno/utils"
 )
 
 // bufferPool caches unused buffer objects for later reuse.
@@ -87,6 +88,42 @@ func (t *TransactionStorage) Get(key *Key) (*Node, error) {
 	return node, err
 }
 
+// GetSorted fetches the nodes at the given keys by seeking a single iterator forward through them,
+// so the keys must be in ascending order of their encoding. cb is called with a nil node for keys
+// that are not present.
+func (t *TransactionStorage) GetSorted(keys []Key, cb func(idx int, node *Node) error) error {
+	it, err := t.txn.NewIterator()
+	if err != nil {
+		return err
+	}
+
+	buffer := getBuffer()
+	defer bufferPool.Put(buffer)
+	for idx := range keys {
+		buffer.Reset()
+		if _, err = t.dbKey(&keys[idx], buffer); err != nil {
+			return utils.RunAndWrapOnError(it.Close, err)
+		}
+
+		var node *Node
+		if it.Seek(buffer.Bytes()) && bytes.Equal(it.Key(), buffer.Bytes()) {
+			val, valErr := it.Value()
+			if valErr != nil {
+				return utils.RunAndWrapOnError(it.Close, valErr)
+			}
+			node = nodePool.Get().(*Node)
+			if err = node.UnmarshalBinary(val); err != nil {
+				return utils.RunAndWrapOnError(it.Close, err)
+			}
+		}
+
+		if err = cb(idx, node); err != nil {
+			return utils.RunAndWrapOnError(it.Close, err)
+		}
+	}
+	return it.Close()
+}
+
 func (t *TransactionStorage) Delete(key *Key) error {
 	buffer := getBuffer()
 	defer bufferPool.Put(buffer)
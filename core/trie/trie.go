@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"slices"
 	"strings"
 	"sync"
 
@@ -182,6 +183,48 @@ func (t *Trie) Get(key *felt.Felt) (*felt.Felt, error) {
 	return &leafValue, nil
 }
 
+// GetMany returns the values of the given keys, in the order of the keys.
+// The leaves are read in ascending key order with a single iterator, falling back to point lookups
+// when the underlying transaction does not support iterators.
+func (t *Trie) GetMany(keys []*felt.Felt) ([]*felt.Felt, error) {
+	order := make([]int, len(keys))
+	for idx := range order {
+		order[idx] = idx
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return keys[a].Cmp(keys[b])
+	})
+
+	// all leaves have the same length, so ordering them by key also orders their encodings
+	storageKeys := make([]Key, len(keys))
+	for idx, keyIdx := range order {
+		storageKeys[idx] = t.feltToKey(keys[keyIdx])
+	}
+
+	values := make([]*felt.Felt, len(keys))
+	err := t.storage.GetSorted(storageKeys, func(idx int, node *Node) error {
+		value := &felt.Zero
+		if node != nil {
+			leafValue := *node.Value
+			value = &leafValue
+			nodePool.Put(node)
+		}
+		values[order[idx]] = value
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, db.ErrIteratorUnsupported) {
+			return nil, err
+		}
+		for idx, key := range keys {
+			if values[idx], err = t.Get(key); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return values, nil
+}
+
 // check if we are updating an existing leaf, if yes avoid traversing the trie
 func (t *Trie) updateLeaf(nodeKey Key, node *Node, value *felt.Felt) (*felt.Felt, error) {
 	// Check if we are updating an existing leaf
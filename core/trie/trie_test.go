@@ -7,6 +7,7 @@ import (
 	"github.com/NethermindEth/juno/core/felt"
 	"github.com/NethermindEth/juno/core/trie"
 	"github.com/NethermindEth/juno/db"
+	"github.com/NethermindEth/juno/db/pebble"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -355,6 +356,55 @@ func TestRootKeyAlwaysUpdatedOnCommit(t *testing.T) {
 	assert.Equal(t, want, got)
 }
 
+func TestGetMany(t *testing.T) {
+	pebbleTxn, err := pebble.NewMemTest(t).NewTransaction(true)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, pebbleTxn.Discard())
+	})
+
+	tests := map[string]db.Transaction{
+		"iterator":     pebbleTxn,
+		"point lookup": db.NewMemTransaction(),
+	}
+
+	for name, txn := range tests {
+		t.Run(name, func(t *testing.T) {
+			tempTrie, err := trie.NewTriePedersen(trie.NewTransactionStorage(txn, []byte{1}), 251)
+			require.NoError(t, err)
+			for _, key := range []uint64{1, 3, 5} {
+				_, err = tempTrie.Put(new(felt.Felt).SetUint64(key), new(felt.Felt).SetUint64(key*10))
+				require.NoError(t, err)
+			}
+			require.NoError(t, tempTrie.Commit())
+
+			// a leaf under a neighbouring prefix must not be mistaken for an unset key
+			otherTrie, err := trie.NewTriePedersen(trie.NewTransactionStorage(txn, []byte{2}), 251)
+			require.NoError(t, err)
+			_, err = otherTrie.Put(new(felt.Felt).SetUint64(4), new(felt.Felt).SetUint64(40))
+			require.NoError(t, err)
+			require.NoError(t, otherTrie.Commit())
+
+			keys := []*felt.Felt{
+				new(felt.Felt).SetUint64(5),
+				new(felt.Felt).SetUint64(2),
+				new(felt.Felt).SetUint64(1),
+				new(felt.Felt).SetUint64(4),
+				new(felt.Felt).SetUint64(5),
+			}
+			values, err := tempTrie.GetMany(keys)
+			require.NoError(t, err)
+			assert.Equal(t, []*felt.Felt{
+				new(felt.Felt).SetUint64(50),
+				&felt.Zero,
+				new(felt.Felt).SetUint64(10),
+				&felt.Zero,
+				new(felt.Felt).SetUint64(50),
+			}, values)
+		})
+	}
+}
+
 func BenchmarkTriePut(b *testing.B) {
 	keys := make([]*felt.Felt, 0, b.N)
 	for i := 0; i < b.N; i++ {
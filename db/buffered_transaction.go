@@ -1,9 +1,5 @@
 package db
 
-import (
-	"errors"
-)
-
 // BufferedTransaction buffers the updates in the memory to be later flushed to the underlying Transaction
 type BufferedTransaction struct {
 	updates map[string][]byte
@@ -80,5 +76,5 @@ func (t *BufferedTransaction) Impl() any {
 
 // NewIterator : see db.Transaction.NewIterator
 func (t *BufferedTransaction) NewIterator() (Iterator, error) {
-	return nil, errors.New("buffered transactions dont support iterators")
+	return nil, ErrIteratorUnsupported
 }
@@ -9,8 +9,12 @@ import (
 	"github.com/NethermindEth/juno/utils"
 )
 
-// ErrKeyNotFound is returned when key isn't found on a txn.Get.
-var ErrKeyNotFound = errors.New("key not found")
+var (
+	// ErrKeyNotFound is returned when key isn't found on a txn.Get.
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrIteratorUnsupported is returned by transactions that cannot create iterators.
+	ErrIteratorUnsupported = errors.New("transaction does not support iterators")
+)
 
 // DB is a key-value database
 type DB interface {
@@ -1,9 +1,5 @@
 package db
 
-import (
-	"errors"
-)
-
 var _ Transaction = (*memTransaction)(nil)
 
 type memTransaction struct {
@@ -15,7 +11,7 @@ func NewMemTransaction() Transaction {
 }
 
 func (t *memTransaction) NewIterator() (Iterator, error) {
-	return nil, errors.New("not implemented")
+	return nil, ErrIteratorUnsupported
 }
 
 func (t *memTransaction) Discard() error {
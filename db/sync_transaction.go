@@ -1,9 +1,6 @@
 package db
 
-import (
-	"errors"
-	"sync"
-)
+import "sync"
 
 type SyncTransaction struct {
 	lock sync.RWMutex
@@ -62,5 +59,5 @@ func (t *SyncTransaction) Impl() any {
 
 // NewIterator : see db.Transaction.NewIterator
 func (t *SyncTransaction) NewIterator() (Iterator, error) {
-	return nil, errors.New("sync transactions dont support iterators")
+	return nil, ErrIteratorUnsupported
 }
@@ -159,3 +159,18 @@ func (mr *MockStateHistoryReaderMockRecorder) ContractStorageAt(arg0, arg1, arg2
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContractStorageAt", reflect.TypeOf((*MockStateHistoryReader)(nil).ContractStorageAt), arg0, arg1, arg2)
 }
+
+// ContractStorageValues mocks base method.
+func (m *MockStateHistoryReader) ContractStorageValues(arg0 *felt.Felt, arg1 []*felt.Felt) ([]*felt.Felt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ContractStorageValues", arg0, arg1)
+	ret0, _ := ret[0].([]*felt.Felt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ContractStorageValues indicates an expected call of ContractStorageValues.
+func (mr *MockStateHistoryReaderMockRecorder) ContractStorageValues(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContractStorageValues", reflect.TypeOf((*MockStateHistoryReader)(nil).ContractStorageValues), arg0, arg1)
+}
@@ -76,10 +76,12 @@ type Config struct {
 	P2PFeederNode bool   `mapstructure:"p2p-feeder-node"`
 	P2PPrivateKey string `mapstructure:"p2p-private-key"`
 
-	MaxVMs          uint `mapstructure:"max-vms"`
-	MaxVMQueue      uint `mapstructure:"max-vm-queue"`
-	RPCMaxBlockScan uint `mapstructure:"rpc-max-block-scan"`
-	RPCCallMaxSteps uint `mapstructure:"rpc-call-max-steps"`
+	MaxVMs           uint `mapstructure:"max-vms"`
+	MaxVMQueue       uint `mapstructure:"max-vm-queue"`
+	RPCMaxBlockScan  uint `mapstructure:"rpc-max-block-scan"`
+	RPCCallMaxSteps  uint `mapstructure:"rpc-call-max-steps"`
+	RPCMaxBatchSize  uint `mapstructure:"rpc-max-batch-size"`
+	RPCMaxOpenStates uint `mapstructure:"rpc-max-open-states"`
 
 	DBCacheSize  uint `mapstructure:"db-cache-size"`
 	DBMaxHandles int  `mapstructure:"db-max-handles"`
@@ -177,6 +179,12 @@ func New(cfg *Config, version string) (*Node, error) { //nolint:gocyclo,funlen
 
 	rpcHandler := rpc.New(chain, syncReader, throttledVM, version, log).WithGateway(gatewayClient).WithFeeder(client)
 	rpcHandler = rpcHandler.WithFilterLimit(cfg.RPCMaxBlockScan).WithCallMaxSteps(uint64(cfg.RPCCallMaxSteps))
+	if cfg.RPCMaxBatchSize > 0 {
+		rpcHandler = rpcHandler.WithMaxBatchSize(cfg.RPCMaxBatchSize)
+	}
+	if cfg.RPCMaxOpenStates > 0 {
+		rpcHandler = rpcHandler.WithMaxOpenStates(cfg.RPCMaxOpenStates)
+	}
 	services = append(services, rpcHandler)
 	// to improve RPC throughput we double GOMAXPROCS
 	maxGoroutines := 2 * runtime.GOMAXPROCS(0)
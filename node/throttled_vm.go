@@ -1,6 +1,8 @@
 package node
 
 import (
+	"context"
+
 	"github.com/NethermindEth/juno/core"
 	"github.com/NethermindEth/juno/core/felt"
 	"github.com/NethermindEth/juno/utils"
@@ -19,13 +21,13 @@ func NewThrottledVM(res vm.VM, concurrenyBudget uint, maxQueueLen int32) *Thrott
 	}
 }
 
-func (tvm *ThrottledVM) Call(callInfo *vm.CallInfo, blockInfo *vm.BlockInfo, state core.StateReader,
+func (tvm *ThrottledVM) Call(ctx context.Context, callInfo *vm.CallInfo, blockInfo *vm.BlockInfo, state core.StateReader,
 	network *utils.Network, maxSteps uint64, useBlobData bool,
-) ([]*felt.Felt, error) {
-	var ret []*felt.Felt
+) (vm.CallResult, error) {
+	var ret vm.CallResult
 	return ret, tvm.Do(func(vm *vm.VM) error {
 		var err error
-		ret, err = (*vm).Call(callInfo, blockInfo, state, network, maxSteps, useBlobData)
+		ret, err = (*vm).Call(ctx, callInfo, blockInfo, state, network, maxSteps, useBlobData)
 		return err
 	})
 }
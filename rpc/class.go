@@ -3,10 +3,13 @@ package rpc
 import (
 	"encoding/json"
 	"errors"
+	"math/big"
+	"slices"
 
 	"github.com/NethermindEth/juno/adapters/sn2core"
 	"github.com/NethermindEth/juno/core"
 	"github.com/NethermindEth/juno/core/felt"
+	"github.com/NethermindEth/juno/jsonrpc"
 	"github.com/NethermindEth/juno/starknet"
 	"github.com/NethermindEth/juno/utils"
 )
@@ -39,6 +42,29 @@ type FunctionCall struct {
 	Calldata           []felt.Felt `json:"calldata"`
 }
 
+// contractAddressBits is the number of bits of a contract address, which is smaller than a felt
+const contractAddressBits = 251
+
+var contractAddressBound = new(felt.Felt).SetBigInt(new(big.Int).Lsh(big.NewInt(1), contractAddressBits))
+
+// isValidContractAddress reports whether the felt is within the range of contract addresses
+func isValidContractAddress(address *felt.Felt) bool {
+	return address.Cmp(contractAddressBound) < 0
+}
+
+// CallResult is the outcome of a single call in a batch, either its output or the error it failed with
+type CallResult struct {
+	Result []*felt.Felt   `json:"result,omitempty"`
+	Error  *jsonrpc.Error `json:"error,omitempty"`
+}
+
+// CallResponse is the output of a call along with the Cairo steps and gas the call consumed
+type CallResponse struct {
+	Result      []*felt.Felt `json:"result"`
+	Steps       uint64       `json:"steps"`
+	GasConsumed uint64       `json:"gas_consumed"`
+}
+
 func adaptDeclaredClass(declaredClass json.RawMessage) (core.Class, error) {
 	var feederClass starknet.ClassDefinition
 	err := json.Unmarshal(declaredClass, &feederClass)
@@ -66,3 +92,21 @@ func adaptDeclaredClass(declaredClass json.RawMessage) (core.Class, error) {
 		return nil, errors.New("empty class")
 	}
 }
+
+// hasExternalEntryPoint reports whether a call to selector reaches an external entry point of the class. Cairo 0
+// classes with a __default__ entry point, which has the zero selector, handle every selector.
+func hasExternalEntryPoint(class core.Class, selector *felt.Felt) bool {
+	switch c := class.(type) {
+	case *core.Cairo0Class:
+		return slices.ContainsFunc(c.Externals, func(ep core.EntryPoint) bool {
+			return ep.Selector.Equal(selector) || ep.Selector.IsZero()
+		})
+	case *core.Cairo1Class:
+		return slices.ContainsFunc(c.EntryPoints.External, func(ep core.SierraEntryPoint) bool {
+			return ep.Selector.Equal(selector)
+		})
+	default:
+		// let the VM decide for classes it knows more about
+		return true
+	}
+}
@@ -9,10 +9,15 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/big"
+	"runtime"
 	"slices"
+	"strings"
 	stdsync "sync"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
+	"github.com/NethermindEth/juno/adapters/core2sn"
 	"github.com/NethermindEth/juno/blockchain"
 	"github.com/NethermindEth/juno/clients/feeder"
 	"github.com/NethermindEth/juno/clients/gateway"
@@ -27,6 +32,7 @@ import (
 	"github.com/NethermindEth/juno/vm"
 	"github.com/ethereum/go-ethereum/common/lru"
 	"github.com/sourcegraph/conc"
+	"github.com/sourcegraph/conc/pool"
 )
 
 //go:generate mockgen -destination=../mocks/mock_gateway_handler.go -package=mocks github.com/NethermindEth/juno/rpc Gateway
@@ -66,18 +72,38 @@ var (
 
 	// These errors can be only be returned by Juno-specific methods.
 	ErrSubscriptionNotFound = &jsonrpc.Error{Code: 100, Message: "Subscription not found"}
+
+	ErrServiceBusy = &jsonrpc.Error{Code: 101, Message: "Service is busy, try again later"}
+	ErrCallTimeout = &jsonrpc.Error{Code: 102, Message: "Call did not finish within the time limit"}
+
+	ErrCalldataTooLarge = &jsonrpc.Error{Code: 103, Message: "Calldata exceeds the maximum allowed length"}
+	ErrNoCompiledCasm   = &jsonrpc.Error{Code: 104, Message: "Cairo 0 classes have no compiled CASM"}
 )
 
 const (
 	maxEventChunkSize  = 10240
 	maxEventFilterKeys = 1024
 	traceCacheSize     = 128
-	throttledVMErr     = "VM throughput limit reached"
+	defaultFeeBand     = 10
+	defaultCallTimeout = 30 * time.Second
+	// defaultMaxCalldataLen is well above the calldata of any transaction accepted on mainnet
+	defaultMaxCalldataLen = 10_000
+	throttledVMErr        = "VM throughput limit reached"
+	// throttledVMRetryAfterMs is the suggested back-off for every request queued for the VM
+	throttledVMRetryAfterMs = 100
+	// maxCallTimestampAhead bounds how far juno_callAtTimestamp can move a block forward in time, in seconds
+	maxCallTimestampAhead = 10 * 365 * 24 * 60 * 60
 )
 
+// ThrottledVMErrData is the data attached to errors returned when the VM is busy
+type ThrottledVMErrData struct {
+	Message      string `json:"message"`
+	RetryAfterMs uint64 `json:"retry_after_ms"`
+}
+
 type traceCacheKey struct {
-	blockHash    felt.Felt
-	v0_6Response bool
+	blockHash felt.Felt
+	spec      specVersion
 }
 
 type Handler struct {
@@ -97,8 +123,14 @@ type Handler struct {
 
 	blockTraceCache *lru.Cache[traceCacheKey, []TracedBlockTransaction]
 
-	filterLimit  uint
-	callMaxSteps uint64
+	filterLimit    uint
+	callMaxSteps   uint64
+	callStepsCeil  uint64 // upper bound for the step limits requested through CallWithLimit, callMaxSteps if zero
+	callTimeout    time.Duration
+	maxCalldataLen uint // maximum number of calldata felts accepted by starknet_call
+	maxBatchSize   uint
+	openStates     chan struct{} // bounds the number of open state readers, unbounded if nil
+	feeBand        uint64        // percentage the gas prices may move by in fee estimate ranges
 }
 
 type subscription struct {
@@ -125,6 +157,10 @@ func New(bcReader blockchain.Reader, syncReader sync.Reader, virtualMachine vm.V
 
 		blockTraceCache: lru.NewCache[traceCacheKey, []TracedBlockTransaction](traceCacheSize),
 		filterLimit:     math.MaxUint,
+		maxBatchSize:    math.MaxUint,
+		feeBand:         defaultFeeBand,
+		callTimeout:     defaultCallTimeout,
+		maxCalldataLen:  defaultMaxCalldataLen,
 	}
 }
 
@@ -139,6 +175,45 @@ func (h *Handler) WithCallMaxSteps(maxSteps uint64) *Handler {
 	return h
 }
 
+// WithCallMaxStepsCeiling sets the highest step limit a client may request through CallWithLimit. Requests
+// above it are clamped to it. If unset, clients can only lower the limit set by WithCallMaxSteps.
+func (h *Handler) WithCallMaxStepsCeiling(ceiling uint64) *Handler {
+	h.callStepsCeil = ceiling
+	return h
+}
+
+// WithMaxCalldataLength sets the maximum number of calldata felts a call may carry before it fails with
+// ErrCalldataTooLarge.
+func (h *Handler) WithMaxCalldataLength(length uint) *Handler {
+	h.maxCalldataLen = length
+	return h
+}
+
+// WithCallTimeout sets the wall-clock time a call may run in the VM before it fails with ErrCallTimeout.
+func (h *Handler) WithCallTimeout(timeout time.Duration) *Handler {
+	h.callTimeout = timeout
+	return h
+}
+
+// WithMaxBatchSize sets the maximum number of entries accepted by handlers that take a list of inputs.
+func (h *Handler) WithMaxBatchSize(size uint) *Handler {
+	h.maxBatchSize = size
+	return h
+}
+
+// WithMaxOpenStates sets the maximum number of state readers that can be open at the same time across handlers.
+// Requests that need a state reader beyond the limit fail with ErrServiceBusy.
+func (h *Handler) WithMaxOpenStates(limit uint) *Handler {
+	h.openStates = make(chan struct{}, limit)
+	return h
+}
+
+// WithFeeBand sets the percentage by which gas prices may move in the ranges returned by EstimateFeeRange.
+func (h *Handler) WithFeeBand(percent uint64) *Handler {
+	h.feeBand = percent
+	return h
+}
+
 func (h *Handler) WithIDGen(idgen func() uint64) *Handler {
 	h.idgen = idgen
 	return h
@@ -808,30 +883,9 @@ func adaptExecutionResources(resources *core.ExecutionResources, v0_6Response bo
 // It follows the specification defined here:
 // https://github.com/starkware-libs/starknet-specs/blob/master/api/starknet_api_openrpc.json#L77
 func (h *Handler) StateUpdate(id BlockID) (*StateUpdate, *jsonrpc.Error) {
-	var update *core.StateUpdate
-	var err error
-	if id.Latest {
-		if height, heightErr := h.bcReader.Height(); heightErr != nil {
-			err = heightErr
-		} else {
-			update, err = h.bcReader.StateUpdateByNumber(height)
-		}
-	} else if id.Pending {
-		var pending blockchain.Pending
-		pending, err = h.bcReader.Pending()
-		if err == nil {
-			update = pending.StateUpdate
-		}
-	} else if id.Hash != nil {
-		update, err = h.bcReader.StateUpdateByHash(id.Hash)
-	} else {
-		update, err = h.bcReader.StateUpdateByNumber(id.Number)
-	}
-	if err != nil {
-		if errors.Is(err, db.ErrKeyNotFound) {
-			return nil, ErrBlockNotFound
-		}
-		return nil, ErrInternal.CloneWithData(err)
+	update, rpcErr := h.stateUpdateByID(&id)
+	if rpcErr != nil {
+		return nil, rpcErr
 	}
 
 	nonces := make([]Nonce, 0, len(update.StateDiff.Nonces))
@@ -894,6 +948,56 @@ func (h *Handler) StateUpdate(id BlockID) (*StateUpdate, *jsonrpc.Error) {
 	}, nil
 }
 
+func (h *Handler) stateUpdateByID(id *BlockID) (*core.StateUpdate, *jsonrpc.Error) {
+	var update *core.StateUpdate
+	var err error
+	if id.Latest {
+		if height, heightErr := h.bcReader.Height(); heightErr != nil {
+			err = heightErr
+		} else {
+			update, err = h.bcReader.StateUpdateByNumber(height)
+		}
+	} else if id.Pending {
+		var pending blockchain.Pending
+		pending, err = h.bcReader.Pending()
+		if err == nil {
+			update = pending.StateUpdate
+		}
+	} else if id.Hash != nil {
+		update, err = h.bcReader.StateUpdateByHash(id.Hash)
+	} else {
+		update, err = h.bcReader.StateUpdateByNumber(id.Number)
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrKeyNotFound) {
+			return nil, ErrBlockNotFound
+		}
+		return nil, ErrInternal.CloneWithData(err)
+	}
+	return update, nil
+}
+
+// DeclaredClasses returns the hashes of all classes declared in the given block.
+// Cairo 0 classes come first in declaration order, followed by Cairo 1 classes sorted by hash.
+func (h *Handler) DeclaredClasses(id BlockID) ([]*felt.Felt, *jsonrpc.Error) {
+	update, rpcErr := h.stateUpdateByID(&id)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	stateDiff := update.StateDiff
+	classHashes := make([]*felt.Felt, 0, len(stateDiff.DeclaredV0Classes)+len(stateDiff.DeclaredV1Classes))
+	classHashes = append(classHashes, stateDiff.DeclaredV0Classes...)
+
+	// the state diff keeps Cairo 1 declarations in a map, sort them so the result is stable
+	cairo1ClassHashes := make([]*felt.Felt, 0, len(stateDiff.DeclaredV1Classes))
+	for classHash := range stateDiff.DeclaredV1Classes {
+		cairo1ClassHashes = append(cairo1ClassHashes, &classHash)
+	}
+	slices.SortFunc(cairo1ClassHashes, (*felt.Felt).Cmp)
+	return append(classHashes, cairo1ClassHashes...), nil
+}
+
 // Syncing returns the syncing status of the node.
 //
 // It follows the specification defined here:
@@ -932,6 +1036,28 @@ func (h *Handler) Syncing() (*Sync, *jsonrpc.Error) {
 }
 
 func (h *Handler) stateByBlockID(id *BlockID) (core.StateReader, blockchain.StateCloser, *jsonrpc.Error) {
+	if h.openStates == nil {
+		return h.openStateByBlockID(id)
+	}
+
+	select {
+	case h.openStates <- struct{}{}:
+	default:
+		return nil, nil, ErrServiceBusy
+	}
+
+	reader, closer, rpcErr := h.openStateByBlockID(id)
+	if rpcErr != nil {
+		<-h.openStates
+		return nil, nil, rpcErr
+	}
+	return reader, stdsync.OnceValue(func() error {
+		defer func() { <-h.openStates }()
+		return closer()
+	}), nil
+}
+
+func (h *Handler) openStateByBlockID(id *BlockID) (core.StateReader, blockchain.StateCloser, *jsonrpc.Error) {
 	var reader core.StateReader
 	var closer blockchain.StateCloser
 	var err error
@@ -968,7 +1094,10 @@ func (h *Handler) Nonce(id BlockID, address felt.Felt) (*felt.Felt, *jsonrpc.Err
 
 	nonce, err := stateReader.ContractNonce(&address)
 	if err != nil {
-		return nil, ErrContractNotFound
+		if errors.Is(err, db.ErrKeyNotFound) {
+			return nil, contractNotFound(err)
+		}
+		return nil, ErrInternal.CloneWithData(err)
 	}
 
 	return nonce, nil
@@ -985,14 +1114,57 @@ func (h *Handler) StorageAt(address, key felt.Felt, id BlockID) (*felt.Felt, *js
 	}
 	defer h.callAndLogErr(stateCloser, "Error closing state reader in getStorageAt")
 
+	// the contract must be deployed at the block, but an unset slot of a deployed contract is zero
+	if _, err := stateReader.ContractClassHash(&address); err != nil {
+		if errors.Is(err, db.ErrKeyNotFound) {
+			return nil, contractNotFound(err)
+		}
+		return nil, ErrInternal.CloneWithData(err)
+	}
+
 	value, err := stateReader.ContractStorage(&address, &key)
 	if err != nil {
-		return nil, ErrContractNotFound
+		if errors.Is(err, db.ErrKeyNotFound) {
+			return &felt.Zero, nil
+		}
+		return nil, ErrInternal.CloneWithData(err)
 	}
 
 	return value, nil
 }
 
+// StorageAtMany gets the values of the given storage keys of a contract, in the order of the keys.
+// The state and the storage of the contract are opened once for all keys, and keys that are not set have a
+// zero value.
+func (h *Handler) StorageAtMany(address felt.Felt, keys []felt.Felt, id BlockID) ([]*felt.Felt, *jsonrpc.Error) {
+	if rpcErr := h.checkBatchSize(len(keys)); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	stateReader, stateCloser, rpcErr := h.stateByBlockID(&id)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	defer h.callAndLogErr(stateCloser, "Error closing state reader in getStorageAtMany")
+
+	if _, err := stateReader.ContractClassHash(&address); err != nil {
+		if errors.Is(err, db.ErrKeyNotFound) {
+			return nil, contractNotFound(err)
+		}
+		return nil, ErrInternal.CloneWithData(err)
+	}
+
+	keyPtrs := make([]*felt.Felt, len(keys))
+	for i := range keys {
+		keyPtrs[i] = &keys[i]
+	}
+	values, err := stateReader.ContractStorageValues(&address, keyPtrs)
+	if err != nil {
+		return nil, ErrInternal.CloneWithData(err)
+	}
+	return values, nil
+}
+
 // ClassHashAt gets the class hash for the contract deployed at the given address in the given block.
 //
 // It follows the specification defined here:
@@ -1006,12 +1178,29 @@ func (h *Handler) ClassHashAt(id BlockID, address felt.Felt) (*felt.Felt, *jsonr
 
 	classHash, err := stateReader.ContractClassHash(&address)
 	if err != nil {
-		return nil, ErrContractNotFound
+		return nil, contractNotFound(err)
 	}
 
 	return classHash, nil
 }
 
+// IsClassDeclared reports whether the class with the given hash is declared in the given block
+func (h *Handler) IsClassDeclared(classHash felt.Felt, id BlockID) (bool, *jsonrpc.Error) {
+	state, stateCloser, rpcErr := h.stateByBlockID(&id)
+	if rpcErr != nil {
+		return false, rpcErr
+	}
+	defer h.callAndLogErr(stateCloser, "Error closing state reader in isClassDeclared")
+
+	if _, err := state.Class(&classHash); err != nil {
+		if errors.Is(err, db.ErrKeyNotFound) {
+			return false, nil
+		}
+		return false, ErrInternal.CloneWithData(err)
+	}
+	return true, nil
+}
+
 // Class gets the contract class definition in the given block associated with the given hash
 //
 // It follows the specification defined here:
@@ -1090,6 +1279,41 @@ func (h *Handler) ClassAt(id BlockID, address felt.Felt) (*Class, *jsonrpc.Error
 	return h.Class(id, *classHash)
 }
 
+// GetCompiledCasm gets the compiled CASM of the Sierra class with the given hash, as declared at the latest block.
+// Cairo 0 classes are not compiled to CASM, so there is nothing to return for them.
+func (h *Handler) GetCompiledCasm(classHash felt.Felt) (json.RawMessage, *jsonrpc.Error) {
+	state, stateCloser, rpcErr := h.stateByBlockID(&BlockID{Latest: true})
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	defer h.callAndLogErr(stateCloser, "Error closing state reader in getCompiledCasm")
+
+	declared, err := state.Class(&classHash)
+	if err != nil {
+		if errors.Is(err, db.ErrKeyNotFound) {
+			return nil, ErrClassHashNotFound
+		}
+		return nil, ErrInternal.CloneWithData(err)
+	}
+
+	switch c := declared.Class.(type) {
+	case *core.Cairo0Class:
+		return nil, ErrNoCompiledCasm
+	case *core.Cairo1Class:
+		if c.Compiled == nil {
+			return nil, ErrInternal.CloneWithData("compiled CASM of the class is not available")
+		}
+
+		casm, err := json.Marshal(core2sn.AdaptCompiledClass(c.Compiled))
+		if err != nil {
+			return nil, ErrInternal.CloneWithData(err)
+		}
+		return casm, nil
+	default:
+		return nil, ErrInternal.CloneWithData(fmt.Sprintf("unsupported class type %T", c))
+	}
+}
+
 // Events gets the events matching a filter
 //
 // It follows the specification defined here:
@@ -1294,28 +1518,148 @@ func (h *Handler) Version() (string, *jsonrpc.Error) {
 
 // https://github.com/starkware-libs/starknet-specs/blob/e0b76ed0d8d8eba405e182371f9edac8b2bcbc5a/api/starknet_api_openrpc.json#L401-L445
 func (h *Handler) Call(funcCall FunctionCall, id BlockID) ([]*felt.Felt, *jsonrpc.Error) { //nolint:gocritic
-	return h.call(funcCall, id, true)
+	res, rpcErr := h.call(funcCall, id, nil, nil, nil, false, h.callMaxSteps, specV0_7)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	return res.Result, nil
 }
 
 func (h *Handler) CallV0_6(call FunctionCall, id BlockID) ([]*felt.Felt, *jsonrpc.Error) { //nolint:gocritic
-	return h.call(call, id, false)
+	res, rpcErr := h.call(call, id, nil, nil, nil, false, h.callMaxSteps, specV0_6)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	return res.Result, nil
 }
 
-func (h *Handler) call(funcCall FunctionCall, id BlockID, useBlobData bool) ([]*felt.Felt, *jsonrpc.Error) { //nolint:gocritic
-	state, closer, rpcErr := h.stateByBlockID(&id)
+// CallWithUsage calls a function on a contract as starknet_call does, and also reports the Cairo steps and gas
+// the call consumed.
+func (h *Handler) CallWithUsage(funcCall FunctionCall, id BlockID) (*CallResponse, *jsonrpc.Error) { //nolint:gocritic
+	res, rpcErr := h.call(funcCall, id, nil, nil, nil, false, h.callMaxSteps, specV0_7)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	return &CallResponse{
+		Result:      res.Result,
+		Steps:       res.Steps,
+		GasConsumed: res.GasConsumed,
+	}, nil
+}
+
+// CallWithOverrides calls a function on a contract as starknet_call does, but on top of the given state overrides.
+// Anything that is not overridden is read from the state at the given block, which is never modified.
+func (h *Handler) CallWithOverrides(funcCall FunctionCall, id BlockID, //nolint:gocritic
+	overrides []StateOverride,
+) ([]*felt.Felt, *jsonrpc.Error) {
+	res, rpcErr := h.call(funcCall, id, overrides, nil, nil, false, h.callMaxSteps, specV0_7)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	return res.Result, nil
+}
+
+// CallWithLimit calls a function on a contract as starknet_call does, but with the given step limit instead of
+// the default one. The limit is clamped to the ceiling set by WithCallMaxStepsCeiling.
+func (h *Handler) CallWithLimit(funcCall FunctionCall, id BlockID, maxSteps *uint64) ([]*felt.Felt, *jsonrpc.Error) { //nolint:gocritic
+	steps := h.callMaxSteps
+	if maxSteps != nil {
+		steps = min(*maxSteps, h.callMaxStepsCeiling())
+	}
+
+	res, rpcErr := h.call(funcCall, id, nil, nil, nil, false, steps, specV0_7)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	return res.Result, nil
+}
+
+// CallWithCaller calls a function on a contract as starknet_call does, but from the given caller address, which
+// the function sees through get_caller_address(). starknet_call always calls from the zero address.
+func (h *Handler) CallWithCaller(funcCall FunctionCall, id BlockID, //nolint:gocritic
+	callerAddress felt.Felt,
+) ([]*felt.Felt, *jsonrpc.Error) {
+	if !isValidContractAddress(&callerAddress) {
+		return nil, jsonrpc.Err(jsonrpc.InvalidParams, "caller address is not a valid contract address")
+	}
+
+	res, rpcErr := h.call(funcCall, id, nil, nil, &callerAddress, false, h.callMaxSteps, specV0_7)
 	if rpcErr != nil {
 		return nil, rpcErr
 	}
-	defer h.callAndLogErr(closer, "Failed to close state in starknet_call")
+	return res.Result, nil
+}
 
+// CallAtTimestamp calls a function on a contract as starknet_call does, but as if the block had the given
+// timestamp, which the function sees through get_block_timestamp(). Everything else about the block is unchanged.
+// The timestamp can't be earlier than the block's own timestamp, nor more than maxCallTimestampAhead after it.
+func (h *Handler) CallAtTimestamp(funcCall FunctionCall, id BlockID, timestamp uint64) ([]*felt.Felt, *jsonrpc.Error) { //nolint:gocritic
 	header, rpcErr := h.blockHeaderByID(&id)
 	if rpcErr != nil {
 		return nil, rpcErr
 	}
+	if timestamp < header.Timestamp {
+		return nil, jsonrpc.Err(jsonrpc.InvalidParams, "timestamp is earlier than the timestamp of the block")
+	}
+	if timestamp-header.Timestamp > maxCallTimestampAhead {
+		return nil, jsonrpc.Err(jsonrpc.InvalidParams, "timestamp is too far ahead of the timestamp of the block")
+	}
+
+	res, rpcErr := h.call(funcCall, id, nil, &BlockOverrides{Timestamp: &timestamp}, nil, false, h.callMaxSteps, specV0_7)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	return res.Result, nil
+}
 
-	classHash, err := state.ContractClassHash(&funcCall.ContractAddress)
-	if err != nil {
-		return nil, ErrContractNotFound
+// CallWithGasContext calls a function on a contract as starknet_call does, but as if it ran in a V3 transaction
+// that bids the given L1 gas price in fri, for view functions that read the resource bounds of their tx_info.
+// The block of the call gets the same L1 gas price in fri. Without a price, the price of the block is used.
+func (h *Handler) CallWithGasContext(funcCall FunctionCall, id BlockID, //nolint:gocritic
+	l1GasPrice *felt.Felt,
+) ([]*felt.Felt, *jsonrpc.Error) {
+	// the VM holds gas prices as u128, a larger price would be silently truncated
+	if l1GasPrice != nil && l1GasPrice.BigInt(new(big.Int)).BitLen() > 128 {
+		return nil, jsonrpc.Err(jsonrpc.InvalidParams, "l1 gas price does not fit in 128 bits")
+	}
+
+	overrides := &BlockOverrides{L1GasPrice: &ResourcePrice{InFri: l1GasPrice}}
+	res, rpcErr := h.call(funcCall, id, nil, overrides, nil, true, h.callMaxSteps, specV0_7)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	return res.Result, nil
+}
+
+func (h *Handler) callMaxStepsCeiling() uint64 {
+	if h.callStepsCeil == 0 {
+		return h.callMaxSteps
+	}
+	return h.callStepsCeil
+}
+
+func (h *Handler) call(funcCall FunctionCall, id BlockID, overrides []StateOverride, //nolint:gocritic
+	blockOverrides *BlockOverrides, caller *felt.Felt, gasPriceInTxInfo bool, maxSteps uint64, spec specVersion,
+) (*vm.CallResult, *jsonrpc.Error) {
+	if uint(len(funcCall.Calldata)) > h.maxCalldataLen {
+		return nil, ErrCalldataTooLarge
+	}
+
+	headState, closer, rpcErr := h.stateByBlockID(&id)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	var calls vmCalls
+	defer calls.closeState(func() { h.callAndLogErr(closer, "Failed to close state in starknet_call") })
+
+	state := headState
+	if len(overrides) > 0 {
+		state = blockchain.NewPendingState(adaptStateOverrides(overrides), nil, headState)
+	}
+
+	header, rpcErr := h.blockHeaderByID(&id)
+	if rpcErr != nil {
+		return nil, rpcErr
 	}
 
 	blockHashToBeRevealed, err := h.getRevealedBlockHash(header.Number)
@@ -1323,40 +1667,169 @@ func (h *Handler) call(funcCall FunctionCall, id BlockID, useBlobData bool) ([]*
 		return nil, ErrInternal.CloneWithData(err)
 	}
 
-	res, err := h.vm.Call(&vm.CallInfo{
-		ContractAddress: &funcCall.ContractAddress,
-		Selector:        &funcCall.EntryPointSelector,
-		Calldata:        funcCall.Calldata,
-		ClassHash:       classHash,
-	}, &vm.BlockInfo{
-		Header:                header,
+	return h.callOnState(&calls, &funcCall, caller, gasPriceInTxInfo, state, &vm.BlockInfo{
+		Header:                blockOverrides.apply(header),
 		BlockHashToBeRevealed: blockHashToBeRevealed,
-	}, state, h.bcReader.Network(), h.callMaxSteps, useBlobData)
-	if err != nil {
-		if errors.Is(err, utils.ErrResourceBusy) {
-			return nil, ErrInternal.CloneWithData(throttledVMErr)
-		}
-		return nil, makeContractError(err)
+	}, maxSteps, spec)
+}
+
+// contractNotFound explains why a contract is missing from a state: a historical block may predate the
+// deployment of a contract that exists at the latest block.
+func contractNotFound(err error) *jsonrpc.Error {
+	if errors.Is(err, core.ErrContractNotDeployedYet) {
+		return ErrContractNotFound.CloneWithData("contract is not deployed yet at the requested block")
 	}
-	return res, nil
+	return ErrContractNotFound
 }
 
-type ContractErrorData struct {
-	RevertError string `json:"revert_error"`
+// CallMany calls each of the given functions as starknet_call does, on the same state and block. A call that
+// fails does not fail the others, its error is reported in its own result instead. A call that times out fails
+// the whole batch with ErrCallTimeout.
+func (h *Handler) CallMany(funcCalls []FunctionCall, id BlockID) ([]CallResult, *jsonrpc.Error) { //nolint:gocritic
+	if rpcErr := h.checkBatchSize(len(funcCalls)); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	state, closer, rpcErr := h.stateByBlockID(&id)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	var calls vmCalls
+	defer calls.closeState(func() { h.callAndLogErr(closer, "Failed to close state in juno_callMany") })
+
+	header, rpcErr := h.blockHeaderByID(&id)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	blockHashToBeRevealed, err := h.getRevealedBlockHash(header.Number)
+	if err != nil {
+		return nil, ErrInternal.CloneWithData(err)
+	}
+	blockInfo := &vm.BlockInfo{
+		Header:                header,
+		BlockHashToBeRevealed: blockHashToBeRevealed,
+	}
+
+	results := make([]CallResult, 0, len(funcCalls))
+	for i := range funcCalls {
+		if uint(len(funcCalls[i].Calldata)) > h.maxCalldataLen {
+			results = append(results, CallResult{Error: ErrCalldataTooLarge})
+			continue
+		}
+
+		res, rpcErr := h.callOnState(&calls, &funcCalls[i], nil, false, state, blockInfo, h.callMaxSteps, specV0_7)
+		if rpcErr == ErrCallTimeout {
+			// the remaining calls would run next to the timed out one, fail the whole batch right away instead
+			return nil, rpcErr
+		}
+		if rpcErr != nil {
+			results = append(results, CallResult{Error: rpcErr})
+			continue
+		}
+		results = append(results, CallResult{Result: res.Result})
+	}
+	return results, nil
 }
 
-func makeContractError(err error) *jsonrpc.Error {
-	return ErrContractError.CloneWithData(ContractErrorData{
-		RevertError: err.Error(),
-	})
+// vmCalls keeps track of the VM calls running on a state, so that the state outlives calls that timed out
+type vmCalls struct {
+	running  stdsync.WaitGroup
+	timedOut bool
 }
 
-type TransactionExecutionErrorData struct {
-	TransactionIndex uint64 `json:"transaction_index"`
-	ExecutionError   string `json:"execution_error"`
+// closeState closes the state right away, or once all VM calls returned if any of them timed out
+func (c *vmCalls) closeState(closeState func()) {
+	if !c.timedOut {
+		closeState()
+		return
+	}
+	go func() {
+		c.running.Wait()
+		closeState()
+	}()
 }
 
-func makeTransactionExecutionError(err *vm.TransactionExecutionError) *jsonrpc.Error {
+// callOnState runs the given function call in the VM on the given state, giving up after the call timeout.
+// A call that timed out is cancelled, it stops at its next state read. A nil caller calls the function from the
+// zero address.
+func (h *Handler) callOnState(calls *vmCalls, funcCall *FunctionCall, caller *felt.Felt, gasPriceInTxInfo bool,
+	state core.StateReader, blockInfo *vm.BlockInfo, maxSteps uint64, spec specVersion,
+) (*vm.CallResult, *jsonrpc.Error) {
+	classHash, err := state.ContractClassHash(&funcCall.ContractAddress)
+	if err != nil {
+		return nil, contractNotFound(err)
+	}
+
+	class, err := state.Class(classHash)
+	if err != nil {
+		return nil, ErrInternal.CloneWithData(err)
+	}
+	if !hasExternalEntryPoint(class.Class, &funcCall.EntryPointSelector) {
+		return nil, ErrContractError.CloneWithData(ContractErrorData{
+			RevertError:     fmt.Sprintf("entry point %s not found in contract", &funcCall.EntryPointSelector),
+			ClassHash:       classHash,
+			UnknownSelector: true,
+		})
+	}
+
+	type callOutcome struct {
+		res vm.CallResult
+		err error
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.callTimeout)
+	defer cancel()
+
+	outcome := make(chan callOutcome, 1)
+	network := h.bcReader.Network()
+	calls.running.Add(1)
+	go func() {
+		defer calls.running.Done()
+		res, err := h.vm.Call(ctx, &vm.CallInfo{
+			ContractAddress:  &funcCall.ContractAddress,
+			Selector:         &funcCall.EntryPointSelector,
+			Calldata:         funcCall.Calldata,
+			ClassHash:        classHash,
+			CallerAddress:    caller,
+			GasPriceInTxInfo: gasPriceInTxInfo,
+		}, blockInfo, state, network, maxSteps, spec.useBlobData)
+		outcome <- callOutcome{res: res, err: err}
+	}()
+
+	select {
+	case o := <-outcome:
+		if o.err != nil {
+			if errors.Is(o.err, utils.ErrResourceBusy) {
+				return nil, h.throttledVMError()
+			}
+			return nil, makeContractError(o.err)
+		}
+		return &o.res, nil
+	case <-ctx.Done():
+		calls.timedOut = true
+		return nil, ErrCallTimeout
+	}
+}
+
+type ContractErrorData struct {
+	RevertError string `json:"revert_error"`
+	// set only when the called entry point does not exist in the class of the contract
+	ClassHash       *felt.Felt `json:"class_hash,omitempty"`
+	UnknownSelector bool       `json:"unknown_selector,omitempty"`
+}
+
+func makeContractError(err error) *jsonrpc.Error {
+	return ErrContractError.CloneWithData(ContractErrorData{
+		RevertError: err.Error(),
+	})
+}
+
+type TransactionExecutionErrorData struct {
+	TransactionIndex uint64 `json:"transaction_index"`
+	ExecutionError   string `json:"execution_error"`
+}
+
+func makeTransactionExecutionError(err *vm.TransactionExecutionError) *jsonrpc.Error {
 	return ErrTransactionExecutionError.CloneWithData(TransactionExecutionErrorData{
 		TransactionIndex: err.Index,
 		ExecutionError:   err.Cause.Error(),
@@ -1407,10 +1880,14 @@ func (h *Handler) TransactionStatus(ctx context.Context, hash felt.Felt) (*Trans
 	return nil, txErr
 }
 
+// EstimateFee estimates the fees of the given transactions. Fees are never charged; passing SkipValidateFlag
+// also skips the accounts' __validate__ entrypoints, so the estimates exclude the validation gas and accounts
+// that could not pass validation yet (e.g. unfunded or not yet deployed ones) can still be estimated.
 func (h *Handler) EstimateFee(broadcastedTxns []BroadcastedTransaction,
 	simulationFlags []SimulationFlag, id BlockID,
 ) ([]FeeEstimate, *jsonrpc.Error) {
-	result, err := h.simulateTransactions(id, broadcastedTxns, append(simulationFlags, SkipFeeChargeFlag), false, true)
+	flags := append(slices.Clone(simulationFlags), SkipFeeChargeFlag)
+	result, err := h.simulateTransactions(id, broadcastedTxns, flags, specV0_7, true)
 	if err != nil {
 		return nil, err
 	}
@@ -1420,10 +1897,128 @@ func (h *Handler) EstimateFee(broadcastedTxns []BroadcastedTransaction,
 	}), nil
 }
 
+// EstimateFeeParallel estimates the fees of the given transactions like EstimateFee, but executes them
+// concurrently, each on its own state reader, with at most GOMAXPROCS executions at a time. Only batches of invoke
+// and L1 handler transactions with distinct senders are executed concurrently, others are estimated sequentially.
+// If any of the concurrent executions fails, or more than one of them calls into or changes the same contract, the
+// batch is estimated again sequentially, since one transaction may have read what another one writes.
+func (h *Handler) EstimateFeeParallel(broadcastedTxns []BroadcastedTransaction,
+	simulationFlags []SimulationFlag, id BlockID,
+) ([]FeeEstimate, *jsonrpc.Error) {
+	if rpcErr := h.checkBatchSize(len(broadcastedTxns)); rpcErr != nil {
+		return nil, rpcErr
+	}
+	// the pending block cannot be pinned, so concurrent executions could see different pending states
+	if id.Pending || len(broadcastedTxns) < 2 || mayDependOnEachOther(broadcastedTxns) {
+		return h.EstimateFee(broadcastedTxns, simulationFlags, id)
+	}
+
+	header, rpcErr := h.blockHeaderByID(&id)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	// every execution opens its own state, pin the block so that they all see the same one
+	pinnedID := BlockID{Hash: header.Hash}
+
+	flags := append(slices.Clone(simulationFlags), SkipFeeChargeFlag)
+	simulated := make([]SimulatedTransaction, len(broadcastedTxns))
+	failed := make([]bool, len(broadcastedTxns))
+	p := pool.New().WithMaxGoroutines(runtime.GOMAXPROCS(0))
+	for i := range broadcastedTxns {
+		p.Go(func() {
+			result, rpcErr := h.simulateTransactions(pinnedID, broadcastedTxns[i:i+1], flags, specV0_7, true)
+			if rpcErr != nil {
+				failed[i] = true
+				return
+			}
+			simulated[i] = result[0]
+		})
+	}
+	p.Wait()
+
+	if slices.Contains(failed, true) || touchSameContract(simulated) {
+		return h.EstimateFee(broadcastedTxns, simulationFlags, pinnedID)
+	}
+	return utils.Map(simulated, func(tx SimulatedTransaction) FeeEstimate {
+		return tx.FeeEstimation
+	}), nil
+}
+
+// EstimateFeePartial estimates the fees of the given transactions like EstimateFee, but a transaction that fails
+// does not fail the whole batch. Its error is reported in its own result instead, and the transactions after it
+// are estimated as if it had never been sent.
+func (h *Handler) EstimateFeePartial(broadcastedTxns []BroadcastedTransaction,
+	simulationFlags []SimulationFlag, id BlockID,
+) ([]FeeEstimateResult, *jsonrpc.Error) {
+	if rpcErr := h.checkBatchSize(len(broadcastedTxns)); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	state, closer, rpcErr := h.stateByBlockID(&id)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	defer h.callAndLogErr(closer, "Failed to close state in juno_estimateFeePartial")
+
+	header, rpcErr := h.blockHeaderByID(&id)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	flags := append(slices.Clone(simulationFlags), SkipFeeChargeFlag)
+	results := make([]FeeEstimateResult, len(broadcastedTxns))
+	// once a transaction fails, the ones before it are kept in executed and the rest of the batch runs on top
+	// of them, so no transaction is executed more than twice
+	var executed *executedState
+	runOn := state
+	for start := 0; start < len(broadcastedTxns); {
+		simulated, rpcErr := h.simulateTransactionsOnState(runOn, header, broadcastedTxns[start:], flags, specV0_7, true)
+		if rpcErr == nil {
+			for i := range simulated {
+				results[start+i] = FeeEstimateResult{FeeEstimate: &simulated[i].FeeEstimation}
+			}
+			break
+		}
+
+		errData, ok := rpcErr.Data.(TransactionExecutionErrorData)
+		if rpcErr.Code != ErrTransactionExecutionError.Code || !ok || errData.TransactionIndex >= uint64(len(broadcastedTxns)-start) {
+			return nil, rpcErr
+		}
+		failed := start + int(errData.TransactionIndex)
+		errData.TransactionIndex = uint64(failed)
+		results[failed] = FeeEstimateResult{Error: ErrTransactionExecutionError.CloneWithData(errData)}
+
+		// the VM drops the results of the whole run when a transaction fails, so the transactions before
+		// the failing one run once more on their own and their changes are kept for the rest of the batch
+		if failed > start {
+			simulated, rpcErr = h.simulateTransactionsOnState(runOn, header, broadcastedTxns[start:failed], flags,
+				specV0_7, true)
+			if rpcErr != nil {
+				return nil, rpcErr
+			}
+			if executed == nil {
+				executed = newExecutedState(state)
+				runOn = executed
+			}
+			for i := range simulated {
+				results[start+i] = FeeEstimateResult{FeeEstimate: &simulated[i].FeeEstimation}
+				if err := executed.apply(&broadcastedTxns[start+i], simulated[i].TransactionTrace.StateDiff,
+					h.bcReader.Network()); err != nil {
+					return nil, ErrInternal.CloneWithData(err)
+				}
+			}
+		}
+		start = failed + 1
+	}
+	return results, nil
+}
+
+// EstimateFeeV0_6 is the v0.6 variant of EstimateFee and honours SkipValidateFlag the same way.
 func (h *Handler) EstimateFeeV0_6(broadcastedTxns []BroadcastedTransaction,
 	simulationFlags []SimulationFlag, id BlockID,
 ) ([]FeeEstimate, *jsonrpc.Error) {
-	result, err := h.simulateTransactions(id, broadcastedTxns, append(simulationFlags, SkipFeeChargeFlag), true, true)
+	flags := append(slices.Clone(simulationFlags), SkipFeeChargeFlag)
+	result, err := h.simulateTransactions(id, broadcastedTxns, flags, specV0_6, true)
 	if err != nil {
 		return nil, err
 	}
@@ -1433,6 +2028,117 @@ func (h *Handler) EstimateFeeV0_6(broadcastedTxns []BroadcastedTransaction,
 	}), nil
 }
 
+// EstimateFeeRange estimates the fees of the given transactions like EstimateFee, and brackets each estimate with
+// the fees for gas prices moved down and up by the configured band around the block's prices.
+func (h *Handler) EstimateFeeRange(broadcastedTxns []BroadcastedTransaction,
+	simulationFlags []SimulationFlag, id BlockID,
+) ([]FeeEstimateRange, *jsonrpc.Error) {
+	if rpcErr := h.checkBatchSize(len(broadcastedTxns)); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	estimates, rpcErr := h.EstimateFee(broadcastedTxns, simulationFlags, id)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	return utils.Map(estimates, func(estimate FeeEstimate) FeeEstimateRange {
+		return estimate.rangeForBand(h.feeBand)
+	}), nil
+}
+
+// EstimateBundleFee estimates the total fee of the given transactions executed in sequence as a single unit,
+// with every transaction observing the state changes of the ones before it.
+// All transactions in the bundle must pay their fees in the same unit.
+func (h *Handler) EstimateBundleFee(broadcastedTxns []BroadcastedTransaction, id BlockID) (*FeeEstimate, *jsonrpc.Error) {
+	if rpcErr := h.checkBatchSize(len(broadcastedTxns)); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if len(broadcastedTxns) == 0 {
+		return nil, jsonrpc.Err(jsonrpc.InvalidParams, "empty transaction bundle")
+	}
+
+	estimates, rpcErr := h.EstimateFee(broadcastedTxns, nil, id)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	bundle := FeeEstimate{
+		GasConsumed:     new(felt.Felt),
+		GasPrice:        estimates[0].GasPrice,
+		DataGasConsumed: new(felt.Felt),
+		DataGasPrice:    estimates[0].DataGasPrice,
+		OverallFee:      new(felt.Felt),
+		Unit:            estimates[0].Unit,
+	}
+	for i := range estimates {
+		if *estimates[i].Unit != *bundle.Unit {
+			return nil, jsonrpc.Err(jsonrpc.InvalidParams, "transactions in a bundle must use the same fee unit")
+		}
+		bundle.GasConsumed.Add(bundle.GasConsumed, estimates[i].GasConsumed)
+		bundle.DataGasConsumed.Add(bundle.DataGasConsumed, estimates[i].DataGasConsumed)
+		bundle.OverallFee.Add(bundle.OverallFee, estimates[i].OverallFee)
+	}
+	return &bundle, nil
+}
+
+// EstimateFeeWithRevertTraces estimates the fees of the given transactions like EstimateFee, but instead of failing
+// on a reverted transaction it returns the VM trace of every transaction that reverted. Traces of successful
+// transactions are omitted to keep the response small.
+func (h *Handler) EstimateFeeWithRevertTraces(broadcastedTxns []BroadcastedTransaction,
+	simulationFlags []SimulationFlag, id BlockID,
+) ([]SimulatedTransaction, *jsonrpc.Error) {
+	if rpcErr := h.checkBatchSize(len(broadcastedTxns)); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	flags := append(slices.Clone(simulationFlags), SkipFeeChargeFlag)
+	result, err := h.simulateTransactions(id, broadcastedTxns, flags, specV0_7, false)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range result {
+		if result[i].TransactionTrace.RevertReason() == "" {
+			result[i].TransactionTrace = nil
+		}
+	}
+	return result, nil
+}
+
+// EstimateFeeMatrix estimates the fee of a single transaction once per given set of simulation flags.
+// All estimates are computed against the same state and header, so the results are directly comparable.
+// Unlike EstimateFee, the flag sets are used as is, which allows comparing fee charge scenarios as well.
+func (h *Handler) EstimateFeeMatrix(broadcastedTxn BroadcastedTransaction, //nolint:gocritic
+	flagSets [][]SimulationFlag, id BlockID,
+) ([]FeeEstimate, *jsonrpc.Error) {
+	if rpcErr := h.checkBatchSize(len(flagSets)); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	state, closer, rpcErr := h.stateByBlockID(&id)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	defer h.callAndLogErr(closer, "Failed to close state in juno_estimateFeeMatrix")
+
+	header, rpcErr := h.blockHeaderByID(&id)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	estimates := make([]FeeEstimate, 0, len(flagSets))
+	for _, flags := range flagSets {
+		result, err := h.simulateTransactionsOnState(state, header, []BroadcastedTransaction{broadcastedTxn}, flags, specV0_7, true)
+		if err != nil {
+			return nil, err
+		}
+		estimates = append(estimates, result[0].FeeEstimation)
+	}
+	return estimates, nil
+}
+
 func (h *Handler) EstimateMessageFee(msg MsgFromL1, id BlockID) (*FeeEstimate, *jsonrpc.Error) { //nolint:gocritic
 	return h.estimateMessageFee(msg, id, h.EstimateFee)
 }
@@ -1455,13 +2161,75 @@ type estimateFeeHandler func(broadcastedTxns []BroadcastedTransaction,
 ) ([]FeeEstimate, *jsonrpc.Error)
 
 func (h *Handler) estimateMessageFee(msg MsgFromL1, id BlockID, f estimateFeeHandler) (*FeeEstimate, *jsonrpc.Error) { //nolint:gocritic
+	tx, rpcErr := l1HandlerTransaction(&msg)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	estimates, rpcErr := f([]BroadcastedTransaction{tx}, nil, id)
+	if rpcErr != nil {
+		if rpcErr.Code == ErrTransactionExecutionError.Code {
+			data := rpcErr.Data.(TransactionExecutionErrorData)
+			return nil, makeContractError(errors.New(data.ExecutionError))
+		}
+		return nil, rpcErr
+	}
+	return &estimates[0], nil
+}
+
+// EstimateMessageFeeMany estimates the fees of several L1 messages in one call. The messages are handled in order,
+// each on top of the ones before it. A message that is invalid or fails to execute gets an error in its place
+// instead of failing the whole batch.
+func (h *Handler) EstimateMessageFeeMany(msgs []MsgFromL1, id BlockID) ([]FeeEstimateResult, *jsonrpc.Error) {
+	if rpcErr := h.checkBatchSize(len(msgs)); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	results := make([]FeeEstimateResult, len(msgs))
+	txns := make([]BroadcastedTransaction, 0, len(msgs))
+	// msgIndexes[i] is the index of the message txns[i] was built from
+	msgIndexes := make([]int, 0, len(msgs))
+	for i := range msgs {
+		tx, rpcErr := l1HandlerTransaction(&msgs[i])
+		if rpcErr != nil {
+			results[i] = FeeEstimateResult{Error: rpcErr}
+			continue
+		}
+		txns = append(txns, tx)
+		msgIndexes = append(msgIndexes, i)
+	}
+	if len(txns) == 0 {
+		return results, nil
+	}
+
+	estimates, rpcErr := h.EstimateFeePartial(txns, nil, id)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	for i, estimate := range estimates {
+		if estimate.Error != nil {
+			if data, ok := estimate.Error.Data.(TransactionExecutionErrorData); ok {
+				estimate.Error = makeContractError(errors.New(data.ExecutionError))
+			}
+		}
+		results[msgIndexes[i]] = estimate
+	}
+	return results, nil
+}
+
+// l1HandlerTransaction builds the L1 handler transaction that handles the given message on L2
+func l1HandlerTransaction(msg *MsgFromL1) (BroadcastedTransaction, *jsonrpc.Error) {
+	// the request validator doesn't check felts, so the target and selector are checked here for every message
+	if msg.To.IsZero() || msg.Selector.IsZero() {
+		return BroadcastedTransaction{}, jsonrpc.Err(jsonrpc.InvalidParams, "to_address and entry_point_selector must be set")
+	}
+
 	calldata := make([]*felt.Felt, 0, len(msg.Payload)+1)
 	// The order of the calldata parameters matters. msg.From must be prepended.
 	calldata = append(calldata, new(felt.Felt).SetBytes(msg.From.Bytes()))
 	for payloadIdx := range msg.Payload {
 		calldata = append(calldata, &msg.Payload[payloadIdx])
 	}
-	tx := BroadcastedTransaction{
+	return BroadcastedTransaction{
 		Transaction: Transaction{
 			Type:               TxnL1Handler,
 			ContractAddress:    &msg.To,
@@ -1473,16 +2241,7 @@ func (h *Handler) estimateMessageFee(msg MsgFromL1, id BlockID, f estimateFeeHan
 		// Needed to marshal to blockifier type.
 		// Must be greater than zero to successfully execute transaction.
 		PaidFeeOnL1: new(felt.Felt).SetUint64(1),
-	}
-	estimates, rpcErr := f([]BroadcastedTransaction{tx}, nil, id)
-	if rpcErr != nil {
-		if rpcErr.Code == ErrTransactionExecutionError.Code {
-			data := rpcErr.Data.(TransactionExecutionErrorData)
-			return nil, makeContractError(errors.New(data.ExecutionError))
-		}
-		return nil, rpcErr
-	}
-	return &estimates[0], nil
+	}, nil
 }
 
 // TraceTransaction returns the trace for a given executed transaction, including internal calls
@@ -1490,14 +2249,14 @@ func (h *Handler) estimateMessageFee(msg MsgFromL1, id BlockID, f estimateFeeHan
 // It follows the specification defined here:
 // https://github.com/starkware-libs/starknet-specs/blob/1ae810e0137cc5d175ace4554892a4f43052be56/api/starknet_trace_api_openrpc.json#L11
 func (h *Handler) TraceTransaction(ctx context.Context, hash felt.Felt) (*vm.TransactionTrace, *jsonrpc.Error) {
-	return h.traceTransaction(ctx, &hash, false)
+	return h.traceTransaction(ctx, &hash, specV0_7)
 }
 
 func (h *Handler) TraceTransactionV0_6(ctx context.Context, hash felt.Felt) (*vm.TransactionTrace, *jsonrpc.Error) {
-	return h.traceTransaction(ctx, &hash, true)
+	return h.traceTransaction(ctx, &hash, specV0_6)
 }
 
-func (h *Handler) traceTransaction(ctx context.Context, hash *felt.Felt, v0_6Response bool) (*vm.TransactionTrace, *jsonrpc.Error) {
+func (h *Handler) traceTransaction(ctx context.Context, hash *felt.Felt, spec specVersion) (*vm.TransactionTrace, *jsonrpc.Error) {
 	_, _, blockNumber, err := h.bcReader.Receipt(hash)
 	if err != nil {
 		return nil, ErrTxnHashNotFound
@@ -1515,7 +2274,7 @@ func (h *Handler) traceTransaction(ctx context.Context, hash *felt.Felt, v0_6Res
 		return nil, ErrTxnHashNotFound
 	}
 
-	traceResults, traceBlockErr := h.traceBlockTransactions(ctx, block, v0_6Response)
+	traceResults, traceBlockErr := h.traceBlockTransactions(ctx, block, spec)
 	if traceBlockErr != nil {
 		return nil, traceBlockErr
 	}
@@ -1523,26 +2282,143 @@ func (h *Handler) traceTransaction(ctx context.Context, hash *felt.Felt, v0_6Res
 	return traceResults[txIndex].TraceRoot, nil
 }
 
+// TransactionStorageKeys returns the storage keys written by a given executed transaction, grouped by contract.
+// The keys are derived from the state diff of the transaction trace, so only writes are reported.
+func (h *Handler) TransactionStorageKeys(ctx context.Context, hash felt.Felt) ([]ContractKeys, *jsonrpc.Error) {
+	trace, rpcErr := h.traceTransaction(ctx, &hash, specV0_7)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	return adaptStorageKeys(trace.StateDiff), nil
+}
+
 func (h *Handler) SimulateTransactions(id BlockID, transactions []BroadcastedTransaction,
 	simulationFlags []SimulationFlag,
 ) ([]SimulatedTransaction, *jsonrpc.Error) {
-	return h.simulateTransactions(id, transactions, simulationFlags, false, false)
+	return h.simulateTransactions(id, transactions, simulationFlags, specV0_7, false)
+}
+
+// SimulateWithStateDiff simulates the given transactions and returns the state diff each of them would produce,
+// honouring the same simulation flags as SimulateTransactions.
+func (h *Handler) SimulateWithStateDiff(id BlockID, transactions []BroadcastedTransaction,
+	simulationFlags []SimulationFlag,
+) ([]SimulatedStateDiff, *jsonrpc.Error) {
+	if rpcErr := h.checkBatchSize(len(transactions)); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	simulated, rpcErr := h.simulateTransactions(id, transactions, simulationFlags, specV0_7, false)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	result := make([]SimulatedStateDiff, 0, len(simulated))
+	for _, simulatedTxn := range simulated {
+		trace := simulatedTxn.TransactionTrace
+		if revertReason := trace.RevertReason(); revertReason != "" {
+			result = append(result, SimulatedStateDiff{
+				StateDiff:    emptyStateDiff(),
+				Reverted:     true,
+				RevertReason: revertReason,
+			})
+			continue
+		}
+
+		stateDiff := trace.StateDiff
+		if stateDiff == nil {
+			stateDiff = emptyStateDiff()
+		}
+		result = append(result, SimulatedStateDiff{StateDiff: stateDiff})
+	}
+	return result, nil
+}
+
+// SimulateWithFeeCharge simulates the given transactions like SimulateTransactions, but always charges fees, so it
+// confirms that each sender can actually afford its transaction. SKIP_FEE_CHARGE is ignored. A transaction whose
+// sender can't cover its fee fails with ErrInsufficientAccountBalance rather than a generic execution error.
+func (h *Handler) SimulateWithFeeCharge(id BlockID, transactions []BroadcastedTransaction,
+	simulationFlags []SimulationFlag,
+) ([]SimulatedTransaction, *jsonrpc.Error) {
+	if rpcErr := h.checkBatchSize(len(transactions)); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	flags := slices.DeleteFunc(slices.Clone(simulationFlags), func(flag SimulationFlag) bool {
+		return flag == SkipFeeChargeFlag
+	})
+
+	simulated, rpcErr := h.simulateTransactions(id, transactions, flags, specV0_7, false)
+	if rpcErr != nil {
+		if data, ok := rpcErr.Data.(TransactionExecutionErrorData); ok && isInsufficientBalanceErr(data.ExecutionError) {
+			return nil, ErrInsufficientAccountBalance.CloneWithData(data)
+		}
+		return nil, rpcErr
+	}
+
+	// the fee is only checked against the balance again after execution, which reverts the transaction
+	for i := range simulated {
+		if revertReason := simulated[i].TransactionTrace.RevertReason(); isInsufficientBalanceRevert(revertReason) {
+			return nil, ErrInsufficientAccountBalance.CloneWithData(TransactionExecutionErrorData{
+				TransactionIndex: uint64(i),
+				ExecutionError:   revertReason,
+			})
+		}
+	}
+	return simulated, nil
+}
+
+// isInsufficientBalanceErr reports whether the blockifier's fee checks rejected a transaction before execution because
+// the sender's fee token balance doesn't cover its max fee or its resource bounds. Only the blockifier's own messages
+// match, a contract that fails with a similar message is still reported as a plain execution error.
+func isInsufficientBalanceErr(msg string) bool {
+	for _, prefix := range []string{"Max fee (", "L1 gas bounds (", "Resources bounds (", "Resource bounds ("} {
+		if strings.HasPrefix(msg, prefix) {
+			return strings.Contains(msg, "exceeds balance (") || strings.Contains(msg, "exceed balance (")
+		}
+	}
+	return false
+}
+
+// isInsufficientBalanceRevert reports whether the blockifier reverted a transaction after execution because the
+// sender's fee token balance doesn't cover its actual fee.
+func isInsufficientBalanceRevert(revertReason string) bool {
+	return strings.HasPrefix(revertReason, "Insufficient fee token balance")
 }
 
 // pre 13.1
 func (h *Handler) SimulateTransactionsV0_6(id BlockID, transactions []BroadcastedTransaction,
 	simulationFlags []SimulationFlag,
 ) ([]SimulatedTransaction, *jsonrpc.Error) {
-	return h.simulateTransactions(id, transactions, simulationFlags, true, true)
+	return h.simulateTransactions(id, transactions, simulationFlags, specV0_6, true)
 }
 
-//nolint:funlen,gocyclo
-func (h *Handler) simulateTransactions(id BlockID, transactions []BroadcastedTransaction,
-	simulationFlags []SimulationFlag, v0_6Response, errOnRevert bool,
+// SimulateWithEnv simulates the given transactions like SimulateTransactions, but in a block environment with the
+// given timestamp, gas prices and sequencer address overrides. The block number is never overridden, so the
+// revealed block hash is always that of the real block.
+func (h *Handler) SimulateWithEnv(id BlockID, transactions []BroadcastedTransaction,
+	simulationFlags []SimulationFlag, overrides *BlockOverrides,
 ) ([]SimulatedTransaction, *jsonrpc.Error) {
-	skipFeeCharge := slices.Contains(simulationFlags, SkipFeeChargeFlag)
-	skipValidate := slices.Contains(simulationFlags, SkipValidateFlag)
+	if rpcErr := h.checkBatchSize(len(transactions)); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	state, closer, rpcErr := h.stateByBlockID(&id)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	defer h.callAndLogErr(closer, "Failed to close state in juno_simulateWithEnv")
+
+	header, rpcErr := h.blockHeaderByID(&id)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
 
+	return h.simulateTransactionsOnState(state, overrides.apply(header), transactions, simulationFlags, specV0_7, false)
+}
+
+func (h *Handler) simulateTransactions(id BlockID, transactions []BroadcastedTransaction,
+	simulationFlags []SimulationFlag, spec specVersion, errOnRevert bool,
+) ([]SimulatedTransaction, *jsonrpc.Error) {
 	state, closer, rpcErr := h.stateByBlockID(&id)
 	if rpcErr != nil {
 		return nil, rpcErr
@@ -1554,6 +2430,19 @@ func (h *Handler) simulateTransactions(id BlockID, transactions []BroadcastedTra
 		return nil, rpcErr
 	}
 
+	return h.simulateTransactionsOnState(state, header, transactions, simulationFlags, spec, errOnRevert)
+}
+
+// simulateTransactionsOnState executes the given transactions on top of an already resolved state and header.
+// The caller is responsible for closing the state.
+//
+//nolint:funlen,gocyclo
+func (h *Handler) simulateTransactionsOnState(state core.StateReader, header *core.Header,
+	transactions []BroadcastedTransaction, simulationFlags []SimulationFlag, spec specVersion, errOnRevert bool,
+) ([]SimulatedTransaction, *jsonrpc.Error) {
+	skipFeeCharge := slices.Contains(simulationFlags, SkipFeeChargeFlag)
+	skipValidate := slices.Contains(simulationFlags, SkipValidateFlag)
+
 	var txns []core.Transaction
 	var classes []core.Class
 
@@ -1582,12 +2471,11 @@ func (h *Handler) simulateTransactions(id BlockID, transactions []BroadcastedTra
 		Header:                header,
 		BlockHashToBeRevealed: blockHashToBeRevealed,
 	}
-	useBlobData := !v0_6Response
 	overallFees, dataGasConsumed, traces, err := h.vm.Execute(txns, classes, paidFeesOnL1, &blockInfo,
-		state, h.bcReader.Network(), skipFeeCharge, skipValidate, errOnRevert, useBlobData)
+		state, h.bcReader.Network(), skipFeeCharge, skipValidate, errOnRevert, spec.useBlobData)
 	if err != nil {
 		if errors.Is(err, utils.ErrResourceBusy) {
-			return nil, ErrInternal.CloneWithData(throttledVMErr)
+			return nil, h.throttledVMError()
 		}
 		var txnExecutionError vm.TransactionExecutionError
 		if errors.As(err, &txnExecutionError) {
@@ -1618,7 +2506,7 @@ func (h *Handler) simulateTransactions(id BlockID, transactions []BroadcastedTra
 		}
 
 		var gasConsumed *felt.Felt
-		if !v0_6Response {
+		if spec.useBlobData {
 			dataGasFee := new(felt.Felt).Mul(dataGasConsumed[i], dataGasPrice)
 			gasConsumed = new(felt.Felt).Sub(overallFee, dataGasFee)
 		} else {
@@ -1633,10 +2521,10 @@ func (h *Handler) simulateTransactions(id BlockID, transactions []BroadcastedTra
 			DataGasPrice:    dataGasPrice,
 			OverallFee:      overallFee,
 			Unit:            utils.Ptr(feeUnit),
-			v0_6Response:    v0_6Response,
+			v0_6Response:    !spec.reportDataGas,
 		}
 
-		if !v0_6Response {
+		if spec.reportDataGas {
 			trace := traces[i]
 			executionResources := trace.TotalExecutionResources()
 			executionResources.DataAvailability = vm.NewDataAvailability(gasConsumed, dataGasConsumed[i], header.L1DAMode)
@@ -1658,7 +2546,7 @@ func (h *Handler) TraceBlockTransactions(ctx context.Context, id BlockID) ([]Tra
 		return nil, rpcErr
 	}
 
-	return h.traceBlockTransactions(ctx, block, false)
+	return h.traceBlockTransactions(ctx, block, specV0_7)
 }
 
 func (h *Handler) TraceBlockTransactionsV0_6(ctx context.Context, id BlockID) ([]TracedBlockTransaction, *jsonrpc.Error) {
@@ -1667,11 +2555,14 @@ func (h *Handler) TraceBlockTransactionsV0_6(ctx context.Context, id BlockID) ([
 		return nil, rpcErr
 	}
 
-	return h.traceBlockTransactions(ctx, block, true)
+	return h.traceBlockTransactions(ctx, block, specV0_6)
 }
 
 var traceFallbackVersion = semver.MustParse("0.12.3")
 
+// getRevealedBlockHash returns the hash of the block that the sequencer writes to the block hash contract when
+// executing the given block. The first blocks have no such block, so the hash is nil, which the VM treats as a
+// zero hash and skips writing, as the sequencer does.
 func (h *Handler) getRevealedBlockHash(blockNumber uint64) (*felt.Felt, error) {
 	const blockHashLag = 10
 	if blockNumber < blockHashLag {
@@ -1685,7 +2576,7 @@ func (h *Handler) getRevealedBlockHash(blockNumber uint64) (*felt.Felt, error) {
 	return header.Hash, nil
 }
 
-func (h *Handler) traceBlockTransactions(ctx context.Context, block *core.Block, v0_6Response bool, //nolint: gocyclo, funlen
+func (h *Handler) traceBlockTransactions(ctx context.Context, block *core.Block, spec specVersion, //nolint: gocyclo, funlen
 ) ([]TracedBlockTransaction, *jsonrpc.Error) {
 	isPending := block.Hash == nil
 	if !isPending {
@@ -1697,22 +2588,28 @@ func (h *Handler) traceBlockTransactions(ctx context.Context, block *core.Block,
 		}
 
 		if trace, hit := h.blockTraceCache.Get(traceCacheKey{
-			blockHash:    *block.Hash,
-			v0_6Response: v0_6Response,
+			blockHash: *block.Hash,
+			spec:      spec,
 		}); hit {
 			return trace, nil
 		}
 	}
 
-	state, closer, err := h.bcReader.StateAtBlockHash(block.ParentHash)
-	if err != nil {
+	state, closer, rpcErr := h.stateByBlockID(&BlockID{Hash: block.ParentHash})
+	if rpcErr != nil {
+		if rpcErr == ErrServiceBusy {
+			return nil, rpcErr
+		}
 		return nil, ErrBlockNotFound
 	}
 	defer h.callAndLogErr(closer, "Failed to close state in traceBlockTransactions")
 
+	// the head state is only used to look up declared classes, it is opened outside of the open state limit so
+	// that a trace never needs two slots
 	var (
 		headState       core.StateReader
 		headStateCloser blockchain.StateCloser
+		err             error
 	)
 	if isPending {
 		headState, headStateCloser, err = h.bcReader.PendingState()
@@ -1752,12 +2649,11 @@ func (h *Handler) traceBlockTransactions(ctx context.Context, block *core.Block,
 		BlockHashToBeRevealed: blockHashToBeRevealed,
 	}
 
-	useBlobData := !v0_6Response
 	overallFees, dataGasConsumed, traces, err := h.vm.Execute(block.Transactions, classes, paidFeesOnL1, &blockInfo, state, network, false,
-		false, false, useBlobData)
+		false, false, spec.useBlobData)
 	if err != nil {
 		if errors.Is(err, utils.ErrResourceBusy) {
-			return nil, ErrInternal.CloneWithData(throttledVMErr)
+			return nil, h.throttledVMError()
 		}
 		// Since we are tracing an existing block, we know that there should be no errors during execution. If we encounter any,
 		// report them as unexpected errors
@@ -1766,7 +2662,7 @@ func (h *Handler) traceBlockTransactions(ctx context.Context, block *core.Block,
 
 	var result []TracedBlockTransaction
 	for index, trace := range traces {
-		if !v0_6Response {
+		if spec.reportDataGas {
 			feeUnit := feeUnit(block.Transactions[index])
 
 			gasPrice := header.GasPrice
@@ -1803,8 +2699,8 @@ func (h *Handler) traceBlockTransactions(ctx context.Context, block *core.Block,
 
 	if !isPending {
 		h.blockTraceCache.Add(traceCacheKey{
-			blockHash:    *block.Hash,
-			v0_6Response: v0_6Response,
+			blockHash: *block.Hash,
+			spec:      spec,
 		}, result)
 	}
 
@@ -1836,6 +2732,28 @@ func (h *Handler) fetchTraces(ctx context.Context, blockHash *felt.Felt) ([]Trac
 	return traces, nil
 }
 
+// checkBatchSize is shared by the juno handlers that accept a list of inputs, so that the limit is enforced
+// uniformly. The spec methods are not limited, their errors are defined by the spec.
+func (h *Handler) checkBatchSize(size int) *jsonrpc.Error {
+	if uint(size) > h.maxBatchSize {
+		return jsonrpc.Err(jsonrpc.InvalidParams, fmt.Sprintf("batch size %d exceeds the limit of %d", size, h.maxBatchSize))
+	}
+	return nil
+}
+
+// throttledVMError builds the error returned when the VM rejects a request because it is busy. The suggested
+// retry delay grows with the number of requests queued for the VM, when the VM exposes it.
+func (h *Handler) throttledVMError() *jsonrpc.Error {
+	queueLen := 0
+	if queue, ok := h.vm.(interface{ QueueLen() int }); ok {
+		queueLen = queue.QueueLen()
+	}
+	return ErrInternal.CloneWithData(ThrottledVMErrData{
+		Message:      throttledVMErr,
+		RetryAfterMs: uint64(queueLen+1) * throttledVMRetryAfterMs,
+	})
+}
+
 func (h *Handler) callAndLogErr(f func() error, msg string) {
 	if err := f(); err != nil {
 		h.log.Errorw(msg, "err", err)
@@ -1843,11 +2761,11 @@ func (h *Handler) callAndLogErr(f func() error, msg string) {
 }
 
 func (h *Handler) SpecVersion() (string, *jsonrpc.Error) {
-	return "0.7.0", nil
+	return specV0_7.version, nil
 }
 
 func (h *Handler) SpecVersionV0_6() (string, *jsonrpc.Error) {
-	return "0.6.0", nil
+	return specV0_6.version, nil
 }
 
 func (h *Handler) SubscribeNewHeads(ctx context.Context) (uint64, *jsonrpc.Error) {
@@ -1971,6 +2889,16 @@ func (h *Handler) Methods() ([]jsonrpc.Method, string) { //nolint: funlen
 			Params:  []jsonrpc.Parameter{{Name: "block_id"}},
 			Handler: h.StateUpdate,
 		},
+		{
+			Name:    "juno_isClassDeclared",
+			Params:  []jsonrpc.Parameter{{Name: "class_hash"}, {Name: "block_id"}},
+			Handler: h.IsClassDeclared,
+		},
+		{
+			Name:    "juno_getDeclaredClasses",
+			Params:  []jsonrpc.Parameter{{Name: "block_id"}},
+			Handler: h.DeclaredClasses,
+		},
 		{
 			Name:    "starknet_syncing",
 			Handler: h.Syncing,
@@ -1985,6 +2913,11 @@ func (h *Handler) Methods() ([]jsonrpc.Method, string) { //nolint: funlen
 			Params:  []jsonrpc.Parameter{{Name: "contract_address"}, {Name: "key"}, {Name: "block_id"}},
 			Handler: h.StorageAt,
 		},
+		{
+			Name:    "juno_getStorageAtMany",
+			Params:  []jsonrpc.Parameter{{Name: "contract_address"}, {Name: "keys"}, {Name: "block_id"}},
+			Handler: h.StorageAtMany,
+		},
 		{
 			Name:    "starknet_getClassHashAt",
 			Params:  []jsonrpc.Parameter{{Name: "block_id"}, {Name: "contract_address"}},
@@ -2000,6 +2933,11 @@ func (h *Handler) Methods() ([]jsonrpc.Method, string) { //nolint: funlen
 			Params:  []jsonrpc.Parameter{{Name: "block_id"}, {Name: "contract_address"}},
 			Handler: h.ClassAt,
 		},
+		{
+			Name:    "starknet_getCompiledCasm",
+			Params:  []jsonrpc.Parameter{{Name: "class_hash"}},
+			Handler: h.GetCompiledCasm,
+		},
 		{
 			Name:    "starknet_addInvokeTransaction",
 			Params:  []jsonrpc.Parameter{{Name: "invoke_transaction"}},
@@ -2034,26 +2972,119 @@ func (h *Handler) Methods() ([]jsonrpc.Method, string) { //nolint: funlen
 			Params:  []jsonrpc.Parameter{{Name: "request"}, {Name: "block_id"}},
 			Handler: h.Call,
 		},
+		{
+			Name:    "juno_callWithUsage",
+			Params:  []jsonrpc.Parameter{{Name: "request"}, {Name: "block_id"}},
+			Handler: h.CallWithUsage,
+		},
+		{
+			Name:    "juno_callWithCaller",
+			Params:  []jsonrpc.Parameter{{Name: "request"}, {Name: "block_id"}, {Name: "caller_address"}},
+			Handler: h.CallWithCaller,
+		},
+		{
+			Name:    "juno_callAtTimestamp",
+			Params:  []jsonrpc.Parameter{{Name: "request"}, {Name: "block_id"}, {Name: "timestamp"}},
+			Handler: h.CallAtTimestamp,
+		},
+		{
+			Name:    "juno_callWithGasContext",
+			Params:  []jsonrpc.Parameter{{Name: "request"}, {Name: "block_id"}, {Name: "l1_gas_price", Optional: true}},
+			Handler: h.CallWithGasContext,
+		},
+		{
+			Name:    "juno_callWithLimit",
+			Params:  []jsonrpc.Parameter{{Name: "request"}, {Name: "block_id"}, {Name: "max_steps", Optional: true}},
+			Handler: h.CallWithLimit,
+		},
+		{
+			Name:    "juno_callMany",
+			Params:  []jsonrpc.Parameter{{Name: "requests"}, {Name: "block_id"}},
+			Handler: h.CallMany,
+		},
+		{
+			Name:    "juno_callWithOverrides",
+			Params:  []jsonrpc.Parameter{{Name: "request"}, {Name: "block_id"}, {Name: "overrides"}},
+			Handler: h.CallWithOverrides,
+		},
 		{
 			Name:    "starknet_estimateFee",
 			Params:  []jsonrpc.Parameter{{Name: "request"}, {Name: "simulation_flags"}, {Name: "block_id"}},
 			Handler: h.EstimateFee,
 		},
+		{
+			Name:    "juno_estimateFeeParallel",
+			Params:  []jsonrpc.Parameter{{Name: "request"}, {Name: "simulation_flags"}, {Name: "block_id"}},
+			Handler: h.EstimateFeeParallel,
+		},
+		{
+			Name:    "juno_estimateMessageFeeMany",
+			Params:  []jsonrpc.Parameter{{Name: "messages"}, {Name: "block_id"}},
+			Handler: h.EstimateMessageFeeMany,
+		},
+		{
+			Name:    "juno_estimateFeePartial",
+			Params:  []jsonrpc.Parameter{{Name: "request"}, {Name: "simulation_flags"}, {Name: "block_id"}},
+			Handler: h.EstimateFeePartial,
+		},
 		{
 			Name:    "starknet_estimateMessageFee",
 			Params:  []jsonrpc.Parameter{{Name: "message"}, {Name: "block_id"}},
 			Handler: h.EstimateMessageFee,
 		},
+		{
+			Name:    "juno_estimateFeeRange",
+			Params:  []jsonrpc.Parameter{{Name: "request"}, {Name: "simulation_flags"}, {Name: "block_id"}},
+			Handler: h.EstimateFeeRange,
+		},
+		{
+			Name:    "juno_estimateBundleFee",
+			Params:  []jsonrpc.Parameter{{Name: "request"}, {Name: "block_id"}},
+			Handler: h.EstimateBundleFee,
+		},
+		{
+			Name:    "juno_estimateFeeWithRevertTraces",
+			Params:  []jsonrpc.Parameter{{Name: "request"}, {Name: "simulation_flags"}, {Name: "block_id"}},
+			Handler: h.EstimateFeeWithRevertTraces,
+		},
+		{
+			Name:    "juno_estimateFeeMatrix",
+			Params:  []jsonrpc.Parameter{{Name: "request"}, {Name: "simulation_flag_sets"}, {Name: "block_id"}},
+			Handler: h.EstimateFeeMatrix,
+		},
 		{
 			Name:    "starknet_traceTransaction",
 			Params:  []jsonrpc.Parameter{{Name: "transaction_hash"}},
 			Handler: h.TraceTransaction,
 		},
+		{
+			Name:    "juno_transactionStorageKeys",
+			Params:  []jsonrpc.Parameter{{Name: "transaction_hash"}},
+			Handler: h.TransactionStorageKeys,
+		},
 		{
 			Name:    "starknet_simulateTransactions",
 			Params:  []jsonrpc.Parameter{{Name: "block_id"}, {Name: "transactions"}, {Name: "simulation_flags"}},
 			Handler: h.SimulateTransactions,
 		},
+		{
+			Name:    "juno_simulateWithFeeCharge",
+			Params:  []jsonrpc.Parameter{{Name: "block_id"}, {Name: "transactions"}, {Name: "simulation_flags"}},
+			Handler: h.SimulateWithFeeCharge,
+		},
+		{
+			Name:    "juno_simulateWithStateDiff",
+			Params:  []jsonrpc.Parameter{{Name: "block_id"}, {Name: "transactions"}, {Name: "simulation_flags"}},
+			Handler: h.SimulateWithStateDiff,
+		},
+		{
+			Name: "juno_simulateWithEnv",
+			Params: []jsonrpc.Parameter{
+				{Name: "block_id"}, {Name: "transactions"}, {Name: "simulation_flags"},
+				{Name: "block_overrides", Optional: true},
+			},
+			Handler: h.SimulateWithEnv,
+		},
 		{
 			Name:    "starknet_traceBlockTransactions",
 			Params:  []jsonrpc.Parameter{{Name: "block_id"}},
@@ -6,9 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/big"
 	"math/rand"
 	"net"
 	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -28,6 +33,7 @@ import (
 	"github.com/NethermindEth/juno/utils"
 	"github.com/NethermindEth/juno/vm"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/sourcegraph/conc"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
@@ -36,6 +42,11 @@ import (
 
 func nopCloser() error { return nil }
 
+// callableClass handles every selector through its __default__ entry point
+var callableClass = &core.DeclaredClass{Class: &core.Cairo0Class{
+	Externals: []core.EntryPoint{{Selector: new(felt.Felt)}},
+}}
+
 func TestChainId(t *testing.T) {
 	for _, n := range []utils.Network{utils.Mainnet, utils.Goerli, utils.Goerli2, utils.Integration} {
 		t.Run(n.String(), func(t *testing.T) {
@@ -51,6 +62,20 @@ func TestChainId(t *testing.T) {
 			assert.Equal(t, n.L2ChainIDFelt(), cID)
 		})
 	}
+
+	t.Run("custom network", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		t.Cleanup(mockCtrl.Finish)
+
+		custom := &utils.Network{Name: "custom", L2ChainID: "SN_AWESOME"}
+		mockReader := mocks.NewMockReader(mockCtrl)
+		mockReader.EXPECT().Network().Return(custom)
+		handler := rpc.New(mockReader, nil, nil, "", nil)
+
+		cID, err := handler.ChainID()
+		require.Nil(t, err)
+		assert.Equal(t, new(felt.Felt).SetBytes([]byte("SN_AWESOME")), cID)
+	})
 }
 
 func TestBlockNumber(t *testing.T) {
@@ -1739,6 +1764,63 @@ func TestStateUpdate(t *testing.T) {
 	})
 }
 
+func TestDeclaredClasses(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+
+	mockReader := mocks.NewMockReader(mockCtrl)
+	handler := rpc.New(mockReader, nil, nil, "", nil)
+
+	cairo0ClassHash := utils.HexToFelt(t, "0x9")
+	otherCairo0ClassHash := utils.HexToFelt(t, "0x1")
+	cairo1ClassHash := utils.HexToFelt(t, "0x2")
+	otherCairo1ClassHash := utils.HexToFelt(t, "0x3")
+	update := &core.StateUpdate{
+		StateDiff: &core.StateDiff{
+			DeclaredV0Classes: []*felt.Felt{cairo0ClassHash, otherCairo0ClassHash},
+			DeclaredV1Classes: map[felt.Felt]*felt.Felt{
+				*otherCairo1ClassHash: utils.HexToFelt(t, "0x33"),
+				*cairo1ClassHash:      utils.HexToFelt(t, "0x22"),
+			},
+		},
+	}
+	// Cairo 0 classes keep their declaration order, Cairo 1 classes are sorted by hash
+	want := []*felt.Felt{cairo0ClassHash, otherCairo0ClassHash, cairo1ClassHash, otherCairo1ClassHash}
+
+	t.Run("block not found", func(t *testing.T) {
+		mockReader.EXPECT().StateUpdateByNumber(uint64(1)).Return(nil, db.ErrKeyNotFound)
+
+		classHashes, rpcErr := handler.DeclaredClasses(rpc.BlockID{Number: 1})
+		assert.Nil(t, classHashes)
+		assert.Equal(t, rpc.ErrBlockNotFound, rpcErr)
+	})
+
+	t.Run("block with declarations", func(t *testing.T) {
+		mockReader.EXPECT().StateUpdateByHash(utils.HexToFelt(t, "0xabc")).Return(update, nil)
+
+		classHashes, rpcErr := handler.DeclaredClasses(rpc.BlockID{Hash: utils.HexToFelt(t, "0xabc")})
+		require.Nil(t, rpcErr)
+		assert.Equal(t, want, classHashes)
+	})
+
+	t.Run("block without declarations", func(t *testing.T) {
+		mockReader.EXPECT().Height().Return(uint64(2), nil)
+		mockReader.EXPECT().StateUpdateByNumber(uint64(2)).Return(&core.StateUpdate{StateDiff: core.EmptyStateDiff()}, nil)
+
+		classHashes, rpcErr := handler.DeclaredClasses(rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+		assert.Empty(t, classHashes)
+	})
+
+	t.Run("pending", func(t *testing.T) {
+		mockReader.EXPECT().Pending().Return(blockchain.Pending{StateUpdate: update}, nil)
+
+		classHashes, rpcErr := handler.DeclaredClasses(rpc.BlockID{Pending: true})
+		require.Nil(t, rpcErr)
+		assert.Equal(t, want, classHashes)
+	})
+}
+
 func TestSyncing(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	t.Cleanup(mockCtrl.Finish)
@@ -1848,13 +1930,42 @@ func TestNonce(t *testing.T) {
 
 	t.Run("non-existent contract", func(t *testing.T) {
 		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
-		mockState.EXPECT().ContractNonce(&felt.Zero).Return(nil, errors.New("non-existent contract"))
+		mockState.EXPECT().ContractNonce(&felt.Zero).Return(nil, db.ErrKeyNotFound)
 
 		nonce, rpcErr := handler.Nonce(rpc.BlockID{Latest: true}, felt.Zero)
 		require.Nil(t, nonce)
 		assert.Equal(t, rpc.ErrContractNotFound, rpcErr)
 	})
 
+	t.Run("contract deployed after the requested block", func(t *testing.T) {
+		historicalState := mocks.NewMockStateHistoryReader(mockCtrl)
+		mockReader.EXPECT().StateAtBlockNumber(uint64(1)).Return(historicalState, nopCloser, nil)
+		historicalState.EXPECT().ContractNonce(&felt.Zero).Return(nil, core.ErrContractNotDeployedYet)
+
+		nonce, rpcErr := handler.Nonce(rpc.BlockID{Number: 1}, felt.Zero)
+		require.Nil(t, nonce)
+		assert.Equal(t, rpc.ErrContractNotFound.CloneWithData("contract is not deployed yet at the requested block"), rpcErr)
+	})
+
+	t.Run("contract that never existed at a historical block", func(t *testing.T) {
+		historicalState := mocks.NewMockStateHistoryReader(mockCtrl)
+		mockReader.EXPECT().StateAtBlockNumber(uint64(1)).Return(historicalState, nopCloser, nil)
+		historicalState.EXPECT().ContractNonce(&felt.Zero).Return(nil, db.ErrKeyNotFound)
+
+		nonce, rpcErr := handler.Nonce(rpc.BlockID{Number: 1}, felt.Zero)
+		require.Nil(t, nonce)
+		assert.Equal(t, rpc.ErrContractNotFound, rpcErr)
+	})
+
+	t.Run("state read failure", func(t *testing.T) {
+		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+		mockState.EXPECT().ContractNonce(&felt.Zero).Return(nil, errors.New("disk failure"))
+
+		nonce, rpcErr := handler.Nonce(rpc.BlockID{Latest: true}, felt.Zero)
+		require.Nil(t, nonce)
+		assert.Equal(t, rpc.ErrInternal.Code, rpcErr.Code)
+	})
+
 	expectedNonce := new(felt.Felt).SetUint64(1)
 
 	t.Run("blockID - latest", func(t *testing.T) {
@@ -1921,23 +2032,64 @@ func TestStorageAt(t *testing.T) {
 
 	t.Run("non-existent contract", func(t *testing.T) {
 		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
-		mockState.EXPECT().ContractStorage(gomock.Any(), gomock.Any()).Return(nil, errors.New("non-existent contract"))
+		mockState.EXPECT().ContractClassHash(&felt.Zero).Return(nil, db.ErrKeyNotFound)
 
 		storage, rpcErr := handler.StorageAt(felt.Zero, felt.Zero, rpc.BlockID{Latest: true})
 		require.Nil(t, storage)
 		assert.Equal(t, rpc.ErrContractNotFound, rpcErr)
 	})
 
-	t.Run("non-existent key", func(t *testing.T) {
+	t.Run("contract deployed after the requested block", func(t *testing.T) {
+		historicalState := mocks.NewMockStateHistoryReader(mockCtrl)
+		mockReader.EXPECT().StateAtBlockNumber(uint64(1)).Return(historicalState, nopCloser, nil)
+		historicalState.EXPECT().ContractClassHash(&felt.Zero).Return(nil, core.ErrContractNotDeployedYet)
+
+		storage, rpcErr := handler.StorageAt(felt.Zero, felt.Zero, rpc.BlockID{Number: 1})
+		require.Nil(t, storage)
+		assert.Equal(t, rpc.ErrContractNotFound.CloneWithData("contract is not deployed yet at the requested block"), rpcErr)
+	})
+
+	t.Run("failed class hash lookup", func(t *testing.T) {
 		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
-		mockState.EXPECT().ContractStorage(gomock.Any(), gomock.Any()).Return(&felt.Zero, errors.New("non-existent key"))
+		mockState.EXPECT().ContractClassHash(&felt.Zero).Return(nil, errors.New("some error"))
 
 		storage, rpcErr := handler.StorageAt(felt.Zero, felt.Zero, rpc.BlockID{Latest: true})
 		require.Nil(t, storage)
-		assert.Equal(t, rpc.ErrContractNotFound, rpcErr)
+		assert.Equal(t, rpc.ErrInternal.Code, rpcErr.Code)
+	})
+
+	t.Run("non-existent key of a deployed contract", func(t *testing.T) {
+		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+		mockState.EXPECT().ContractClassHash(&felt.Zero).Return(new(felt.Felt), nil)
+		mockState.EXPECT().ContractStorage(gomock.Any(), gomock.Any()).Return(nil, db.ErrKeyNotFound)
+
+		storage, rpcErr := handler.StorageAt(felt.Zero, felt.Zero, rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+		assert.Equal(t, &felt.Zero, storage)
+	})
+
+	t.Run("unset key of a deployed contract", func(t *testing.T) {
+		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+		mockState.EXPECT().ContractClassHash(&felt.Zero).Return(new(felt.Felt), nil)
+		mockState.EXPECT().ContractStorage(gomock.Any(), gomock.Any()).Return(&felt.Zero, nil)
+
+		storage, rpcErr := handler.StorageAt(felt.Zero, felt.Zero, rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+		assert.Equal(t, &felt.Zero, storage)
+	})
+
+	t.Run("failed storage lookup", func(t *testing.T) {
+		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+		mockState.EXPECT().ContractClassHash(&felt.Zero).Return(new(felt.Felt), nil)
+		mockState.EXPECT().ContractStorage(gomock.Any(), gomock.Any()).Return(nil, errors.New("some error"))
+
+		storage, rpcErr := handler.StorageAt(felt.Zero, felt.Zero, rpc.BlockID{Latest: true})
+		require.Nil(t, storage)
+		assert.Equal(t, rpc.ErrInternal.Code, rpcErr.Code)
 	})
 
 	expectedStorage := new(felt.Felt).SetUint64(1)
+	mockState.EXPECT().ContractClassHash(&felt.Zero).Return(new(felt.Felt), nil).Times(3)
 
 	t.Run("blockID - latest", func(t *testing.T) {
 		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
@@ -1967,6 +2119,98 @@ func TestStorageAt(t *testing.T) {
 	})
 }
 
+func TestStorageAtMany(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	handler := rpc.New(mockReader, nil, nil, "", utils.NewNopZapLogger())
+
+	address := *utils.HexToFelt(t, "0x123")
+	undeployedAddress := *utils.HexToFelt(t, "0x456")
+	setKey := *utils.HexToFelt(t, "0x1")
+	unsetKey := *utils.HexToFelt(t, "0x2")
+	otherSetKey := *utils.HexToFelt(t, "0x3")
+
+	txn, err := pebble.NewMemTest(t).NewTransaction(true)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, txn.Discard())
+	})
+	contract, err := core.DeployContract(&address, utils.HexToFelt(t, "0xc1a55"), txn)
+	require.NoError(t, err)
+	require.NoError(t, contract.UpdateStorage(map[felt.Felt]*felt.Felt{
+		setKey:      new(felt.Felt).SetUint64(10),
+		otherSetKey: new(felt.Felt).SetUint64(30),
+	}, func(_, _ *felt.Felt) error { return nil }))
+	state := core.NewState(txn)
+
+	t.Run("block not found", func(t *testing.T) {
+		mockReader.EXPECT().HeadState().Return(nil, nil, db.ErrKeyNotFound)
+
+		values, rpcErr := handler.StorageAtMany(address, []felt.Felt{setKey}, rpc.BlockID{Latest: true})
+		assert.Nil(t, values)
+		assert.Equal(t, rpc.ErrBlockNotFound, rpcErr)
+	})
+
+	t.Run("contract not found", func(t *testing.T) {
+		mockReader.EXPECT().HeadState().Return(state, nopCloser, nil)
+
+		values, rpcErr := handler.StorageAtMany(undeployedAddress, []felt.Felt{setKey}, rpc.BlockID{Latest: true})
+		assert.Nil(t, values)
+		assert.Equal(t, rpc.ErrContractNotFound, rpcErr)
+	})
+
+	t.Run("contract deployed after the requested block", func(t *testing.T) {
+		historicalState := mocks.NewMockStateHistoryReader(mockCtrl)
+		mockReader.EXPECT().StateAtBlockNumber(uint64(1)).Return(historicalState, nopCloser, nil)
+		historicalState.EXPECT().ContractClassHash(&address).Return(nil, core.ErrContractNotDeployedYet)
+
+		values, rpcErr := handler.StorageAtMany(address, []felt.Felt{setKey}, rpc.BlockID{Number: 1})
+		assert.Nil(t, values)
+		assert.Equal(t, rpc.ErrContractNotFound.CloneWithData("contract is not deployed yet at the requested block"), rpcErr)
+	})
+
+	t.Run("failed class hash lookup", func(t *testing.T) {
+		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+		mockState.EXPECT().ContractClassHash(&address).Return(nil, errors.New("some error"))
+
+		values, rpcErr := handler.StorageAtMany(address, []felt.Felt{setKey}, rpc.BlockID{Latest: true})
+		assert.Nil(t, values)
+		assert.Equal(t, rpc.ErrInternal.Code, rpcErr.Code)
+	})
+
+	t.Run("failed storage read", func(t *testing.T) {
+		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+		mockState.EXPECT().ContractClassHash(&address).Return(new(felt.Felt), nil)
+		mockState.EXPECT().ContractStorageValues(&address, []*felt.Felt{&setKey}).Return(nil, errors.New("some error"))
+
+		values, rpcErr := handler.StorageAtMany(address, []felt.Felt{setKey}, rpc.BlockID{Latest: true})
+		assert.Nil(t, values)
+		assert.Equal(t, rpc.ErrInternal.Code, rpcErr.Code)
+	})
+
+	t.Run("set and unset keys", func(t *testing.T) {
+		mockReader.EXPECT().HeadState().Return(state, nopCloser, nil)
+
+		values, rpcErr := handler.StorageAtMany(address, []felt.Felt{otherSetKey, unsetKey, setKey},
+			rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+		assert.Equal(t, []*felt.Felt{new(felt.Felt).SetUint64(30), &felt.Zero, new(felt.Felt).SetUint64(10)}, values)
+	})
+
+	t.Run("too many keys", func(t *testing.T) {
+		handler := rpc.New(mockReader, nil, nil, "", utils.NewNopZapLogger()).WithMaxBatchSize(2)
+
+		values, rpcErr := handler.StorageAtMany(address, []felt.Felt{setKey, unsetKey, otherSetKey},
+			rpc.BlockID{Latest: true})
+		assert.Nil(t, values)
+		require.NotNil(t, rpcErr)
+		assert.Equal(t, jsonrpc.InvalidParams, rpcErr.Code)
+	})
+}
+
 func TestClassHashAt(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	t.Cleanup(mockCtrl.Finish)
@@ -2038,6 +2282,33 @@ func TestClassHashAt(t *testing.T) {
 		require.Nil(t, rpcErr)
 		assert.Equal(t, expectedClassHash, classHash)
 	})
+
+	t.Run("historical block", func(t *testing.T) {
+		deployed := new(felt.Felt).SetUint64(1)
+		undeployed := new(felt.Felt).SetUint64(2)
+		historicalState := mocks.NewMockStateHistoryReader(mockCtrl)
+		historicalState.EXPECT().ContractClassHash(deployed).Return(expectedClassHash, nil)
+		historicalState.EXPECT().ContractClassHash(undeployed).Return(nil, db.ErrKeyNotFound)
+		mockReader.EXPECT().StateAtBlockNumber(uint64(5)).Return(historicalState, nopCloser, nil).Times(2)
+
+		classHash, rpcErr := handler.ClassHashAt(rpc.BlockID{Number: 5}, *deployed)
+		require.Nil(t, rpcErr)
+		assert.Equal(t, expectedClassHash, classHash)
+
+		classHash, rpcErr = handler.ClassHashAt(rpc.BlockID{Number: 5}, *undeployed)
+		require.Nil(t, classHash)
+		assert.Equal(t, rpc.ErrContractNotFound, rpcErr)
+	})
+
+	t.Run("contract deployed after the requested block", func(t *testing.T) {
+		historicalState := mocks.NewMockStateHistoryReader(mockCtrl)
+		mockReader.EXPECT().StateAtBlockNumber(uint64(1)).Return(historicalState, nopCloser, nil)
+		historicalState.EXPECT().ContractClassHash(&felt.Zero).Return(nil, core.ErrContractNotDeployedYet)
+
+		classHash, rpcErr := handler.ClassHashAt(rpc.BlockID{Number: 1}, felt.Zero)
+		require.Nil(t, classHash)
+		assert.Equal(t, rpc.ErrContractNotFound.CloneWithData("contract is not deployed yet at the requested block"), rpcErr)
+	})
 }
 
 func assertEqualCairo0Class(t *testing.T, cairo0Class *core.Cairo0Class, class *rpc.Class) {
@@ -2139,6 +2410,93 @@ func TestClass(t *testing.T) {
 		cairo0Class := coreClass.(*core.Cairo0Class)
 		assertEqualCairo0Class(t, cairo0Class, class)
 	})
+
+	t.Run("class declared in pending", func(t *testing.T) {
+		pendingClassHash := utils.HexToFelt(t, "0xDEADBEEF")
+		pendingClass := &core.Cairo0Class{
+			Abi:          json.RawMessage(`[]`),
+			Program:      "program",
+			Externals:    []core.EntryPoint{{Selector: utils.HexToFelt(t, "0x1"), Offset: utils.HexToFelt(t, "0x2")}},
+			L1Handlers:   []core.EntryPoint{},
+			Constructors: []core.EntryPoint{},
+		}
+		pendingState := blockchain.NewPendingState(core.EmptyStateDiff(), map[felt.Felt]core.Class{
+			*pendingClassHash: pendingClass,
+		}, mockState)
+		mockReader.EXPECT().PendingState().Return(pendingState, nopCloser, nil)
+
+		class, rpcErr := handler.Class(rpc.BlockID{Pending: true}, *pendingClassHash)
+		require.Nil(t, rpcErr)
+		assertEqualCairo0Class(t, pendingClass, class)
+
+		// the class is not visible until the pending block is committed
+		_, rpcErr = handler.Class(latest, *pendingClassHash)
+		assert.Equal(t, rpc.ErrClassHashNotFound, rpcErr)
+	})
+}
+
+func TestIsClassDeclared(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	handler := rpc.New(mockReader, nil, nil, "", utils.NewNopZapLogger())
+
+	declaredClassHash := utils.HexToFelt(t, "0x1")
+	undeclaredClassHash := utils.HexToFelt(t, "0x2")
+	pendingClassHash := utils.HexToFelt(t, "0x3")
+	mockState.EXPECT().Class(declaredClassHash).Return(&core.DeclaredClass{Class: &core.Cairo1Class{}}, nil).AnyTimes()
+	mockState.EXPECT().Class(undeclaredClassHash).Return(nil, db.ErrKeyNotFound).AnyTimes()
+	mockState.EXPECT().Class(pendingClassHash).Return(nil, db.ErrKeyNotFound).AnyTimes()
+
+	t.Run("block not found", func(t *testing.T) {
+		mockReader.EXPECT().StateAtBlockNumber(uint64(7)).Return(nil, nil, db.ErrKeyNotFound)
+
+		_, rpcErr := handler.IsClassDeclared(*declaredClassHash, rpc.BlockID{Number: 7})
+		assert.Equal(t, rpc.ErrBlockNotFound, rpcErr)
+	})
+
+	t.Run("latest", func(t *testing.T) {
+		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil).Times(3)
+
+		for classHash, declared := range map[*felt.Felt]bool{
+			declaredClassHash:   true,
+			undeclaredClassHash: false,
+			pendingClassHash:    false,
+		} {
+			isDeclared, rpcErr := handler.IsClassDeclared(*classHash, rpc.BlockID{Latest: true})
+			require.Nil(t, rpcErr)
+			assert.Equal(t, declared, isDeclared, classHash.String())
+		}
+	})
+
+	t.Run("pending", func(t *testing.T) {
+		pendingState := blockchain.NewPendingState(core.EmptyStateDiff(), map[felt.Felt]core.Class{
+			*pendingClassHash: &core.Cairo1Class{},
+		}, mockState)
+		mockReader.EXPECT().PendingState().Return(pendingState, nopCloser, nil).Times(3)
+
+		for classHash, declared := range map[*felt.Felt]bool{
+			declaredClassHash:   true,
+			undeclaredClassHash: false,
+			pendingClassHash:    true,
+		} {
+			isDeclared, rpcErr := handler.IsClassDeclared(*classHash, rpc.BlockID{Pending: true})
+			require.Nil(t, rpcErr)
+			assert.Equal(t, declared, isDeclared, classHash.String())
+		}
+	})
+
+	t.Run("state error", func(t *testing.T) {
+		failingClassHash := utils.HexToFelt(t, "0x4")
+		mockState.EXPECT().Class(failingClassHash).Return(nil, errors.New("some error"))
+		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+
+		_, rpcErr := handler.IsClassDeclared(*failingClassHash, rpc.BlockID{Latest: true})
+		require.NotNil(t, rpcErr)
+		assert.Equal(t, rpc.ErrInternal.Code, rpcErr.Code)
+	})
 }
 
 func TestClassAt(t *testing.T) {
@@ -2193,6 +2551,110 @@ func TestClassAt(t *testing.T) {
 	})
 }
 
+func TestGetCompiledCasm(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil).AnyTimes()
+	handler := rpc.New(mockReader, nil, nil, "", utils.NewNopZapLogger())
+
+	t.Run("sierra class", func(t *testing.T) {
+		classHash := new(felt.Felt).SetUint64(1)
+		mockState.EXPECT().Class(classHash).Return(&core.DeclaredClass{Class: &core.Cairo1Class{
+			Compiled: &core.CompiledClass{
+				Bytecode:        []*felt.Felt{new(felt.Felt).SetUint64(0xa), new(felt.Felt).SetUint64(0xb)},
+				CompilerVersion: "2.6.0",
+				Hints:           json.RawMessage(`[[0,[{"AllocSegment":{"dst":{"register":"AP","offset":0}}}]]]`),
+				PythonicHints:   json.RawMessage(`[[0,["memory[ap + 0] = segments.add()"]]]`),
+				Prime:           new(big.Int).SetUint64(0x11),
+				External: []core.CompiledEntryPoint{
+					{Selector: new(felt.Felt).SetUint64(0x2), Offset: 1, Builtins: []string{"range_check"}},
+				},
+				Constructor:            []core.CompiledEntryPoint{{Selector: new(felt.Felt).SetUint64(0x3), Builtins: []string{}}},
+				BytecodeSegmentLengths: core.SegmentLengths{Length: 2},
+			},
+		}}, nil)
+
+		casm, rpcErr := handler.GetCompiledCasm(*classHash)
+		require.Nil(t, rpcErr)
+		assert.JSONEq(t, `{
+			"entry_points_by_type": {
+				"CONSTRUCTOR": [{"selector": "0x3", "offset": 0, "builtins": []}],
+				"EXTERNAL": [{"selector": "0x2", "offset": 1, "builtins": ["range_check"]}],
+				"L1_HANDLER": []
+			},
+			"bytecode": ["0xa", "0xb"],
+			"prime": "0x11",
+			"compiler_version": "2.6.0",
+			"hints": [[0, [{"AllocSegment": {"dst": {"register": "AP", "offset": 0}}}]]],
+			"pythonic_hints": [[0, ["memory[ap + 0] = segments.add()"]]],
+			"bytecode_segment_lengths": 2
+		}`, string(casm))
+	})
+
+	t.Run("nested bytecode segment lengths", func(t *testing.T) {
+		classHash := new(felt.Felt).SetUint64(6)
+		mockState.EXPECT().Class(classHash).Return(&core.DeclaredClass{Class: &core.Cairo1Class{
+			Compiled: &core.CompiledClass{
+				Prime: new(big.Int).SetUint64(0x11),
+				BytecodeSegmentLengths: core.SegmentLengths{
+					Children: []core.SegmentLengths{
+						{Length: 1},
+						{Children: []core.SegmentLengths{{Length: 2}, {Length: 3}}},
+					},
+				},
+			},
+		}}, nil)
+
+		casm, rpcErr := handler.GetCompiledCasm(*classHash)
+		require.Nil(t, rpcErr)
+
+		var compiled struct {
+			BytecodeSegmentLengths json.RawMessage `json:"bytecode_segment_lengths"`
+		}
+		require.NoError(t, json.Unmarshal(casm, &compiled))
+		assert.JSONEq(t, `[1, [2, 3]]`, string(compiled.BytecodeSegmentLengths))
+	})
+
+	t.Run("sierra class without compiled casm", func(t *testing.T) {
+		classHash := new(felt.Felt).SetUint64(2)
+		mockState.EXPECT().Class(classHash).Return(&core.DeclaredClass{Class: &core.Cairo1Class{}}, nil)
+
+		casm, rpcErr := handler.GetCompiledCasm(*classHash)
+		assert.Nil(t, casm)
+		assert.Equal(t, rpc.ErrInternal.Code, rpcErr.Code)
+	})
+
+	t.Run("legacy class", func(t *testing.T) {
+		classHash := new(felt.Felt).SetUint64(3)
+		mockState.EXPECT().Class(classHash).Return(&core.DeclaredClass{Class: &core.Cairo0Class{}}, nil)
+
+		casm, rpcErr := handler.GetCompiledCasm(*classHash)
+		assert.Nil(t, casm)
+		assert.Equal(t, rpc.ErrNoCompiledCasm, rpcErr)
+	})
+
+	t.Run("unknown class hash", func(t *testing.T) {
+		classHash := new(felt.Felt).SetUint64(4)
+		mockState.EXPECT().Class(classHash).Return(nil, db.ErrKeyNotFound)
+
+		casm, rpcErr := handler.GetCompiledCasm(*classHash)
+		assert.Nil(t, casm)
+		assert.Equal(t, rpc.ErrClassHashNotFound, rpcErr)
+	})
+
+	t.Run("failed class lookup", func(t *testing.T) {
+		classHash := new(felt.Felt).SetUint64(5)
+		mockState.EXPECT().Class(classHash).Return(nil, errors.New("some error"))
+
+		casm, rpcErr := handler.GetCompiledCasm(*classHash)
+		assert.Nil(t, casm)
+		assert.Equal(t, rpc.ErrInternal.Code, rpcErr.Code)
+	})
+}
+
 func TestEvents(t *testing.T) {
 	testDB := pebble.NewMemTest(t)
 	chain := blockchain.New(testDB, &utils.Goerli2)
@@ -2877,6 +3339,30 @@ func TestCall(t *testing.T) {
 		assert.Equal(t, rpc.ErrContractNotFound, rpcErr)
 	})
 
+	t.Run("call - contract deployed after the requested block", func(t *testing.T) {
+		historicalState := mocks.NewMockStateHistoryReader(mockCtrl)
+		contractAddr := new(felt.Felt).SetUint64(1)
+		mockReader.EXPECT().StateAtBlockNumber(uint64(5)).Return(historicalState, nopCloser, nil)
+		mockReader.EXPECT().BlockHeaderByNumber(uint64(5)).Return(&core.Header{Number: 5}, nil)
+		historicalState.EXPECT().ContractClassHash(contractAddr).Return(nil, core.ErrContractNotDeployedYet)
+
+		res, rpcErr := handler.Call(rpc.FunctionCall{ContractAddress: *contractAddr}, rpc.BlockID{Number: 5})
+		require.Nil(t, res)
+		assert.Equal(t, rpc.ErrContractNotFound.Code, rpcErr.Code)
+		assert.Equal(t, "contract is not deployed yet at the requested block", rpcErr.Data)
+	})
+
+	t.Run("call - contract that never existed at a historical block", func(t *testing.T) {
+		historicalState := mocks.NewMockStateHistoryReader(mockCtrl)
+		mockReader.EXPECT().StateAtBlockNumber(uint64(5)).Return(historicalState, nopCloser, nil)
+		mockReader.EXPECT().BlockHeaderByNumber(uint64(5)).Return(&core.Header{Number: 5}, nil)
+		historicalState.EXPECT().ContractClassHash(&felt.Zero).Return(nil, db.ErrKeyNotFound)
+
+		res, rpcErr := handler.Call(rpc.FunctionCall{}, rpc.BlockID{Number: 5})
+		require.Nil(t, res)
+		assert.Equal(t, rpc.ErrContractNotFound, rpcErr)
+	})
+
 	t.Run("ok", func(t *testing.T) {
 		handler = handler.WithCallMaxSteps(1337)
 
@@ -2899,13 +3385,15 @@ func TestCall(t *testing.T) {
 		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
 		mockReader.EXPECT().HeadsHeader().Return(headsHeader, nil)
 		mockState.EXPECT().ContractClassHash(contractAddr).Return(classHash, nil)
+		mockState.EXPECT().Class(classHash).Return(callableClass, nil)
 		mockReader.EXPECT().Network().Return(&utils.Mainnet)
-		mockVM.EXPECT().Call(&vm.CallInfo{
+		mockVM.EXPECT().Call(gomock.Any(), &vm.CallInfo{
 			ContractAddress: contractAddr,
 			ClassHash:       classHash,
 			Selector:        selector,
 			Calldata:        calldata,
-		}, &vm.BlockInfo{Header: headsHeader}, gomock.Any(), &utils.Mainnet, uint64(1337), true).Return(expectedRes, nil)
+		}, &vm.BlockInfo{Header: headsHeader}, gomock.Any(), &utils.Mainnet, uint64(1337), true).
+			Return(vm.CallResult{Result: expectedRes}, nil)
 
 		res, rpcErr := handler.Call(rpc.FunctionCall{
 			ContractAddress:    *contractAddr,
@@ -2915,258 +3403,1583 @@ func TestCall(t *testing.T) {
 		require.Nil(t, rpcErr)
 		require.Equal(t, expectedRes, res)
 	})
-}
 
-func TestEstimateMessageFee(t *testing.T) {
-	t.Skip()
-	mockCtrl := gomock.NewController(t)
-	t.Cleanup(mockCtrl.Finish)
+	t.Run("unknown selector", func(t *testing.T) {
+		contractAddr := new(felt.Felt).SetUint64(1)
+		classHash := new(felt.Felt).SetUint64(3)
+		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+		mockReader.EXPECT().HeadsHeader().Return(new(core.Header), nil)
+		mockState.EXPECT().ContractClassHash(contractAddr).Return(classHash, nil)
+		class := new(core.Cairo1Class)
+		class.EntryPoints.External = []core.SierraEntryPoint{{Selector: new(felt.Felt).SetUint64(1)}}
+		// the class is checked before the VM runs, so no VM call is expected
+		mockState.EXPECT().Class(classHash).Return(&core.DeclaredClass{Class: class}, nil)
 
-	mockReader := mocks.NewMockReader(mockCtrl)
-	mockReader.EXPECT().Network().Return(&utils.Mainnet).AnyTimes()
-	mockVM := mocks.NewMockVM(mockCtrl)
+		res, rpcErr := handler.Call(rpc.FunctionCall{
+			ContractAddress:    *contractAddr,
+			EntryPointSelector: *new(felt.Felt).SetUint64(2),
+		}, rpc.BlockID{Latest: true})
+		require.Nil(t, res)
+		assert.Equal(t, rpc.ErrContractError.CloneWithData(rpc.ContractErrorData{
+			RevertError:     "entry point 0x2 not found in contract",
+			ClassHash:       classHash,
+			UnknownSelector: true,
+		}), rpcErr)
+	})
 
-	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
-	msg := rpc.MsgFromL1{
-		From:     common.HexToAddress("0xDEADBEEF"),
-		To:       *new(felt.Felt).SetUint64(1337),
-		Payload:  []felt.Felt{*new(felt.Felt).SetUint64(1), *new(felt.Felt).SetUint64(2)},
-		Selector: *new(felt.Felt).SetUint64(44),
-	}
+	t.Run("revert", func(t *testing.T) {
+		contractAddr := new(felt.Felt).SetUint64(1)
+		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+		mockReader.EXPECT().HeadsHeader().Return(new(core.Header), nil)
+		mockState.EXPECT().ContractClassHash(contractAddr).Return(new(felt.Felt).SetUint64(3), nil)
+		mockState.EXPECT().Class(new(felt.Felt).SetUint64(3)).Return(callableClass, nil)
+		mockReader.EXPECT().Network().Return(&utils.Mainnet)
+		mockVM.EXPECT().Call(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), &utils.Mainnet, gomock.Any(), true).
+			Return(vm.CallResult{}, errors.New("Execution failed. Failure reason: 0x4e6f7065 ('Nope')."))
 
-	t.Run("block not found", func(t *testing.T) {
-		mockReader.EXPECT().HeadState().Return(nil, nil, db.ErrKeyNotFound)
-		_, err := handler.EstimateMessageFeeV0_6(msg, rpc.BlockID{Latest: true})
-		require.Equal(t, rpc.ErrBlockNotFound, err)
+		res, rpcErr := handler.Call(rpc.FunctionCall{ContractAddress: *contractAddr}, rpc.BlockID{Latest: true})
+		require.Nil(t, res)
+		assert.Equal(t, rpc.ErrContractError.CloneWithData(rpc.ContractErrorData{
+			RevertError: "Execution failed. Failure reason: 0x4e6f7065 ('Nope').",
+		}), rpcErr)
 	})
 
-	latestHeader := &core.Header{
-		Number:    9,
-		Timestamp: 456,
-		GasPrice:  new(felt.Felt).SetUint64(42),
-	}
-	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	t.Run("calldata too large", func(t *testing.T) {
+		handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger()).WithMaxCalldataLength(2)
 
-	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
-	mockReader.EXPECT().HeadsHeader().Return(latestHeader, nil)
+		// rejected before any state is opened
+		res, rpcErr := handler.Call(rpc.FunctionCall{Calldata: make([]felt.Felt, 3)}, rpc.BlockID{Latest: true})
+		require.Nil(t, res)
+		assert.Equal(t, rpc.ErrCalldataTooLarge, rpcErr)
 
-	expectedGasConsumed := new(felt.Felt).SetUint64(37)
-	mockVM.EXPECT().Execute(gomock.Any(), gomock.Any(), gomock.Any(), &vm.BlockInfo{
-		Header: latestHeader,
-	}, gomock.Any(), &utils.Mainnet, gomock.Any(), false, true, false).DoAndReturn(
-		func(txns []core.Transaction, declaredClasses []core.Class, paidFeesOnL1 []*felt.Felt, blockInfo *vm.BlockInfo,
-			state core.StateReader, network *utils.Network, skipChargeFee, skipValidate, errOnRevert bool,
-		) ([]*felt.Felt, []*felt.Felt, []vm.TransactionTrace, error) {
-			require.Len(t, txns, 1)
-			assert.NotNil(t, txns[0].(*core.L1HandlerTransaction))
+		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+		mockReader.EXPECT().HeadsHeader().Return(new(core.Header), nil)
+		mockReader.EXPECT().Network().Return(&utils.Mainnet)
+		mockState.EXPECT().ContractClassHash(&felt.Zero).Return(new(felt.Felt), nil)
+		mockState.EXPECT().Class(&felt.Zero).Return(callableClass, nil)
+		mockVM.EXPECT().Call(gomock.Any(), gomock.Any(), gomock.Any(), mockState, &utils.Mainnet, gomock.Any(), true).
+			Return(vm.CallResult{}, nil)
 
-			assert.Empty(t, declaredClasses)
-			assert.Len(t, paidFeesOnL1, 1)
+		_, rpcErr = handler.Call(rpc.FunctionCall{Calldata: make([]felt.Felt, 2)}, rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+	})
 
-			actualFee := new(felt.Felt).Mul(expectedGasConsumed, blockInfo.Header.GasPrice)
-			return []*felt.Felt{actualFee}, []*felt.Felt{&felt.Zero}, []vm.TransactionTrace{{
-				StateDiff: &vm.StateDiff{
-					StorageDiffs:              []vm.StorageDiff{},
-					Nonces:                    []vm.Nonce{},
-					DeployedContracts:         []vm.DeployedContract{},
-					DeprecatedDeclaredClasses: []*felt.Felt{},
-					DeclaredClasses:           []vm.DeclaredClass{},
-					ReplacedClasses:           []vm.ReplacedClass{},
-				},
-			}}, nil
-		},
-	)
+	t.Run("revealed block hash", func(t *testing.T) {
+		revealedHash := new(felt.Felt).SetUint64(0xabc)
+		for name, test := range map[string]struct {
+			blockNumber  uint64
+			revealedHash *felt.Felt
+		}{
+			"genesis block":       {blockNumber: 0},
+			"last early block":    {blockNumber: 9},
+			"first revealing one": {blockNumber: 10, revealedHash: revealedHash},
+		} {
+			t.Run(name, func(t *testing.T) {
+				header := &core.Header{Number: test.blockNumber}
+				mockReader.EXPECT().StateAtBlockNumber(test.blockNumber).Return(mockState, nopCloser, nil)
+				mockReader.EXPECT().BlockHeaderByNumber(test.blockNumber).Return(header, nil)
+				if test.revealedHash != nil {
+					mockReader.EXPECT().BlockHeaderByNumber(uint64(0)).Return(&core.Header{Hash: revealedHash}, nil)
+				}
+				mockReader.EXPECT().Network().Return(&utils.Mainnet)
+				mockState.EXPECT().ContractClassHash(&felt.Zero).Return(new(felt.Felt), nil)
+				mockState.EXPECT().Class(&felt.Zero).Return(callableClass, nil)
+				mockVM.EXPECT().Call(gomock.Any(), gomock.Any(), &vm.BlockInfo{
+					Header:                header,
+					BlockHashToBeRevealed: test.revealedHash,
+				}, mockState, &utils.Mainnet, gomock.Any(), true).Return(vm.CallResult{}, nil)
+
+				_, rpcErr := handler.Call(rpc.FunctionCall{}, rpc.BlockID{Number: test.blockNumber})
+				require.Nil(t, rpcErr)
+			})
+		}
+	})
 
-	estimateFee, err := handler.EstimateMessageFeeV0_6(msg, rpc.BlockID{Latest: true})
-	require.Nil(t, err)
-	feeUnit := rpc.WEI
-	require.Equal(t, rpc.FeeEstimate{
-		GasConsumed: expectedGasConsumed,
-		GasPrice:    latestHeader.GasPrice,
-		OverallFee:  new(felt.Felt).Mul(expectedGasConsumed, latestHeader.GasPrice),
-		Unit:        &feeUnit,
-	}, *estimateFee)
-}
+	t.Run("no pending block", func(t *testing.T) {
+		mockReader.EXPECT().PendingState().Return(nil, nil, db.ErrKeyNotFound)
 
-func TestTraceTransaction(t *testing.T) {
-	t.Skip()
+		res, rpcErr := handler.Call(rpc.FunctionCall{}, rpc.BlockID{Pending: true})
+		require.Nil(t, res)
+		assert.Equal(t, rpc.ErrBlockNotFound, rpcErr)
+	})
+
+	t.Run("pending", func(t *testing.T) {
+		contractAddr := new(felt.Felt).SetUint64(1)
+		classHash := new(felt.Felt).SetUint64(2)
+		key := new(felt.Felt).SetUint64(3)
+		pendingValue := new(felt.Felt).SetUint64(4)
+
+		pendingHeader := &core.Header{Number: 20, Timestamp: 202}
+		revealedHeader := &core.Header{Number: 10, Hash: new(felt.Felt).SetUint64(0xabc)}
+		pendingState := blockchain.NewPendingState(&core.StateDiff{
+			StorageDiffs: map[felt.Felt]map[felt.Felt]*felt.Felt{
+				*contractAddr: {*key: pendingValue},
+			},
+		}, nil, mockState)
+
+		mockReader.EXPECT().PendingState().Return(pendingState, nopCloser, nil)
+		mockReader.EXPECT().Pending().Return(blockchain.Pending{
+			Block: &core.Block{Header: pendingHeader},
+		}, nil)
+		mockReader.EXPECT().BlockHeaderByNumber(revealedHeader.Number).Return(revealedHeader, nil)
+		mockReader.EXPECT().Network().Return(&utils.Mainnet)
+		mockState.EXPECT().ContractClassHash(contractAddr).Return(classHash, nil)
+		mockState.EXPECT().Class(classHash).Return(callableClass, nil)
+		mockVM.EXPECT().Call(gomock.Any(), gomock.Any(), &vm.BlockInfo{
+			Header:                pendingHeader,
+			BlockHashToBeRevealed: revealedHeader.Hash,
+		}, pendingState, &utils.Mainnet, gomock.Any(), true).DoAndReturn(
+			func(_ context.Context, _ *vm.CallInfo, _ *vm.BlockInfo, state core.StateReader, _ *utils.Network, _ uint64, _ bool,
+			) (vm.CallResult, error) {
+				// the write only exists in the pending block, the head state is never consulted
+				value, err := state.ContractStorage(contractAddr, key)
+				require.NoError(t, err)
+				return vm.CallResult{Result: []*felt.Felt{value}}, nil
+			})
+
+		res, rpcErr := handler.Call(rpc.FunctionCall{ContractAddress: *contractAddr}, rpc.BlockID{Pending: true})
+		require.Nil(t, rpcErr)
+		assert.Equal(t, []*felt.Felt{pendingValue}, res)
+	})
+}
 
+func TestCallWithUsage(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	t.Cleanup(mockCtrl.Finish)
 
 	mockReader := mocks.NewMockReader(mockCtrl)
-	mockReader.EXPECT().Network().Return(&utils.Mainnet).AnyTimes()
 	mockVM := mocks.NewMockVM(mockCtrl)
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
 	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
 
-	t.Run("not found", func(t *testing.T) {
-		hash := utils.HexToFelt(t, "0xBBBB")
-		// Receipt() returns error related to db
-		mockReader.EXPECT().Receipt(hash).Return(nil, nil, uint64(0), db.ErrKeyNotFound)
+	contractAddr := new(felt.Felt).SetUint64(1)
+	classHash := new(felt.Felt).SetUint64(2)
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+	mockReader.EXPECT().HeadsHeader().Return(new(core.Header), nil)
+	mockReader.EXPECT().Network().Return(&utils.Mainnet)
+	mockState.EXPECT().ContractClassHash(contractAddr).Return(classHash, nil)
+	mockState.EXPECT().Class(classHash).Return(callableClass, nil)
+	mockVM.EXPECT().Call(gomock.Any(), gomock.Any(), gomock.Any(), mockState, &utils.Mainnet, gomock.Any(), true).Return(vm.CallResult{
+		Result:      []*felt.Felt{new(felt.Felt).SetUint64(3)},
+		Steps:       120,
+		GasConsumed: 4500,
+	}, nil)
+
+	res, rpcErr := handler.CallWithUsage(rpc.FunctionCall{ContractAddress: *contractAddr}, rpc.BlockID{Latest: true})
+	require.Nil(t, rpcErr)
+	assert.Equal(t, &rpc.CallResponse{
+		Result:      []*felt.Felt{new(felt.Felt).SetUint64(3)},
+		Steps:       120,
+		GasConsumed: 4500,
+	}, res)
+}
 
-		trace, err := handler.TraceTransaction(context.Background(), *hash)
-		assert.Nil(t, trace)
-		assert.Equal(t, rpc.ErrTxnHashNotFound, err)
-	})
-	t.Run("ok", func(t *testing.T) {
-		hash := utils.HexToFelt(t, "0x37b244ea7dc6b3f9735fba02d183ef0d6807a572dd91a63cc1b14b923c1ac0")
-		tx := &core.DeclareTransaction{
-			TransactionHash: hash,
-			ClassHash:       utils.HexToFelt(t, "0x000000000"),
-		}
+func TestCallWithCaller(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
 
-		header := &core.Header{
-			Hash:             utils.HexToFelt(t, "0xCAFEBABE"),
-			ParentHash:       utils.HexToFelt(t, "0x0"),
-			Number:           0,
-			SequencerAddress: utils.HexToFelt(t, "0X111"),
-			ProtocolVersion:  "99.12.3",
-		}
-		block := &core.Block{
-			Header:       header,
-			Transactions: []core.Transaction{tx},
-		}
-		declaredClass := &core.DeclaredClass{
-			At:    3002,
-			Class: &core.Cairo1Class{},
-		}
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&utils.Mainnet).AnyTimes()
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	mockState.EXPECT().ContractClassHash(gomock.Any()).Return(new(felt.Felt), nil).AnyTimes()
+	mockState.EXPECT().Class(gomock.Any()).Return(callableClass, nil).AnyTimes()
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil).AnyTimes()
+	mockReader.EXPECT().HeadsHeader().Return(new(core.Header), nil).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
 
-		mockReader.EXPECT().Receipt(hash).Return(nil, header.Hash, header.Number, nil)
-		mockReader.EXPECT().BlockByNumber(header.Number).Return(block, nil)
+	// the contract echoes get_caller_address()
+	mockVM.EXPECT().Call(gomock.Any(), gomock.Any(), gomock.Any(), mockState, &utils.Mainnet, gomock.Any(), true).DoAndReturn(
+		func(_ context.Context, callInfo *vm.CallInfo, _ *vm.BlockInfo, _ core.StateReader, _ *utils.Network, _ uint64, _ bool,
+		) (vm.CallResult, error) {
+			caller := &felt.Zero
+			if callInfo.CallerAddress != nil {
+				caller = callInfo.CallerAddress
+			}
+			return vm.CallResult{Result: []*felt.Felt{caller}}, nil
+		}).AnyTimes()
 
-		mockReader.EXPECT().StateAtBlockHash(header.ParentHash).Return(nil, nopCloser, nil)
-		headState := mocks.NewMockStateHistoryReader(mockCtrl)
-		headState.EXPECT().Class(tx.ClassHash).Return(declaredClass, nil)
-		mockReader.EXPECT().HeadState().Return(headState, nopCloser, nil)
+	funcCall := rpc.FunctionCall{ContractAddress: *new(felt.Felt).SetUint64(1)}
 
-		vmTraceJSON := json.RawMessage(`{
-		"validate_invocation": {"contract_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "entry_point_selector": "0x162da33a4585851fe8d3af3c2a9c60b557814e221e0d4f30ff0b2189d9c7775", "calldata": ["0x2", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x219209e083275171774dab1df80982e9df2096516f06319c5c6d71ae0a8480c", "0x0", "0x3", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1171593aa5bdadda4d6b0efde6cc94ee7649c3163d5efeb19da6c16d63a2a63", "0x3", "0x10", "0x13", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x1e8480", "0x0", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x420eeb770f7a4", "0x0", "0x40139799e37e4", "0x0", "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x0", "0x0", "0x1", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x64"], "caller_address": "0x0", "class_hash": "0x25ec026985a3bf9d0cc1fe17326b245dfdc3ff89b8fde106542a3ea56c5a918", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": [], "calls": [{"contract_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "entry_point_selector": "0x162da33a4585851fe8d3af3c2a9c60b557814e221e0d4f30ff0b2189d9c7775", "calldata": ["0x2", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x219209e083275171774dab1df80982e9df2096516f06319c5c6d71ae0a8480c", "0x0", "0x3", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1171593aa5bdadda4d6b0efde6cc94ee7649c3163d5efeb19da6c16d63a2a63", "0x3", "0x10", "0x13", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x1e8480", "0x0", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x420eeb770f7a4", "0x0", "0x40139799e37e4", "0x0", "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x0", "0x0", "0x1", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x64"], "caller_address": "0x0", "class_hash": "0x33434ad846cdd5f23eb73ff09fe6fddd568284a0fb7d1be20ee482f044dabe2", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": [], "calls": [], "events": [], "messages": []}], "events": [], "messages": []},
-		"execute_invocation": {"contract_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "entry_point_selector": "0x15d40a3d6ca2ac30f4031e42be28da9b056fef9bb7357ac5e85627ee876e5ad", "calldata": ["0x2", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x219209e083275171774dab1df80982e9df2096516f06319c5c6d71ae0a8480c", "0x0", "0x3", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1171593aa5bdadda4d6b0efde6cc94ee7649c3163d5efeb19da6c16d63a2a63", "0x3", "0x10", "0x13", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x1e8480", "0x0", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x420eeb770f7a4", "0x0", "0x40139799e37e4", "0x0", "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x0", "0x0", "0x1", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x64"], "caller_address": "0x0", "class_hash": "0x25ec026985a3bf9d0cc1fe17326b245dfdc3ff89b8fde106542a3ea56c5a918", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1", "0x1"], "calls": [{"contract_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "entry_point_selector": "0x15d40a3d6ca2ac30f4031e42be28da9b056fef9bb7357ac5e85627ee876e5ad", "calldata": ["0x2", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x219209e083275171774dab1df80982e9df2096516f06319c5c6d71ae0a8480c", "0x0", "0x3", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1171593aa5bdadda4d6b0efde6cc94ee7649c3163d5efeb19da6c16d63a2a63", "0x3", "0x10", "0x13", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x1e8480", "0x0", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x420eeb770f7a4", "0x0", "0x40139799e37e4", "0x0", "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x0", "0x0", "0x1", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x64"], "caller_address": "0x0", "class_hash": "0x33434ad846cdd5f23eb73ff09fe6fddd568284a0fb7d1be20ee482f044dabe2", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1", "0x1"], "calls": [{"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x219209e083275171774dab1df80982e9df2096516f06319c5c6d71ae0a8480c", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0"], "caller_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "class_hash": "0x52c7ba99c77fc38dd3346beea6c0753c3471f2e3135af5bb837d6c9523fff62", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1"], "calls": [{"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x219209e083275171774dab1df80982e9df2096516f06319c5c6d71ae0a8480c", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0"], "caller_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1"], "calls": [], "events": [{"keys": ["0x134692b230b9e1ffa39098904722134159652b09c5bc41d88d6698779d228ff"], "data": ["0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0"]}], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "entry_point_selector": "0x1171593aa5bdadda4d6b0efde6cc94ee7649c3163d5efeb19da6c16d63a2a63", "calldata": ["0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x1e8480", "0x0", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x420eeb770f7a4", "0x0", "0x40139799e37e4", "0x0", "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x0", "0x0", "0x1", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x64"], "caller_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "class_hash": "0x5ee939756c1a60b029c594da00e637bf5923bf04a86ff163e877e899c0840eb", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1"], "calls": [{"contract_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "entry_point_selector": "0x1171593aa5bdadda4d6b0efde6cc94ee7649c3163d5efeb19da6c16d63a2a63", "calldata": ["0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x1e8480", "0x0", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x420eeb770f7a4", "0x0", "0x40139799e37e4", "0x0", "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x0", "0x0", "0x1", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x64"], "caller_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "class_hash": "0x38627c278c0b3cb3c84ddee2c783fb22c3c3a3f0e667ea2b82be0ea2253bce4", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1"], "calls": [{"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x41b033f4a31df8067c24d1e9b550a2ce75fd4a29e1147af9752174f0e6cb20", "calldata": ["0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x52c7ba99c77fc38dd3346beea6c0753c3471f2e3135af5bb837d6c9523fff62", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1"], "calls": [{"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x41b033f4a31df8067c24d1e9b550a2ce75fd4a29e1147af9752174f0e6cb20", "calldata": ["0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1"], "calls": [], "events": [{"keys": ["0x99cd8bde557814842a3121e8ddfd433a539b8c9f14bf31ebf108d12e6196e9"], "data": ["0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0"]}], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x1ed6790cdca923073adc728080b06c159d9784cc9bf8fb26181acfdbe4256e6", "entry_point_selector": "0x260bb04cf90403013190e77d7e75f3d40d3d307180364da33c63ff53061d4e8", "calldata": [], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x5ee939756c1a60b029c594da00e637bf5923bf04a86ff163e877e899c0840eb", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x0", "0x0", "0x5"], "calls": [{"contract_address": "0x1ed6790cdca923073adc728080b06c159d9784cc9bf8fb26181acfdbe4256e6", "entry_point_selector": "0x260bb04cf90403013190e77d7e75f3d40d3d307180364da33c63ff53061d4e8", "calldata": [], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x46668cd07d83af5d7158e7cd62c710f1a7573501bcd4f4092c6a4e1ecd2bf61", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x0", "0x0", "0x5"], "calls": [], "events": [], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x2e4263afad30923c891518314c3c95dbe830a16874e8abc5777a9a20b54c76e", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x52c7ba99c77fc38dd3346beea6c0753c3471f2e3135af5bb837d6c9523fff62", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1e8480", "0x0"], "calls": [{"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x2e4263afad30923c891518314c3c95dbe830a16874e8abc5777a9a20b54c76e", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1e8480", "0x0"], "calls": [], "events": [], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "entry_point_selector": "0x15543c3708653cda9d418b4ccd3be11368e40636c10c44b18cfe756b6d88b29", "calldata": ["0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x1e8480", "0x0", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x0", "0x0", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f"], "caller_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "class_hash": "0x2ceb6369dba6af865bca639f9f1342dfb1ae4e5d0d0723de98028b812e7cdd2", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": [], "calls": [{"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x219209e083275171774dab1df80982e9df2096516f06319c5c6d71ae0a8480c", "calldata": ["0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x1e8480", "0x0"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x52c7ba99c77fc38dd3346beea6c0753c3471f2e3135af5bb837d6c9523fff62", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1"], "calls": [{"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x219209e083275171774dab1df80982e9df2096516f06319c5c6d71ae0a8480c", "calldata": ["0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x1e8480", "0x0"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1"], "calls": [], "events": [{"keys": ["0x134692b230b9e1ffa39098904722134159652b09c5bc41d88d6698779d228ff"], "data": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x1e8480", "0x0"]}], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "entry_point_selector": "0x2c0f7bf2d6cf5304c29171bf493feb222fef84bdaf17805a6574b0c2e8bcc87", "calldata": ["0x1e8480", "0x0", "0x0", "0x0", "0x2", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x648f780a"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x514718bb56ed2a8607554c7d393c2ffd73cbab971c120b00a2ce27cc58dd1c1", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x2", "0x1e8480", "0x0", "0x417c36e4fc16d", "0x0"], "calls": [{"contract_address": "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "entry_point_selector": "0x3c388f7eb137a89061c6f0b6e78bae453202258b0b3c419f8dd9814a547d406", "calldata": [], "caller_address": "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "class_hash": "0x231adde42526bad434ca2eb983efdd64472638702f87f97e6e3c084f264e06f", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x178b60b3a0bcc4aa98", "0xaf07589b7c", "0x648f7422"], "calls": [], "events": [], "messages": []}, {"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x41b033f4a31df8067c24d1e9b550a2ce75fd4a29e1147af9752174f0e6cb20", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "0x1e8480", "0x0"], "caller_address": "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "class_hash": "0x52c7ba99c77fc38dd3346beea6c0753c3471f2e3135af5bb837d6c9523fff62", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1"], "calls": [{"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x41b033f4a31df8067c24d1e9b550a2ce75fd4a29e1147af9752174f0e6cb20", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "0x1e8480", "0x0"], "caller_address": "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1"], "calls": [], "events": [{"keys": ["0x99cd8bde557814842a3121e8ddfd433a539b8c9f14bf31ebf108d12e6196e9"], "data": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "0x1e8480", "0x0"]}], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "entry_point_selector": "0x15543c3708653cda9d418b4ccd3be11368e40636c10c44b18cfe756b6d88b29", "calldata": ["0x417c36e4fc16d", "0x0", "0x0", "0x0", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f"], "caller_address": "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "class_hash": "0x231adde42526bad434ca2eb983efdd64472638702f87f97e6e3c084f264e06f", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": [], "calls": [{"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x417c36e4fc16d", "0x0"], "caller_address": "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "class_hash": "0xd0e183745e9dae3e4e78a8ffedcce0903fc4900beace4e0abf192d4c202da3", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1"], "calls": [{"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x417c36e4fc16d", "0x0"], "caller_address": "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1"], "calls": [], "events": [{"keys": ["0x99cd8bde557814842a3121e8ddfd433a539b8c9f14bf31ebf108d12e6196e9"], "data": ["0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x417c36e4fc16d", "0x0"]}], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x2e4263afad30923c891518314c3c95dbe830a16874e8abc5777a9a20b54c76e", "calldata": ["0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325"], "caller_address": "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "class_hash": "0xd0e183745e9dae3e4e78a8ffedcce0903fc4900beace4e0abf192d4c202da3", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x178b5c9bdd4e74e92b", "0x0"], "calls": [{"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x2e4263afad30923c891518314c3c95dbe830a16874e8abc5777a9a20b54c76e", "calldata": ["0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325"], "caller_address": "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x178b5c9bdd4e74e92b", "0x0"], "calls": [], "events": [], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x2e4263afad30923c891518314c3c95dbe830a16874e8abc5777a9a20b54c76e", "calldata": ["0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325"], "caller_address": "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "class_hash": "0x52c7ba99c77fc38dd3346beea6c0753c3471f2e3135af5bb837d6c9523fff62", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0xaf07771ffc", "0x0"], "calls": [{"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x2e4263afad30923c891518314c3c95dbe830a16874e8abc5777a9a20b54c76e", "calldata": ["0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325"], "caller_address": "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0xaf07771ffc", "0x0"], "calls": [], "events": [], "messages": []}], "events": [], "messages": []}], "events": [{"keys": ["0xe14a408baf7f453312eec68e9b7d728ec5337fbdf671f917ee8c80f3255232"], "data": ["0x178b5c9bdd4e74e92b", "0xaf07771ffc"]}, {"keys": ["0xe316f0d9d2a3affa97de1d99bb2aac0538e2666d0d8545545ead241ef0ccab"], "data": ["0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x0", "0x0", "0x1e8480", "0x0", "0x417c36e4fc16d", "0x0", "0x0", "0x0", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f"]}], "messages": []}], "events": [], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x2e4263afad30923c891518314c3c95dbe830a16874e8abc5777a9a20b54c76e", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0xd0e183745e9dae3e4e78a8ffedcce0903fc4900beace4e0abf192d4c202da3", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x417c36e4fc16d", "0x0"], "calls": [{"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x2e4263afad30923c891518314c3c95dbe830a16874e8abc5777a9a20b54c76e", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x417c36e4fc16d", "0x0"], "calls": [], "events": [], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e", "calldata": ["0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x417c36e4fc16d", "0x0"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0xd0e183745e9dae3e4e78a8ffedcce0903fc4900beace4e0abf192d4c202da3", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1"], "calls": [{"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e", "calldata": ["0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x417c36e4fc16d", "0x0"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1"], "calls": [], "events": [{"keys": ["0x99cd8bde557814842a3121e8ddfd433a539b8c9f14bf31ebf108d12e6196e9"], "data": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x417c36e4fc16d", "0x0"]}], "messages": []}], "events": [], "messages": []}], "events": [{"keys": ["0xe316f0d9d2a3affa97de1d99bb2aac0538e2666d0d8545545ead241ef0ccab"], "data": ["0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x1e8480", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x417c36e4fc16d", "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a"]}], "messages": []}], "events": [], "messages": []}], "events": [{"keys": ["0x5ad857f66a5b55f1301ff1ed7e098ac6d4433148f0b72ebc4a2945ab85ad53"], "data": ["0x2fc5e96de394697c1311606c96ec14840e408493fd42cf0c54b73b39d312b81", "0x2", "0x1", "0x1"]}], "messages": []}], "events": [], "messages": []},
-		"fee_transfer_invocation": {"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e", "calldata": ["0x1176a1bd84444c89232ec27754698e5d2e7e1a7f1539f12027f28b23ec9f3d8", "0x2cb6", "0x0"], "caller_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "class_hash": "0xd0e183745e9dae3e4e78a8ffedcce0903fc4900beace4e0abf192d4c202da3", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1"], "calls": [{"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e", "calldata": ["0x1176a1bd84444c89232ec27754698e5d2e7e1a7f1539f12027f28b23ec9f3d8", "0x2cb6", "0x0"], "caller_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1"], "calls": [], "events": [{"keys": ["0x99cd8bde557814842a3121e8ddfd433a539b8c9f14bf31ebf108d12e6196e9"], "data": ["0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x1176a1bd84444c89232ec27754698e5d2e7e1a7f1539f12027f28b23ec9f3d8", "0x2cb6", "0x0"]}], "messages": []}], "events": [], "messages": []},
-		"state_diff": {
-			"storage_diffs": [],
-			"nonces": [],
-			"deployed_contracts": [],
-			"deprecated_declared_classes": [],
-			"declared_classes": [],
-			"replaced_classes": []
-		}
-	}`)
-		vmTrace := new(vm.TransactionTrace)
-		require.NoError(t, json.Unmarshal(vmTraceJSON, vmTrace))
-		mockVM.EXPECT().Execute([]core.Transaction{tx}, []core.Class{declaredClass.Class}, []*felt.Felt{},
-			&vm.BlockInfo{Header: header}, gomock.Any(), &utils.Mainnet, false, false, false, false).Return(nil, []vm.TransactionTrace{*vmTrace}, nil)
+	t.Run("default caller", func(t *testing.T) {
+		res, rpcErr := handler.Call(funcCall, rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+		assert.Equal(t, []*felt.Felt{&felt.Zero}, res)
+	})
 
-		trace, err := handler.TraceTransaction(context.Background(), *hash)
-		require.Nil(t, err)
-		assert.Equal(t, vmTrace, trace)
+	t.Run("caller override", func(t *testing.T) {
+		caller := utils.HexToFelt(t, "0xca11e4")
+		res, rpcErr := handler.CallWithCaller(funcCall, rpc.BlockID{Latest: true}, *caller)
+		require.Nil(t, rpcErr)
+		assert.Equal(t, []*felt.Felt{caller}, res)
+	})
+
+	t.Run("invalid caller address", func(t *testing.T) {
+		// 2^251 is just outside of the contract address range
+		caller := utils.HexToFelt(t, "0x800000000000000000000000000000000000000000000000000000000000000")
+		res, rpcErr := handler.CallWithCaller(funcCall, rpc.BlockID{Latest: true}, *caller)
+		assert.Nil(t, res)
+		assert.Equal(t, jsonrpc.InvalidParams, rpcErr.Code)
 	})
 }
 
-func TestSimulateTransactions(t *testing.T) {
-	t.Skip()
+func TestCallAtTimestamp(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
-	defer mockCtrl.Finish()
+	t.Cleanup(mockCtrl.Finish)
 
-	network := utils.Mainnet
+	const blockTimestamp, unlockTimestamp = 1000, 2000
 
 	mockReader := mocks.NewMockReader(mockCtrl)
-	mockReader.EXPECT().Network().Return(&network).AnyTimes()
+	mockReader.EXPECT().Network().Return(&utils.Mainnet).AnyTimes()
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	mockState.EXPECT().ContractClassHash(gomock.Any()).Return(new(felt.Felt), nil).AnyTimes()
+	mockState.EXPECT().Class(gomock.Any()).Return(callableClass, nil).AnyTimes()
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil).AnyTimes()
+	header := &core.Header{Number: 5, Timestamp: blockTimestamp}
+	mockReader.EXPECT().HeadsHeader().Return(header, nil).AnyTimes()
 	mockVM := mocks.NewMockVM(mockCtrl)
 	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
 
-	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
-	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil).AnyTimes()
-	headsHeader := &core.Header{
-		SequencerAddress: network.BlockHashMetaInfo.FallBackSequencerAddress,
-	}
-	mockReader.EXPECT().HeadsHeader().Return(headsHeader, nil).AnyTimes()
+	// the contract reports whether a time lock has expired, based on get_block_timestamp()
+	mockVM.EXPECT().Call(gomock.Any(), gomock.Any(), gomock.Any(), mockState, &utils.Mainnet, gomock.Any(), true).DoAndReturn(
+		func(_ context.Context, _ *vm.CallInfo, blockInfo *vm.BlockInfo, _ core.StateReader, _ *utils.Network, _ uint64, _ bool,
+		) (vm.CallResult, error) {
+			unlocked := &felt.Zero
+			if blockInfo.Header.Timestamp >= unlockTimestamp {
+				unlocked = new(felt.Felt).SetUint64(1)
+			}
+			return vm.CallResult{Result: []*felt.Felt{unlocked}}, nil
+		}).AnyTimes()
 
-	t.Run("ok with zero values, skip fee", func(t *testing.T) {
-		mockVM.EXPECT().Execute(nil, nil, []*felt.Felt{}, &vm.BlockInfo{
-			Header: headsHeader,
-		}, mockState, &network, true, false, false, false).
-			Return([]*felt.Felt{}, []vm.TransactionTrace{}, nil)
+	funcCall := rpc.FunctionCall{ContractAddress: *new(felt.Felt).SetUint64(1)}
+	locked := []*felt.Felt{&felt.Zero}
+	unlocked := []*felt.Felt{new(felt.Felt).SetUint64(1)}
 
-		_, err := handler.SimulateTransactions(rpc.BlockID{Latest: true}, []rpc.BroadcastedTransaction{}, []rpc.SimulationFlag{rpc.SkipFeeChargeFlag})
-		require.Nil(t, err)
+	t.Run("block timestamp", func(t *testing.T) {
+		res, rpcErr := handler.Call(funcCall, rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+		assert.Equal(t, locked, res)
 	})
 
-	t.Run("ok with zero values, skip validate", func(t *testing.T) {
-		mockVM.EXPECT().Execute(nil, nil, []*felt.Felt{}, &vm.BlockInfo{
-			Header: headsHeader,
-		}, mockState, &network, false, false, false, false).
-			Return([]*felt.Felt{}, []vm.TransactionTrace{}, nil)
+	t.Run("before the threshold", func(t *testing.T) {
+		res, rpcErr := handler.CallAtTimestamp(funcCall, rpc.BlockID{Latest: true}, unlockTimestamp-1)
+		require.Nil(t, rpcErr)
+		assert.Equal(t, locked, res)
+	})
 
-		_, err := handler.SimulateTransactions(rpc.BlockID{Latest: true}, []rpc.BroadcastedTransaction{}, []rpc.SimulationFlag{rpc.SkipValidateFlag})
-		require.Nil(t, err)
+	t.Run("after the threshold", func(t *testing.T) {
+		res, rpcErr := handler.CallAtTimestamp(funcCall, rpc.BlockID{Latest: true}, unlockTimestamp)
+		require.Nil(t, rpcErr)
+		assert.Equal(t, unlocked, res)
+		assert.Equal(t, uint64(blockTimestamp), header.Timestamp, "block header must not be modified")
 	})
 
-	t.Run("transaction execution error", func(t *testing.T) {
-		mockVM.EXPECT().Execute(nil, nil, []*felt.Felt{}, &vm.BlockInfo{
-			Header: headsHeader,
-		}, mockState, &network, false, false, false, false).
-			Return(nil, nil, vm.TransactionExecutionError{
-				Index: 44,
-				Cause: errors.New("oops"),
-			})
+	t.Run("timestamp earlier than the block", func(t *testing.T) {
+		res, rpcErr := handler.CallAtTimestamp(funcCall, rpc.BlockID{Latest: true}, blockTimestamp-1)
+		assert.Nil(t, res)
+		assert.Equal(t, jsonrpc.InvalidParams, rpcErr.Code)
+	})
 
-		_, err := handler.SimulateTransactions(rpc.BlockID{Latest: true}, []rpc.BroadcastedTransaction{}, []rpc.SimulationFlag{rpc.SkipValidateFlag})
-		require.Equal(t, rpc.ErrTransactionExecutionError.CloneWithData(rpc.TransactionExecutionErrorData{
-			TransactionIndex: 44,
-			ExecutionError:   "oops",
-		}), err)
+	t.Run("timestamp too far ahead of the block", func(t *testing.T) {
+		res, rpcErr := handler.CallAtTimestamp(funcCall, rpc.BlockID{Latest: true}, math.MaxUint64)
+		assert.Nil(t, res)
+		assert.Equal(t, jsonrpc.InvalidParams, rpcErr.Code)
+	})
+}
 
-		mockVM.EXPECT().Execute(nil, nil, []*felt.Felt{}, &vm.BlockInfo{
-			Header: headsHeader,
-		}, mockState, &network, false, true, true, false).
-			Return(nil, nil, vm.TransactionExecutionError{
-				Index: 44,
-				Cause: errors.New("oops"),
-			})
+func TestCallWithGasContext(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
 
-		_, err = handler.SimulateTransactionsV0_6(rpc.BlockID{Latest: true}, []rpc.BroadcastedTransaction{}, []rpc.SimulationFlag{rpc.SkipValidateFlag})
-		require.Equal(t, rpc.ErrContractError.CloneWithData(rpc.ContractErrorData{
-			RevertError: "oops",
-		}), err)
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&utils.Mainnet).AnyTimes()
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	mockState.EXPECT().ContractClassHash(gomock.Any()).Return(new(felt.Felt), nil).AnyTimes()
+	mockState.EXPECT().Class(gomock.Any()).Return(callableClass, nil).AnyTimes()
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil).AnyTimes()
+	header := &core.Header{GasPrice: new(felt.Felt).SetUint64(10), GasPriceSTRK: new(felt.Felt).SetUint64(20)}
+	mockReader.EXPECT().HeadsHeader().Return(header, nil).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
+
+	// the contract echoes the L1 gas price in fri that it observes in its tx_info
+	mockVM.EXPECT().Call(gomock.Any(), gomock.Any(), gomock.Any(), mockState, &utils.Mainnet, gomock.Any(), true).DoAndReturn(
+		func(_ context.Context, callInfo *vm.CallInfo, blockInfo *vm.BlockInfo, _ core.StateReader, _ *utils.Network,
+			_ uint64, _ bool,
+		) (vm.CallResult, error) {
+			assert.True(t, callInfo.GasPriceInTxInfo)
+			return vm.CallResult{Result: []*felt.Felt{blockInfo.Header.GasPriceSTRK}}, nil
+		}).AnyTimes()
+
+	funcCall := rpc.FunctionCall{ContractAddress: *new(felt.Felt).SetUint64(1)}
+
+	t.Run("header price when unspecified", func(t *testing.T) {
+		res, rpcErr := handler.CallWithGasContext(funcCall, rpc.BlockID{Latest: true}, nil)
+		require.Nil(t, rpcErr)
+		assert.Equal(t, []*felt.Felt{new(felt.Felt).SetUint64(20)}, res)
 	})
-}
 
-func TestTraceBlockTransactions(t *testing.T) {
-	t.Skip()
+	t.Run("l1 gas price", func(t *testing.T) {
+		res, rpcErr := handler.CallWithGasContext(funcCall, rpc.BlockID{Latest: true}, new(felt.Felt).SetUint64(200))
+		require.Nil(t, rpcErr)
+		assert.Equal(t, []*felt.Felt{new(felt.Felt).SetUint64(200)}, res)
+		assert.Equal(t, uint64(20), header.GasPriceSTRK.Uint64(), "block header must not be modified")
+	})
 
-	errTests := map[string]rpc.BlockID{
-		"latest":  {Latest: true},
-		"pending": {Pending: true},
-		"hash":    {Hash: new(felt.Felt).SetUint64(1)},
-		"number":  {Number: 1},
-	}
+	t.Run("l1 gas price above u128", func(t *testing.T) {
+		maxPrice := new(felt.Felt).SetBigInt(new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1)))
+		res, rpcErr := handler.CallWithGasContext(funcCall, rpc.BlockID{Latest: true}, maxPrice)
+		require.Nil(t, rpcErr)
+		assert.Equal(t, []*felt.Felt{maxPrice}, res)
 
-	for description, id := range errTests {
-		t.Run(description, func(t *testing.T) {
-			log := utils.NewNopZapLogger()
-			network := utils.Mainnet
-			chain := blockchain.New(pebble.NewMemTest(t), &network)
-			handler := rpc.New(chain, nil, nil, "", log)
+		tooLarge := new(felt.Felt).SetBigInt(new(big.Int).Lsh(big.NewInt(1), 128))
+		res, rpcErr = handler.CallWithGasContext(funcCall, rpc.BlockID{Latest: true}, tooLarge)
+		assert.Nil(t, res)
+		require.NotNil(t, rpcErr)
+		assert.Equal(t, jsonrpc.InvalidParams, rpcErr.Code)
+	})
 
-			update, rpcErr := handler.TraceBlockTransactions(context.Background(), id)
-			assert.Nil(t, update)
-			assert.Equal(t, rpc.ErrBlockNotFound, rpcErr)
-		})
-	}
+	t.Run("other calls see an empty tx_info", func(t *testing.T) {
+		mockVM := mocks.NewMockVM(mockCtrl)
+		handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
+		mockVM.EXPECT().Call(gomock.Any(), gomock.Any(), gomock.Any(), mockState, &utils.Mainnet, gomock.Any(), true).
+			DoAndReturn(func(_ context.Context, callInfo *vm.CallInfo, _ *vm.BlockInfo, _ core.StateReader,
+				_ *utils.Network, _ uint64, _ bool,
+			) (vm.CallResult, error) {
+				assert.False(t, callInfo.GasPriceInTxInfo)
+				return vm.CallResult{}, nil
+			})
+
+		_, rpcErr := handler.Call(funcCall, rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+	})
+}
 
+func TestCallWithLimit(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	t.Cleanup(mockCtrl.Finish)
-	network := utils.Mainnet
 
 	mockReader := mocks.NewMockReader(mockCtrl)
-	mockReader.EXPECT().Network().Return(&network).AnyTimes()
+	mockReader.EXPECT().Network().Return(&utils.Mainnet).AnyTimes()
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	mockState.EXPECT().ContractClassHash(gomock.Any()).Return(new(felt.Felt), nil).AnyTimes()
+	mockState.EXPECT().Class(gomock.Any()).Return(callableClass, nil).AnyTimes()
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil).AnyTimes()
+	mockReader.EXPECT().HeadsHeader().Return(new(core.Header), nil).AnyTimes()
 	mockVM := mocks.NewMockVM(mockCtrl)
-	log := utils.NewNopZapLogger()
+
+	const stepsNeeded = 5000
+	var lastMaxSteps uint64
+	mockVM.EXPECT().Call(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), &utils.Mainnet, gomock.Any(), true).DoAndReturn(
+		func(_ context.Context, _ *vm.CallInfo, _ *vm.BlockInfo, _ core.StateReader, _ *utils.Network, maxSteps uint64, _ bool,
+		) (vm.CallResult, error) {
+			lastMaxSteps = maxSteps
+			if maxSteps < stepsNeeded {
+				return vm.CallResult{}, errors.New("RunResources has no remaining steps")
+			}
+			return vm.CallResult{Result: []*felt.Felt{new(felt.Felt).SetUint64(1)}, Steps: stepsNeeded}, nil
+		}).AnyTimes()
+
+	funcCall := rpc.FunctionCall{ContractAddress: *new(felt.Felt).SetUint64(1)}
+	newHandler := func() *rpc.Handler {
+		return rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger()).WithCallMaxSteps(stepsNeeded)
+	}
+	stepLimitErr := rpc.ErrContractError.CloneWithData(rpc.ContractErrorData{
+		RevertError: "RunResources has no remaining steps",
+	})
+
+	t.Run("default limit", func(t *testing.T) {
+		res, rpcErr := newHandler().CallWithLimit(funcCall, rpc.BlockID{Latest: true}, nil)
+		require.Nil(t, rpcErr)
+		assert.Equal(t, []*felt.Felt{new(felt.Felt).SetUint64(1)}, res)
+		assert.Equal(t, uint64(stepsNeeded), lastMaxSteps)
+	})
+
+	t.Run("lowered limit", func(t *testing.T) {
+		res, rpcErr := newHandler().CallWithLimit(funcCall, rpc.BlockID{Latest: true}, utils.Ptr[uint64](stepsNeeded-1))
+		assert.Nil(t, res)
+		assert.Equal(t, stepLimitErr, rpcErr)
+		assert.Equal(t, uint64(stepsNeeded-1), lastMaxSteps)
+	})
+
+	t.Run("raised limit is clamped to the default without a ceiling", func(t *testing.T) {
+		handler := newHandler().WithCallMaxSteps(stepsNeeded - 1)
+		res, rpcErr := handler.CallWithLimit(funcCall, rpc.BlockID{Latest: true}, utils.Ptr[uint64](stepsNeeded))
+		assert.Nil(t, res)
+		assert.Equal(t, stepLimitErr, rpcErr)
+		assert.Equal(t, uint64(stepsNeeded-1), lastMaxSteps)
+	})
+
+	t.Run("raised limit", func(t *testing.T) {
+		handler := newHandler().WithCallMaxSteps(stepsNeeded - 1).WithCallMaxStepsCeiling(2 * stepsNeeded)
+
+		// plain starknet_call keeps using the default
+		_, rpcErr := handler.Call(funcCall, rpc.BlockID{Latest: true})
+		assert.Equal(t, stepLimitErr, rpcErr)
+
+		res, rpcErr := handler.CallWithLimit(funcCall, rpc.BlockID{Latest: true}, utils.Ptr[uint64](stepsNeeded))
+		require.Nil(t, rpcErr)
+		assert.Equal(t, []*felt.Felt{new(felt.Felt).SetUint64(1)}, res)
+		assert.Equal(t, uint64(stepsNeeded), lastMaxSteps)
+	})
+
+	t.Run("limit above the ceiling is clamped", func(t *testing.T) {
+		handler := newHandler().WithCallMaxStepsCeiling(2 * stepsNeeded)
+		_, rpcErr := handler.CallWithLimit(funcCall, rpc.BlockID{Latest: true}, utils.Ptr[uint64](math.MaxUint64))
+		require.Nil(t, rpcErr)
+		assert.Equal(t, uint64(2*stepsNeeded), lastMaxSteps)
+	})
+}
+
+func TestCallMany(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&utils.Mainnet).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger()).WithCallMaxSteps(1337)
+
+	t.Run("too many calls", func(t *testing.T) {
+		handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger()).WithMaxBatchSize(1)
+
+		results, rpcErr := handler.CallMany(make([]rpc.FunctionCall, 2), rpc.BlockID{Latest: true})
+		assert.Nil(t, results)
+		assert.Equal(t, jsonrpc.InvalidParams, rpcErr.Code)
+	})
+
+	t.Run("successful and failing calls", func(t *testing.T) {
+		balanceOf := rpc.FunctionCall{ContractAddress: *new(felt.Felt).SetUint64(1)}
+		reverting := rpc.FunctionCall{ContractAddress: *new(felt.Felt).SetUint64(2)}
+		unknown := rpc.FunctionCall{ContractAddress: *new(felt.Felt).SetUint64(3)}
+		balance := []*felt.Felt{new(felt.Felt).SetUint64(100)}
+
+		header := new(core.Header)
+		// state and header are resolved once for the whole batch
+		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+		mockReader.EXPECT().HeadsHeader().Return(header, nil)
+		mockState.EXPECT().ContractClassHash(&balanceOf.ContractAddress).Return(new(felt.Felt), nil).Times(2)
+		mockState.EXPECT().ContractClassHash(&reverting.ContractAddress).Return(new(felt.Felt), nil)
+		mockState.EXPECT().Class(&felt.Zero).Return(callableClass, nil).Times(3)
+		mockState.EXPECT().ContractClassHash(&unknown.ContractAddress).Return(nil, db.ErrKeyNotFound)
+		mockVM.EXPECT().Call(gomock.Any(), gomock.Any(), &vm.BlockInfo{Header: header}, mockState, &utils.Mainnet, uint64(1337),
+			true).DoAndReturn(func(_ context.Context, callInfo *vm.CallInfo, _ *vm.BlockInfo, _ core.StateReader,
+			_ *utils.Network, _ uint64, _ bool,
+		) (vm.CallResult, error) {
+			if callInfo.ContractAddress.Equal(&reverting.ContractAddress) {
+				return vm.CallResult{}, errors.New("execution reverted")
+			}
+			return vm.CallResult{Result: balance}, nil
+		}).Times(3)
+
+		results, rpcErr := handler.CallMany([]rpc.FunctionCall{balanceOf, reverting, unknown, balanceOf},
+			rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+		assert.Equal(t, []rpc.CallResult{
+			{Result: balance},
+			{Error: rpc.ErrContractError.CloneWithData(rpc.ContractErrorData{RevertError: "execution reverted"})},
+			{Error: rpc.ErrContractNotFound},
+			{Result: balance},
+		}, results)
+	})
+}
+
+func TestCallTimeout(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&utils.Mainnet).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger()).WithCallTimeout(10 * time.Millisecond)
+
+	// the VM runs until its call is cancelled, then waits for release before it returns, as a VM that is
+	// still finishing its current step would
+	expectSlowCall := func() (chan struct{}, chan struct{}) {
+		stateClosed := make(chan struct{})
+		mockReader.EXPECT().HeadState().Return(mockState, func() error {
+			close(stateClosed)
+			return nil
+		}, nil)
+		mockReader.EXPECT().HeadsHeader().Return(new(core.Header), nil)
+		mockState.EXPECT().ContractClassHash(&felt.Zero).Return(new(felt.Felt), nil)
+		mockState.EXPECT().Class(&felt.Zero).Return(callableClass, nil)
+
+		release := make(chan struct{})
+		mockVM.EXPECT().Call(gomock.Any(), gomock.Any(), gomock.Any(), mockState, gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, _ *vm.CallInfo, _ *vm.BlockInfo, _ core.StateReader, _ *utils.Network,
+				_ uint64, _ bool,
+			) (vm.CallResult, error) {
+				<-ctx.Done()
+				<-release
+				return vm.CallResult{}, ctx.Err()
+			})
+		return stateClosed, release
+	}
+
+	assertStateOutlivesVM := func(t *testing.T, stateClosed, release chan struct{}) {
+		select {
+		case <-stateClosed:
+			t.Fatal("state closed while the VM is still running")
+		default:
+		}
+		close(release)
+		<-stateClosed
+	}
+
+	t.Run("call", func(t *testing.T) {
+		stateClosed, release := expectSlowCall()
+
+		res, rpcErr := handler.Call(rpc.FunctionCall{}, rpc.BlockID{Latest: true})
+		assert.Nil(t, res)
+		assert.Equal(t, rpc.ErrCallTimeout, rpcErr)
+		assertStateOutlivesVM(t, stateClosed, release)
+	})
+
+	t.Run("call many stops at the first timeout", func(t *testing.T) {
+		// only the first call reaches the VM
+		stateClosed, release := expectSlowCall()
+
+		results, rpcErr := handler.CallMany(make([]rpc.FunctionCall, 3), rpc.BlockID{Latest: true})
+		assert.Nil(t, results)
+		assert.Equal(t, rpc.ErrCallTimeout, rpcErr)
+		assertStateOutlivesVM(t, stateClosed, release)
+	})
+}
+
+func TestCallWithOverrides(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&utils.Mainnet).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
+
+	contractAddr := new(felt.Felt).SetUint64(1)
+	classHash := new(felt.Felt).SetUint64(2)
+	overriddenKey := new(felt.Felt).SetUint64(3)
+	otherKey := new(felt.Felt).SetUint64(4)
+	storedValue := new(felt.Felt).SetUint64(5)
+
+	// the VM reads both storage slots and returns them, so the result shows what the call observed
+	expectCall := func(expectedClassHash *felt.Felt) {
+		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+		mockReader.EXPECT().HeadsHeader().Return(new(core.Header), nil)
+		mockState.EXPECT().Class(expectedClassHash).Return(callableClass, nil)
+		mockVM.EXPECT().Call(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), &utils.Mainnet, gomock.Any(), true).DoAndReturn(
+			func(_ context.Context, callInfo *vm.CallInfo, _ *vm.BlockInfo, state core.StateReader, _ *utils.Network, _ uint64, _ bool,
+			) (vm.CallResult, error) {
+				assert.Equal(t, expectedClassHash, callInfo.ClassHash)
+				overridden, err := state.ContractStorage(contractAddr, overriddenKey)
+				require.NoError(t, err)
+				other, err := state.ContractStorage(contractAddr, otherKey)
+				require.NoError(t, err)
+				return vm.CallResult{Result: []*felt.Felt{overridden, other}}, nil
+			})
+	}
+
+	t.Run("without overrides", func(t *testing.T) {
+		expectCall(classHash)
+		mockState.EXPECT().ContractClassHash(contractAddr).Return(classHash, nil)
+		mockState.EXPECT().ContractStorage(contractAddr, overriddenKey).Return(storedValue, nil)
+		mockState.EXPECT().ContractStorage(contractAddr, otherKey).Return(storedValue, nil)
+
+		res, rpcErr := handler.CallWithOverrides(rpc.FunctionCall{ContractAddress: *contractAddr},
+			rpc.BlockID{Latest: true}, nil)
+		require.Nil(t, rpcErr)
+		assert.Equal(t, []*felt.Felt{storedValue, storedValue}, res)
+	})
+
+	t.Run("overridden storage slot", func(t *testing.T) {
+		expectCall(classHash)
+		mockState.EXPECT().ContractClassHash(contractAddr).Return(classHash, nil)
+		mockState.EXPECT().ContractStorage(contractAddr, otherKey).Return(storedValue, nil)
+
+		overrideValue := new(felt.Felt).SetUint64(6)
+		res, rpcErr := handler.CallWithOverrides(rpc.FunctionCall{ContractAddress: *contractAddr},
+			rpc.BlockID{Latest: true}, []rpc.StateOverride{{
+				ContractAddress: *contractAddr,
+				Storage:         []rpc.Entry{{Key: *overriddenKey, Value: *overrideValue}},
+			}})
+		require.Nil(t, rpcErr)
+		assert.Equal(t, []*felt.Felt{overrideValue, storedValue}, res)
+	})
+
+	t.Run("overridden class hash", func(t *testing.T) {
+		overrideClassHash := new(felt.Felt).SetUint64(7)
+		expectCall(overrideClassHash)
+		mockState.EXPECT().ContractStorage(contractAddr, overriddenKey).Return(storedValue, nil)
+		mockState.EXPECT().ContractStorage(contractAddr, otherKey).Return(storedValue, nil)
+
+		res, rpcErr := handler.CallWithOverrides(rpc.FunctionCall{ContractAddress: *contractAddr},
+			rpc.BlockID{Latest: true}, []rpc.StateOverride{{
+				ContractAddress: *contractAddr,
+				ClassHash:       overrideClassHash,
+			}})
+		require.Nil(t, rpcErr)
+		assert.Equal(t, []*felt.Felt{storedValue, storedValue}, res)
+	})
+}
+
+func TestEstimateMessageFee(t *testing.T) {
+	t.Skip()
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&utils.Mainnet).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
+	msg := rpc.MsgFromL1{
+		From:     common.HexToAddress("0xDEADBEEF"),
+		To:       *new(felt.Felt).SetUint64(1337),
+		Payload:  []felt.Felt{*new(felt.Felt).SetUint64(1), *new(felt.Felt).SetUint64(2)},
+		Selector: *new(felt.Felt).SetUint64(44),
+	}
+
+	t.Run("block not found", func(t *testing.T) {
+		mockReader.EXPECT().HeadState().Return(nil, nil, db.ErrKeyNotFound)
+		_, err := handler.EstimateMessageFeeV0_6(msg, rpc.BlockID{Latest: true})
+		require.Equal(t, rpc.ErrBlockNotFound, err)
+	})
+
+	latestHeader := &core.Header{
+		Number:    9,
+		Timestamp: 456,
+		GasPrice:  new(felt.Felt).SetUint64(42),
+	}
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+	mockReader.EXPECT().HeadsHeader().Return(latestHeader, nil)
+
+	expectedGasConsumed := new(felt.Felt).SetUint64(37)
+	mockVM.EXPECT().Execute(gomock.Any(), gomock.Any(), gomock.Any(), &vm.BlockInfo{
+		Header: latestHeader,
+	}, gomock.Any(), &utils.Mainnet, gomock.Any(), false, true, false).DoAndReturn(
+		func(txns []core.Transaction, declaredClasses []core.Class, paidFeesOnL1 []*felt.Felt, blockInfo *vm.BlockInfo,
+			state core.StateReader, network *utils.Network, skipChargeFee, skipValidate, errOnRevert bool,
+		) ([]*felt.Felt, []*felt.Felt, []vm.TransactionTrace, error) {
+			require.Len(t, txns, 1)
+			assert.NotNil(t, txns[0].(*core.L1HandlerTransaction))
+
+			assert.Empty(t, declaredClasses)
+			assert.Len(t, paidFeesOnL1, 1)
+
+			actualFee := new(felt.Felt).Mul(expectedGasConsumed, blockInfo.Header.GasPrice)
+			return []*felt.Felt{actualFee}, []*felt.Felt{&felt.Zero}, []vm.TransactionTrace{{
+				StateDiff: &vm.StateDiff{
+					StorageDiffs:              []vm.StorageDiff{},
+					Nonces:                    []vm.Nonce{},
+					DeployedContracts:         []vm.DeployedContract{},
+					DeprecatedDeclaredClasses: []*felt.Felt{},
+					DeclaredClasses:           []vm.DeclaredClass{},
+					ReplacedClasses:           []vm.ReplacedClass{},
+				},
+			}}, nil
+		},
+	)
+
+	estimateFee, err := handler.EstimateMessageFeeV0_6(msg, rpc.BlockID{Latest: true})
+	require.Nil(t, err)
+	feeUnit := rpc.WEI
+	require.Equal(t, rpc.FeeEstimate{
+		GasConsumed: expectedGasConsumed,
+		GasPrice:    latestHeader.GasPrice,
+		OverallFee:  new(felt.Felt).Mul(expectedGasConsumed, latestHeader.GasPrice),
+		Unit:        &feeUnit,
+	}, *estimateFee)
+}
+
+func TestEstimateMessageFeeValidation(t *testing.T) {
+	handler := rpc.New(nil, nil, nil, "", utils.NewNopZapLogger())
+
+	t.Run("over-long from address", func(t *testing.T) {
+		// from_address is a 20 byte address, so the request fails while its params are decoded
+		server := jsonrpc.NewServer(1, utils.NewNopZapLogger())
+		require.NoError(t, server.RegisterMethods(jsonrpc.Method{
+			Name:    "starknet_estimateMessageFee",
+			Params:  []jsonrpc.Parameter{{Name: "message"}, {Name: "block_id"}},
+			Handler: handler.EstimateMessageFee,
+		}))
+
+		res, err := server.HandleReader(context.Background(), strings.NewReader(`{
+			"jsonrpc": "2.0",
+			"id": 1,
+			"method": "starknet_estimateMessageFee",
+			"params": [{
+				"from_address": "0x00DEADBEEF00000000000000000000000000000000",
+				"to_address": "0x1",
+				"entry_point_selector": "0x2",
+				"payload": []
+			}, "latest"]
+		}`))
+		require.NoError(t, err)
+
+		var resp struct {
+			Error *jsonrpc.Error `json:"error"`
+		}
+		require.NoError(t, json.Unmarshal(res, &resp))
+		require.NotNil(t, resp.Error)
+		assert.Equal(t, jsonrpc.InvalidParams, resp.Error.Code)
+	})
+
+	validMsg := rpc.MsgFromL1{
+		From:     common.HexToAddress("0xDEADBEEF"),
+		To:       *new(felt.Felt).SetUint64(1337),
+		Selector: *new(felt.Felt).SetUint64(44),
+	}
+	for name, msg := range map[string]rpc.MsgFromL1{
+		"missing to address": {From: validMsg.From, Selector: validMsg.Selector},
+		"missing selector":   {From: validMsg.From, To: validMsg.To},
+	} {
+		t.Run(name, func(t *testing.T) {
+			estimate, rpcErr := handler.EstimateMessageFee(msg, rpc.BlockID{Latest: true})
+			assert.Nil(t, estimate)
+			require.NotNil(t, rpcErr)
+			assert.Equal(t, jsonrpc.InvalidParams, rpcErr.Code)
+		})
+	}
+}
+
+func TestEstimateMessageFeeMany(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+
+	network := utils.Mainnet
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&network).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
+
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+	mockReader.EXPECT().HeadsHeader().Return(&core.Header{GasPrice: new(felt.Felt).SetUint64(10)}, nil)
+
+	failingSelector := new(felt.Felt).SetUint64(0xbad)
+	newMsg := func(selector uint64) rpc.MsgFromL1 {
+		return rpc.MsgFromL1{
+			From:     common.HexToAddress("0xDEADBEEF"),
+			To:       *new(felt.Felt).SetUint64(1337),
+			Payload:  []felt.Felt{*new(felt.Felt).SetUint64(selector)},
+			Selector: *new(felt.Felt).SetUint64(selector),
+		}
+	}
+	msgs := []rpc.MsgFromL1{
+		newMsg(1),
+		newMsg(failingSelector.Uint64()),
+		{From: common.HexToAddress("0xDEADBEEF"), To: *new(felt.Felt).SetUint64(1337)}, // no selector
+		newMsg(4),
+	}
+
+	// every handled message costs 10 times its selector, the failing one is rejected by the L2 contract. The
+	// messages before it run once more to keep their changes, and the ones after it run on top of those.
+	mockVM.EXPECT().Execute(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), &network,
+		true, false, true, true).DoAndReturn(
+		func(txns []core.Transaction, _ []core.Class, paidFeesOnL1 []*felt.Felt, _ *vm.BlockInfo, _ core.StateReader,
+			_ *utils.Network, _, _, _, _ bool,
+		) ([]*felt.Felt, []*felt.Felt, []vm.TransactionTrace, error) {
+			require.Len(t, paidFeesOnL1, len(txns))
+			var fees, dataGas []*felt.Felt
+			var traces []vm.TransactionTrace
+			for i, txn := range txns {
+				l1Handler, ok := txn.(*core.L1HandlerTransaction)
+				require.True(t, ok)
+				if l1Handler.EntryPointSelector.Equal(failingSelector) {
+					return nil, nil, nil, vm.TransactionExecutionError{Index: uint64(i), Cause: errors.New("message rejected")}
+				}
+				fees = append(fees, new(felt.Felt).Mul(l1Handler.EntryPointSelector, new(felt.Felt).SetUint64(10)))
+				dataGas = append(dataGas, &felt.Zero)
+				traces = append(traces, vm.TransactionTrace{})
+			}
+			return fees, dataGas, traces, nil
+		}).Times(3)
+
+	results, rpcErr := handler.EstimateMessageFeeMany(msgs, rpc.BlockID{Latest: true})
+	require.Nil(t, rpcErr)
+	require.Len(t, results, len(msgs))
+
+	for _, i := range []int{0, 3} {
+		require.Nil(t, results[i].Error, i)
+		assert.Equal(t, msgs[i].Selector.Uint64(), results[i].FeeEstimate.GasConsumed.Uint64(), i)
+	}
+
+	assert.Nil(t, results[1].FeeEstimate)
+	assert.Equal(t, rpc.ErrContractError.CloneWithData(rpc.ContractErrorData{RevertError: "message rejected"}), results[1].Error)
+
+	assert.Nil(t, results[2].FeeEstimate)
+	require.NotNil(t, results[2].Error)
+	assert.Equal(t, jsonrpc.InvalidParams, results[2].Error.Code)
+}
+
+func TestEstimateMessageFeeUnit(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+
+	network := utils.Mainnet
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&network).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
+
+	msg := rpc.MsgFromL1{
+		From:     common.HexToAddress("0xDEADBEEF"),
+		To:       *new(felt.Felt).SetUint64(1337),
+		Payload:  []felt.Felt{*new(felt.Felt).SetUint64(1)},
+		Selector: *new(felt.Felt).SetUint64(44),
+	}
+	header := &core.Header{
+		GasPrice:     new(felt.Felt).SetUint64(10),
+		GasPriceSTRK: new(felt.Felt).SetUint64(20),
+		L1DataGasPrice: &core.GasPrice{
+			PriceInWei: new(felt.Felt).SetUint64(2),
+			PriceInFri: new(felt.Felt).SetUint64(3),
+		},
+	}
+
+	expectExecute := func(overallFee uint64, useBlobData bool) {
+		mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+		mockReader.EXPECT().HeadsHeader().Return(header, nil)
+		mockVM.EXPECT().Execute(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+			true, false, true, useBlobData).DoAndReturn(
+			func(txns []core.Transaction, _ []core.Class, _ []*felt.Felt, _ *vm.BlockInfo, _ core.StateReader,
+				_ *utils.Network, _, _, _, _ bool,
+			) ([]*felt.Felt, []*felt.Felt, []vm.TransactionTrace, error) {
+				require.Len(t, txns, 1)
+				require.IsType(t, &core.L1HandlerTransaction{}, txns[0])
+				return []*felt.Felt{new(felt.Felt).SetUint64(overallFee)}, []*felt.Felt{new(felt.Felt).SetUint64(4)},
+					[]vm.TransactionTrace{{}}, nil
+			})
+	}
+
+	t.Run("v0.7 response is priced in wei and carries data gas", func(t *testing.T) {
+		expectExecute(108, true)
+
+		estimate, rpcErr := handler.EstimateMessageFee(msg, rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+
+		estimateJSON, err := json.Marshal(estimate)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"gas_consumed": "0xa",
+			"gas_price": "0xa",
+			"data_gas_consumed": "0x4",
+			"data_gas_price": "0x2",
+			"overall_fee": "0x6c",
+			"unit": "WEI"
+		}`, string(estimateJSON))
+	})
+
+	t.Run("v0.6 response is priced in wei without data gas", func(t *testing.T) {
+		expectExecute(100, false)
+
+		estimate, rpcErr := handler.EstimateMessageFeeV0_6(msg, rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+
+		estimateJSON, err := json.Marshal(estimate)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"gas_consumed": "0xa",
+			"gas_price": "0xa",
+			"overall_fee": "0x64",
+			"unit": "WEI"
+		}`, string(estimateJSON))
+	})
+}
+
+func TestTraceTransaction(t *testing.T) {
+	t.Skip()
+
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&utils.Mainnet).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
+
+	t.Run("not found", func(t *testing.T) {
+		hash := utils.HexToFelt(t, "0xBBBB")
+		// Receipt() returns error related to db
+		mockReader.EXPECT().Receipt(hash).Return(nil, nil, uint64(0), db.ErrKeyNotFound)
+
+		trace, err := handler.TraceTransaction(context.Background(), *hash)
+		assert.Nil(t, trace)
+		assert.Equal(t, rpc.ErrTxnHashNotFound, err)
+	})
+	t.Run("ok", func(t *testing.T) {
+		hash := utils.HexToFelt(t, "0x37b244ea7dc6b3f9735fba02d183ef0d6807a572dd91a63cc1b14b923c1ac0")
+		tx := &core.DeclareTransaction{
+			TransactionHash: hash,
+			ClassHash:       utils.HexToFelt(t, "0x000000000"),
+		}
+
+		header := &core.Header{
+			Hash:             utils.HexToFelt(t, "0xCAFEBABE"),
+			ParentHash:       utils.HexToFelt(t, "0x0"),
+			Number:           0,
+			SequencerAddress: utils.HexToFelt(t, "0X111"),
+			ProtocolVersion:  "99.12.3",
+		}
+		block := &core.Block{
+			Header:       header,
+			Transactions: []core.Transaction{tx},
+		}
+		declaredClass := &core.DeclaredClass{
+			At:    3002,
+			Class: &core.Cairo1Class{},
+		}
+
+		mockReader.EXPECT().Receipt(hash).Return(nil, header.Hash, header.Number, nil)
+		mockReader.EXPECT().BlockByNumber(header.Number).Return(block, nil)
+
+		mockReader.EXPECT().StateAtBlockHash(header.ParentHash).Return(nil, nopCloser, nil)
+		headState := mocks.NewMockStateHistoryReader(mockCtrl)
+		headState.EXPECT().Class(tx.ClassHash).Return(declaredClass, nil)
+		mockReader.EXPECT().HeadState().Return(headState, nopCloser, nil)
+
+		vmTraceJSON := json.RawMessage(`{
+		"validate_invocation": {"contract_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "entry_point_selector": "0x162da33a4585851fe8d3af3c2a9c60b557814e221e0d4f30ff0b2189d9c7775", "calldata": ["0x2", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x219209e083275171774dab1df80982e9df2096516f06319c5c6d71ae0a8480c", "0x0", "0x3", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1171593aa5bdadda4d6b0efde6cc94ee7649c3163d5efeb19da6c16d63a2a63", "0x3", "0x10", "0x13", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x1e8480", "0x0", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x420eeb770f7a4", "0x0", "0x40139799e37e4", "0x0", "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x0", "0x0", "0x1", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x64"], "caller_address": "0x0", "class_hash": "0x25ec026985a3bf9d0cc1fe17326b245dfdc3ff89b8fde106542a3ea56c5a918", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": [], "calls": [{"contract_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "entry_point_selector": "0x162da33a4585851fe8d3af3c2a9c60b557814e221e0d4f30ff0b2189d9c7775", "calldata": ["0x2", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x219209e083275171774dab1df80982e9df2096516f06319c5c6d71ae0a8480c", "0x0", "0x3", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1171593aa5bdadda4d6b0efde6cc94ee7649c3163d5efeb19da6c16d63a2a63", "0x3", "0x10", "0x13", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x1e8480", "0x0", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x420eeb770f7a4", "0x0", "0x40139799e37e4", "0x0", "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x0", "0x0", "0x1", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x64"], "caller_address": "0x0", "class_hash": "0x33434ad846cdd5f23eb73ff09fe6fddd568284a0fb7d1be20ee482f044dabe2", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": [], "calls": [], "events": [], "messages": []}], "events": [], "messages": []},
+		"execute_invocation": {"contract_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "entry_point_selector": "0x15d40a3d6ca2ac30f4031e42be28da9b056fef9bb7357ac5e85627ee876e5ad", "calldata": ["0x2", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x219209e083275171774dab1df80982e9df2096516f06319c5c6d71ae0a8480c", "0x0", "0x3", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1171593aa5bdadda4d6b0efde6cc94ee7649c3163d5efeb19da6c16d63a2a63", "0x3", "0x10", "0x13", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x1e8480", "0x0", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x420eeb770f7a4", "0x0", "0x40139799e37e4", "0x0", "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x0", "0x0", "0x1", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x64"], "caller_address": "0x0", "class_hash": "0x25ec026985a3bf9d0cc1fe17326b245dfdc3ff89b8fde106542a3ea56c5a918", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1", "0x1"], "calls": [{"contract_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "entry_point_selector": "0x15d40a3d6ca2ac30f4031e42be28da9b056fef9bb7357ac5e85627ee876e5ad", "calldata": ["0x2", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x219209e083275171774dab1df80982e9df2096516f06319c5c6d71ae0a8480c", "0x0", "0x3", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1171593aa5bdadda4d6b0efde6cc94ee7649c3163d5efeb19da6c16d63a2a63", "0x3", "0x10", "0x13", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x1e8480", "0x0", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x420eeb770f7a4", "0x0", "0x40139799e37e4", "0x0", "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x0", "0x0", "0x1", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x64"], "caller_address": "0x0", "class_hash": "0x33434ad846cdd5f23eb73ff09fe6fddd568284a0fb7d1be20ee482f044dabe2", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1", "0x1"], "calls": [{"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x219209e083275171774dab1df80982e9df2096516f06319c5c6d71ae0a8480c", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0"], "caller_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "class_hash": "0x52c7ba99c77fc38dd3346beea6c0753c3471f2e3135af5bb837d6c9523fff62", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1"], "calls": [{"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x219209e083275171774dab1df80982e9df2096516f06319c5c6d71ae0a8480c", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0"], "caller_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1"], "calls": [], "events": [{"keys": ["0x134692b230b9e1ffa39098904722134159652b09c5bc41d88d6698779d228ff"], "data": ["0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0"]}], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "entry_point_selector": "0x1171593aa5bdadda4d6b0efde6cc94ee7649c3163d5efeb19da6c16d63a2a63", "calldata": ["0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x1e8480", "0x0", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x420eeb770f7a4", "0x0", "0x40139799e37e4", "0x0", "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x0", "0x0", "0x1", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x64"], "caller_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "class_hash": "0x5ee939756c1a60b029c594da00e637bf5923bf04a86ff163e877e899c0840eb", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1"], "calls": [{"contract_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "entry_point_selector": "0x1171593aa5bdadda4d6b0efde6cc94ee7649c3163d5efeb19da6c16d63a2a63", "calldata": ["0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x1e8480", "0x0", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x420eeb770f7a4", "0x0", "0x40139799e37e4", "0x0", "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x0", "0x0", "0x1", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x64"], "caller_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "class_hash": "0x38627c278c0b3cb3c84ddee2c783fb22c3c3a3f0e667ea2b82be0ea2253bce4", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1"], "calls": [{"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x41b033f4a31df8067c24d1e9b550a2ce75fd4a29e1147af9752174f0e6cb20", "calldata": ["0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x52c7ba99c77fc38dd3346beea6c0753c3471f2e3135af5bb837d6c9523fff62", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1"], "calls": [{"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x41b033f4a31df8067c24d1e9b550a2ce75fd4a29e1147af9752174f0e6cb20", "calldata": ["0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1"], "calls": [], "events": [{"keys": ["0x99cd8bde557814842a3121e8ddfd433a539b8c9f14bf31ebf108d12e6196e9"], "data": ["0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x1e8480", "0x0"]}], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x1ed6790cdca923073adc728080b06c159d9784cc9bf8fb26181acfdbe4256e6", "entry_point_selector": "0x260bb04cf90403013190e77d7e75f3d40d3d307180364da33c63ff53061d4e8", "calldata": [], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x5ee939756c1a60b029c594da00e637bf5923bf04a86ff163e877e899c0840eb", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x0", "0x0", "0x5"], "calls": [{"contract_address": "0x1ed6790cdca923073adc728080b06c159d9784cc9bf8fb26181acfdbe4256e6", "entry_point_selector": "0x260bb04cf90403013190e77d7e75f3d40d3d307180364da33c63ff53061d4e8", "calldata": [], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x46668cd07d83af5d7158e7cd62c710f1a7573501bcd4f4092c6a4e1ecd2bf61", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x0", "0x0", "0x5"], "calls": [], "events": [], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x2e4263afad30923c891518314c3c95dbe830a16874e8abc5777a9a20b54c76e", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x52c7ba99c77fc38dd3346beea6c0753c3471f2e3135af5bb837d6c9523fff62", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1e8480", "0x0"], "calls": [{"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x2e4263afad30923c891518314c3c95dbe830a16874e8abc5777a9a20b54c76e", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1e8480", "0x0"], "calls": [], "events": [], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "entry_point_selector": "0x15543c3708653cda9d418b4ccd3be11368e40636c10c44b18cfe756b6d88b29", "calldata": ["0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x1e8480", "0x0", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x0", "0x0", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f"], "caller_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "class_hash": "0x2ceb6369dba6af865bca639f9f1342dfb1ae4e5d0d0723de98028b812e7cdd2", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": [], "calls": [{"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x219209e083275171774dab1df80982e9df2096516f06319c5c6d71ae0a8480c", "calldata": ["0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x1e8480", "0x0"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x52c7ba99c77fc38dd3346beea6c0753c3471f2e3135af5bb837d6c9523fff62", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1"], "calls": [{"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x219209e083275171774dab1df80982e9df2096516f06319c5c6d71ae0a8480c", "calldata": ["0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x1e8480", "0x0"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1"], "calls": [], "events": [{"keys": ["0x134692b230b9e1ffa39098904722134159652b09c5bc41d88d6698779d228ff"], "data": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x1e8480", "0x0"]}], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "entry_point_selector": "0x2c0f7bf2d6cf5304c29171bf493feb222fef84bdaf17805a6574b0c2e8bcc87", "calldata": ["0x1e8480", "0x0", "0x0", "0x0", "0x2", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x648f780a"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x514718bb56ed2a8607554c7d393c2ffd73cbab971c120b00a2ce27cc58dd1c1", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x2", "0x1e8480", "0x0", "0x417c36e4fc16d", "0x0"], "calls": [{"contract_address": "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "entry_point_selector": "0x3c388f7eb137a89061c6f0b6e78bae453202258b0b3c419f8dd9814a547d406", "calldata": [], "caller_address": "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "class_hash": "0x231adde42526bad434ca2eb983efdd64472638702f87f97e6e3c084f264e06f", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x178b60b3a0bcc4aa98", "0xaf07589b7c", "0x648f7422"], "calls": [], "events": [], "messages": []}, {"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x41b033f4a31df8067c24d1e9b550a2ce75fd4a29e1147af9752174f0e6cb20", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "0x1e8480", "0x0"], "caller_address": "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "class_hash": "0x52c7ba99c77fc38dd3346beea6c0753c3471f2e3135af5bb837d6c9523fff62", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1"], "calls": [{"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x41b033f4a31df8067c24d1e9b550a2ce75fd4a29e1147af9752174f0e6cb20", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "0x1e8480", "0x0"], "caller_address": "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1"], "calls": [], "events": [{"keys": ["0x99cd8bde557814842a3121e8ddfd433a539b8c9f14bf31ebf108d12e6196e9"], "data": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "0x1e8480", "0x0"]}], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "entry_point_selector": "0x15543c3708653cda9d418b4ccd3be11368e40636c10c44b18cfe756b6d88b29", "calldata": ["0x417c36e4fc16d", "0x0", "0x0", "0x0", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f"], "caller_address": "0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "class_hash": "0x231adde42526bad434ca2eb983efdd64472638702f87f97e6e3c084f264e06f", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": [], "calls": [{"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x417c36e4fc16d", "0x0"], "caller_address": "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "class_hash": "0xd0e183745e9dae3e4e78a8ffedcce0903fc4900beace4e0abf192d4c202da3", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1"], "calls": [{"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x417c36e4fc16d", "0x0"], "caller_address": "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1"], "calls": [], "events": [{"keys": ["0x99cd8bde557814842a3121e8ddfd433a539b8c9f14bf31ebf108d12e6196e9"], "data": ["0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0x417c36e4fc16d", "0x0"]}], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x2e4263afad30923c891518314c3c95dbe830a16874e8abc5777a9a20b54c76e", "calldata": ["0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325"], "caller_address": "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "class_hash": "0xd0e183745e9dae3e4e78a8ffedcce0903fc4900beace4e0abf192d4c202da3", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x178b5c9bdd4e74e92b", "0x0"], "calls": [{"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x2e4263afad30923c891518314c3c95dbe830a16874e8abc5777a9a20b54c76e", "calldata": ["0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325"], "caller_address": "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x178b5c9bdd4e74e92b", "0x0"], "calls": [], "events": [], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x2e4263afad30923c891518314c3c95dbe830a16874e8abc5777a9a20b54c76e", "calldata": ["0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325"], "caller_address": "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "class_hash": "0x52c7ba99c77fc38dd3346beea6c0753c3471f2e3135af5bb837d6c9523fff62", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0xaf07771ffc", "0x0"], "calls": [{"contract_address": "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "entry_point_selector": "0x2e4263afad30923c891518314c3c95dbe830a16874e8abc5777a9a20b54c76e", "calldata": ["0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325"], "caller_address": "0x23c72abdf49dffc85ae3ede714f2168ad384cc67d08524732acea90df325", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0xaf07771ffc", "0x0"], "calls": [], "events": [], "messages": []}], "events": [], "messages": []}], "events": [{"keys": ["0xe14a408baf7f453312eec68e9b7d728ec5337fbdf671f917ee8c80f3255232"], "data": ["0x178b5c9bdd4e74e92b", "0xaf07771ffc"]}, {"keys": ["0xe316f0d9d2a3affa97de1d99bb2aac0538e2666d0d8545545ead241ef0ccab"], "data": ["0x7a6f98c03379b9513ca84cca1373ff452a7462a3b61598f0af5bb27ad7f76d1", "0x0", "0x0", "0x1e8480", "0x0", "0x417c36e4fc16d", "0x0", "0x0", "0x0", "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f"]}], "messages": []}], "events": [], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x2e4263afad30923c891518314c3c95dbe830a16874e8abc5777a9a20b54c76e", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0xd0e183745e9dae3e4e78a8ffedcce0903fc4900beace4e0abf192d4c202da3", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x417c36e4fc16d", "0x0"], "calls": [{"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x2e4263afad30923c891518314c3c95dbe830a16874e8abc5777a9a20b54c76e", "calldata": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x417c36e4fc16d", "0x0"], "calls": [], "events": [], "messages": []}], "events": [], "messages": []}, {"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e", "calldata": ["0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x417c36e4fc16d", "0x0"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0xd0e183745e9dae3e4e78a8ffedcce0903fc4900beace4e0abf192d4c202da3", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1"], "calls": [{"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e", "calldata": ["0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x417c36e4fc16d", "0x0"], "caller_address": "0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1"], "calls": [], "events": [{"keys": ["0x99cd8bde557814842a3121e8ddfd433a539b8c9f14bf31ebf108d12e6196e9"], "data": ["0x4270219d365d6b017231b52e92b3fb5d7c8378b05e9abc97724537a80e93b0f", "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x417c36e4fc16d", "0x0"]}], "messages": []}], "events": [], "messages": []}], "events": [{"keys": ["0xe316f0d9d2a3affa97de1d99bb2aac0538e2666d0d8545545ead241ef0ccab"], "data": ["0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x53c91253bc9682c04929ca02ed00b3e423f6710d2ee7e0d5ebb06f3ecf368a8", "0x1e8480", "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "0x417c36e4fc16d", "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a"]}], "messages": []}], "events": [], "messages": []}], "events": [{"keys": ["0x5ad857f66a5b55f1301ff1ed7e098ac6d4433148f0b72ebc4a2945ab85ad53"], "data": ["0x2fc5e96de394697c1311606c96ec14840e408493fd42cf0c54b73b39d312b81", "0x2", "0x1", "0x1"]}], "messages": []}], "events": [], "messages": []},
+		"fee_transfer_invocation": {"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e", "calldata": ["0x1176a1bd84444c89232ec27754698e5d2e7e1a7f1539f12027f28b23ec9f3d8", "0x2cb6", "0x0"], "caller_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "class_hash": "0xd0e183745e9dae3e4e78a8ffedcce0903fc4900beace4e0abf192d4c202da3", "entry_point_type": "EXTERNAL", "call_type": "CALL", "result": ["0x1"], "calls": [{"contract_address": "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7", "entry_point_selector": "0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e", "calldata": ["0x1176a1bd84444c89232ec27754698e5d2e7e1a7f1539f12027f28b23ec9f3d8", "0x2cb6", "0x0"], "caller_address": "0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "class_hash": "0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0", "entry_point_type": "EXTERNAL", "call_type": "DELEGATE", "result": ["0x1"], "calls": [], "events": [{"keys": ["0x99cd8bde557814842a3121e8ddfd433a539b8c9f14bf31ebf108d12e6196e9"], "data": ["0xd747220b2744d8d8d48c8a52bd3869fb98aea915665ab2485d5eadb49def6a", "0x1176a1bd84444c89232ec27754698e5d2e7e1a7f1539f12027f28b23ec9f3d8", "0x2cb6", "0x0"]}], "messages": []}], "events": [], "messages": []},
+		"state_diff": {
+			"storage_diffs": [],
+			"nonces": [],
+			"deployed_contracts": [],
+			"deprecated_declared_classes": [],
+			"declared_classes": [],
+			"replaced_classes": []
+		}
+	}`)
+		vmTrace := new(vm.TransactionTrace)
+		require.NoError(t, json.Unmarshal(vmTraceJSON, vmTrace))
+		mockVM.EXPECT().Execute([]core.Transaction{tx}, []core.Class{declaredClass.Class}, []*felt.Felt{},
+			&vm.BlockInfo{Header: header}, gomock.Any(), &utils.Mainnet, false, false, false, false).Return(nil, []vm.TransactionTrace{*vmTrace}, nil)
+
+		trace, err := handler.TraceTransaction(context.Background(), *hash)
+		require.Nil(t, err)
+		assert.Equal(t, vmTrace, trace)
+	})
+}
+
+func TestTransactionStorageKeys(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&utils.Mainnet).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
+
+	t.Run("not found", func(t *testing.T) {
+		hash := utils.HexToFelt(t, "0xBBBB")
+		mockReader.EXPECT().Receipt(hash).Return(nil, nil, uint64(0), db.ErrKeyNotFound)
+
+		keys, err := handler.TransactionStorageKeys(context.Background(), *hash)
+		assert.Nil(t, keys)
+		assert.Equal(t, rpc.ErrTxnHashNotFound, err)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		hash := utils.HexToFelt(t, "0x1234")
+		tx := &core.InvokeTransaction{
+			TransactionHash: hash,
+			Version:         new(core.TransactionVersion).SetUint64(1),
+		}
+		header := &core.Header{
+			Hash:            utils.HexToFelt(t, "0xCAFEBABE"),
+			ParentHash:      utils.HexToFelt(t, "0x0"),
+			Number:          0,
+			ProtocolVersion: "0.13.1",
+			GasPrice:        new(felt.Felt).SetUint64(1),
+		}
+		block := &core.Block{
+			Header:       header,
+			Transactions: []core.Transaction{tx},
+		}
+
+		mockReader.EXPECT().Receipt(hash).Return(nil, header.Hash, header.Number, nil)
+		mockReader.EXPECT().BlockByNumber(header.Number).Return(block, nil)
+		mockReader.EXPECT().StateAtBlockHash(header.ParentHash).Return(nil, nopCloser, nil)
+		mockReader.EXPECT().HeadState().Return(mocks.NewMockStateHistoryReader(mockCtrl), nopCloser, nil)
+
+		contractA := *utils.HexToFelt(t, "0xA")
+		contractB := *utils.HexToFelt(t, "0xB")
+		vmTrace := vm.TransactionTrace{
+			Type: vm.TxnInvoke,
+			StateDiff: &vm.StateDiff{
+				StorageDiffs: []vm.StorageDiff{
+					{
+						Address: contractA,
+						StorageEntries: []vm.Entry{
+							{Key: *utils.HexToFelt(t, "0x1"), Value: *utils.HexToFelt(t, "0x11")},
+							{Key: *utils.HexToFelt(t, "0x2"), Value: *utils.HexToFelt(t, "0x22")},
+						},
+					},
+					{
+						Address: contractB,
+						StorageEntries: []vm.Entry{
+							{Key: *utils.HexToFelt(t, "0x3"), Value: *utils.HexToFelt(t, "0x33")},
+						},
+					},
+				},
+			},
+		}
+		mockVM.EXPECT().Execute([]core.Transaction{tx}, nil, []*felt.Felt{}, &vm.BlockInfo{Header: header},
+			gomock.Any(), &utils.Mainnet, false, false, false, true).
+			Return([]*felt.Felt{&felt.Zero}, []*felt.Felt{&felt.Zero}, []vm.TransactionTrace{vmTrace}, nil)
+
+		keys, err := handler.TransactionStorageKeys(context.Background(), *hash)
+		require.Nil(t, err)
+		assert.Equal(t, []rpc.ContractKeys{
+			{
+				ContractAddress: contractA,
+				StorageKeys:     []felt.Felt{*utils.HexToFelt(t, "0x1"), *utils.HexToFelt(t, "0x2")},
+			},
+			{
+				ContractAddress: contractB,
+				StorageKeys:     []felt.Felt{*utils.HexToFelt(t, "0x3")},
+			},
+		}, keys)
+	})
+}
+
+func TestSimulateTransactions(t *testing.T) {
+	t.Skip()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	network := utils.Mainnet
+
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&network).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
+
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil).AnyTimes()
+	headsHeader := &core.Header{
+		SequencerAddress: network.BlockHashMetaInfo.FallBackSequencerAddress,
+	}
+	mockReader.EXPECT().HeadsHeader().Return(headsHeader, nil).AnyTimes()
+
+	t.Run("ok with zero values, skip fee", func(t *testing.T) {
+		mockVM.EXPECT().Execute(nil, nil, []*felt.Felt{}, &vm.BlockInfo{
+			Header: headsHeader,
+		}, mockState, &network, true, false, false, false).
+			Return([]*felt.Felt{}, []vm.TransactionTrace{}, nil)
+
+		_, err := handler.SimulateTransactions(rpc.BlockID{Latest: true}, []rpc.BroadcastedTransaction{}, []rpc.SimulationFlag{rpc.SkipFeeChargeFlag})
+		require.Nil(t, err)
+	})
+
+	t.Run("ok with zero values, skip validate", func(t *testing.T) {
+		mockVM.EXPECT().Execute(nil, nil, []*felt.Felt{}, &vm.BlockInfo{
+			Header: headsHeader,
+		}, mockState, &network, false, false, false, false).
+			Return([]*felt.Felt{}, []vm.TransactionTrace{}, nil)
+
+		_, err := handler.SimulateTransactions(rpc.BlockID{Latest: true}, []rpc.BroadcastedTransaction{}, []rpc.SimulationFlag{rpc.SkipValidateFlag})
+		require.Nil(t, err)
+	})
+
+	t.Run("transaction execution error", func(t *testing.T) {
+		mockVM.EXPECT().Execute(nil, nil, []*felt.Felt{}, &vm.BlockInfo{
+			Header: headsHeader,
+		}, mockState, &network, false, false, false, false).
+			Return(nil, nil, vm.TransactionExecutionError{
+				Index: 44,
+				Cause: errors.New("oops"),
+			})
+
+		_, err := handler.SimulateTransactions(rpc.BlockID{Latest: true}, []rpc.BroadcastedTransaction{}, []rpc.SimulationFlag{rpc.SkipValidateFlag})
+		require.Equal(t, rpc.ErrTransactionExecutionError.CloneWithData(rpc.TransactionExecutionErrorData{
+			TransactionIndex: 44,
+			ExecutionError:   "oops",
+		}), err)
+
+		mockVM.EXPECT().Execute(nil, nil, []*felt.Felt{}, &vm.BlockInfo{
+			Header: headsHeader,
+		}, mockState, &network, false, true, true, false).
+			Return(nil, nil, vm.TransactionExecutionError{
+				Index: 44,
+				Cause: errors.New("oops"),
+			})
+
+		_, err = handler.SimulateTransactionsV0_6(rpc.BlockID{Latest: true}, []rpc.BroadcastedTransaction{}, []rpc.SimulationFlag{rpc.SkipValidateFlag})
+		require.Equal(t, rpc.ErrContractError.CloneWithData(rpc.ContractErrorData{
+			RevertError: "oops",
+		}), err)
+	})
+}
+
+func TestTraceBlockTransactions(t *testing.T) {
+	t.Skip()
+
+	errTests := map[string]rpc.BlockID{
+		"latest":  {Latest: true},
+		"pending": {Pending: true},
+		"hash":    {Hash: new(felt.Felt).SetUint64(1)},
+		"number":  {Number: 1},
+	}
+
+	for description, id := range errTests {
+		t.Run(description, func(t *testing.T) {
+			log := utils.NewNopZapLogger()
+			network := utils.Mainnet
+			chain := blockchain.New(pebble.NewMemTest(t), &network)
+			handler := rpc.New(chain, nil, nil, "", log)
+
+			update, rpcErr := handler.TraceBlockTransactions(context.Background(), id)
+			assert.Nil(t, update)
+			assert.Equal(t, rpc.ErrBlockNotFound, rpcErr)
+		})
+	}
+
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+	network := utils.Mainnet
+
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&network).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	log := utils.NewNopZapLogger()
 
 	handler := rpc.New(mockReader, nil, mockVM, "", log)
 
-	t.Run("pending block", func(t *testing.T) {
+	t.Run("pending block", func(t *testing.T) {
+		blockHash := utils.HexToFelt(t, "0x0001")
+		header := &core.Header{
+			// hash is not set because it's pending block
+			ParentHash:      utils.HexToFelt(t, "0x0C3"),
+			Number:          0,
+			GasPrice:        utils.HexToFelt(t, "0x777"),
+			ProtocolVersion: "99.12.3",
+		}
+		l1Tx := &core.L1HandlerTransaction{
+			TransactionHash: utils.HexToFelt(t, "0x000000C"),
+		}
+		declaredClass := &core.DeclaredClass{
+			At:    3002,
+			Class: &core.Cairo1Class{},
+		}
+		declareTx := &core.DeclareTransaction{
+			TransactionHash: utils.HexToFelt(t, "0x000000001"),
+			ClassHash:       utils.HexToFelt(t, "0x00000BC00"),
+		}
+		block := &core.Block{
+			Header:       header,
+			Transactions: []core.Transaction{l1Tx, declareTx},
+		}
+
+		mockReader.EXPECT().BlockByHash(blockHash).Return(block, nil)
+		state := mocks.NewMockStateHistoryReader(mockCtrl)
+		mockReader.EXPECT().StateAtBlockHash(header.ParentHash).Return(state, nopCloser, nil)
+		headState := mocks.NewMockStateHistoryReader(mockCtrl)
+		headState.EXPECT().Class(declareTx.ClassHash).Return(declaredClass, nil)
+		mockReader.EXPECT().PendingState().Return(headState, nopCloser, nil)
+
+		paidL1Fees := []*felt.Felt{(&felt.Felt{}).SetUint64(1)}
+		vmTraceJSON := json.RawMessage(`{
+			"validate_invocation": {},
+			"execute_invocation": {},
+			"fee_transfer_invocation": {},
+			"state_diff": {
+				"storage_diffs": [],
+				"nonces": [],
+				"deployed_contracts": [],
+				"deprecated_declared_classes": [],
+				"declared_classes": [],
+				"replaced_classes": []
+			}
+		}`)
+		vmTrace := vm.TransactionTrace{}
+		require.NoError(t, json.Unmarshal(vmTraceJSON, &vmTrace))
+		mockVM.EXPECT().Execute(block.Transactions, []core.Class{declaredClass.Class}, paidL1Fees, &vm.BlockInfo{Header: header},
+			gomock.Any(), &network, false, false, false, false).Return(nil, []vm.TransactionTrace{vmTrace, vmTrace}, nil)
+
+		result, err := handler.TraceBlockTransactions(context.Background(), rpc.BlockID{Hash: blockHash})
+		require.Nil(t, err)
+		assert.Equal(t, &vm.TransactionTrace{
+			ValidateInvocation:    &vm.FunctionInvocation{},
+			ExecuteInvocation:     &vm.ExecuteInvocation{},
+			FeeTransferInvocation: &vm.FunctionInvocation{},
+			StateDiff: &vm.StateDiff{
+				StorageDiffs:              []vm.StorageDiff{},
+				Nonces:                    []vm.Nonce{},
+				DeployedContracts:         []vm.DeployedContract{},
+				DeprecatedDeclaredClasses: []*felt.Felt{},
+				DeclaredClasses:           []vm.DeclaredClass{},
+				ReplacedClasses:           []vm.ReplacedClass{},
+			},
+		}, result[0].TraceRoot)
+		assert.Equal(t, l1Tx.TransactionHash, result[0].TransactionHash)
+	})
+	t.Run("regular block", func(t *testing.T) {
+		blockHash := utils.HexToFelt(t, "0x37b244ea7dc6b3f9735fba02d183ef0d6807a572dd91a63cc1b14b923c1ac0")
+		tx := &core.DeclareTransaction{
+			TransactionHash: utils.HexToFelt(t, "0x000000001"),
+			ClassHash:       utils.HexToFelt(t, "0x000000000"),
+		}
+
+		header := &core.Header{
+			Hash:             blockHash,
+			ParentHash:       utils.HexToFelt(t, "0x0"),
+			Number:           0,
+			SequencerAddress: utils.HexToFelt(t, "0X111"),
+			GasPrice:         utils.HexToFelt(t, "0x777"),
+			ProtocolVersion:  "99.12.3",
+		}
+		block := &core.Block{
+			Header:       header,
+			Transactions: []core.Transaction{tx},
+		}
+		declaredClass := &core.DeclaredClass{
+			At:    3002,
+			Class: &core.Cairo1Class{},
+		}
+
+		mockReader.EXPECT().BlockByHash(blockHash).Return(block, nil)
+
+		mockReader.EXPECT().StateAtBlockHash(header.ParentHash).Return(nil, nopCloser, nil)
+		headState := mocks.NewMockStateHistoryReader(mockCtrl)
+		headState.EXPECT().Class(tx.ClassHash).Return(declaredClass, nil)
+		mockReader.EXPECT().HeadState().Return(headState, nopCloser, nil)
+
+		vmTraceJSON := json.RawMessage(`{
+			"validate_invocation":{"entry_point_selector":"0x36fcbf06cd96843058359e1a75928beacfac10727dab22a3972f0af8aa92895","calldata":["0x25ec026985a3bf9d0cc1fe17326b245dfdc3ff89b8fde106542a3ea56c5a918","0x322258135d04971e96b747a5551061aa046ad5d8be11a35c67029d96b23f98","0x33434ad846cdd5f23eb73ff09fe6fddd568284a0fb7d1be20ee482f044dabe2","0x79dc0da7c54b95f10aa182ad0a46400db63156920adb65eca2654c0945a463","0x2","0x322258135d04971e96b747a5551061aa046ad5d8be11a35c67029d96b23f98","0x0"],"caller_address":"0x0","class_hash":"0x25ec026985a3bf9d0cc1fe17326b245dfdc3ff89b8fde106542a3ea56c5a918","entry_point_type":"EXTERNAL","call_type":"CALL","result":[],"calls":[{"entry_point_selector":"0x36fcbf06cd96843058359e1a75928beacfac10727dab22a3972f0af8aa92895","calldata":["0x25ec026985a3bf9d0cc1fe17326b245dfdc3ff89b8fde106542a3ea56c5a918","0x322258135d04971e96b747a5551061aa046ad5d8be11a35c67029d96b23f98","0x33434ad846cdd5f23eb73ff09fe6fddd568284a0fb7d1be20ee482f044dabe2","0x79dc0da7c54b95f10aa182ad0a46400db63156920adb65eca2654c0945a463","0x2","0x322258135d04971e96b747a5551061aa046ad5d8be11a35c67029d96b23f98","0x0"],"caller_address":"0x0","class_hash":"0x33434ad846cdd5f23eb73ff09fe6fddd568284a0fb7d1be20ee482f044dabe2","entry_point_type":"EXTERNAL","call_type":"DELEGATE","result":[],"calls":[],"events":[],"messages":[]}],"events":[],"messages":[]},
+			"execute_invocation":{"entry_point_selector":"0x28ffe4ff0f226a9107253e17a904099aa4f63a02a5621de0576e5aa71bc5194","calldata":["0x33434ad846cdd5f23eb73ff09fe6fddd568284a0fb7d1be20ee482f044dabe2","0x79dc0da7c54b95f10aa182ad0a46400db63156920adb65eca2654c0945a463","0x2","0x322258135d04971e96b747a5551061aa046ad5d8be11a35c67029d96b23f98","0x0"],"caller_address":"0x0","class_hash":"0x25ec026985a3bf9d0cc1fe17326b245dfdc3ff89b8fde106542a3ea56c5a918","entry_point_type":"CONSTRUCTOR","call_type":"CALL","result":[],"calls":[{"entry_point_selector":"0x79dc0da7c54b95f10aa182ad0a46400db63156920adb65eca2654c0945a463","calldata":["0x322258135d04971e96b747a5551061aa046ad5d8be11a35c67029d96b23f98","0x0"],"caller_address":"0x0","class_hash":"0x33434ad846cdd5f23eb73ff09fe6fddd568284a0fb7d1be20ee482f044dabe2","entry_point_type":"EXTERNAL","call_type":"DELEGATE","result":[],"calls":[],"events":[{"keys":["0x10c19bef19acd19b2c9f4caa40fd47c9fbe1d9f91324d44dcd36be2dae96784"],"data":["0xdac9bcffb3d967f19a7fe21002c98c984d5a9458a88e6fc5d1c478a97ed412","0x322258135d04971e96b747a5551061aa046ad5d8be11a35c67029d96b23f98","0x0"]}],"messages":[]}],"events":[],"messages":[]},
+			"fee_transfer_invocation":{"entry_point_selector":"0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e","calldata":["0x5dcd266a80b8a5f29f04d779c6b166b80150c24f2180a75e82427242dab20a9","0x15be","0x0"],"caller_address":"0xdac9bcffb3d967f19a7fe21002c98c984d5a9458a88e6fc5d1c478a97ed412","class_hash":"0xd0e183745e9dae3e4e78a8ffedcce0903fc4900beace4e0abf192d4c202da3","entry_point_type":"EXTERNAL","call_type":"CALL","result":["0x1"],"calls":[{"entry_point_selector":"0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e","calldata":["0x5dcd266a80b8a5f29f04d779c6b166b80150c24f2180a75e82427242dab20a9","0x15be","0x0"],"caller_address":"0xdac9bcffb3d967f19a7fe21002c98c984d5a9458a88e6fc5d1c478a97ed412","class_hash":"0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0","entry_point_type":"EXTERNAL","call_type":"DELEGATE","result":["0x1"],"calls":[],"events":[{"keys":["0x99cd8bde557814842a3121e8ddfd433a539b8c9f14bf31ebf108d12e6196e9"],"data":["0xdac9bcffb3d967f19a7fe21002c98c984d5a9458a88e6fc5d1c478a97ed412","0x5dcd266a80b8a5f29f04d779c6b166b80150c24f2180a75e82427242dab20a9","0x15be","0x0"]}],"messages":[]}],"events":[],"messages":[]},
+			"state_diff": {
+				"storage_diffs": [],
+				"nonces": [],
+				"deployed_contracts": [],
+				"deprecated_declared_classes": [],
+				"declared_classes": [],
+				"replaced_classes": []
+			}
+		}`)
+		vmTrace := vm.TransactionTrace{}
+		require.NoError(t, json.Unmarshal(vmTraceJSON, &vmTrace))
+		mockVM.EXPECT().Execute([]core.Transaction{tx}, []core.Class{declaredClass.Class}, []*felt.Felt{}, &vm.BlockInfo{Header: header},
+			gomock.Any(), &network, false, false, false, false).Return(nil, []vm.TransactionTrace{vmTrace}, nil)
+
+		expectedResult := []rpc.TracedBlockTransaction{
+			{
+				TransactionHash: tx.Hash(),
+				TraceRoot:       &vmTrace,
+			},
+		}
+		result, err := handler.TraceBlockTransactions(context.Background(), rpc.BlockID{Hash: blockHash})
+		require.Nil(t, err)
+		assert.Equal(t, expectedResult, result)
+	})
+}
+
+func TestRpcBlockAdaptation(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+
+	mockReader := mocks.NewMockReader(mockCtrl)
+	handler := rpc.New(mockReader, nil, nil, "", nil)
+
+	client := feeder.NewTestClient(t, &utils.Goerli)
+	gw := adaptfeeder.New(client)
+	latestBlockNumber := uint64(485004)
+
+	t.Run("default sequencer address", func(t *testing.T) {
+		latestBlock, err := gw.BlockByNumber(context.Background(), latestBlockNumber)
+		require.NoError(t, err)
+		latestBlock.Header.SequencerAddress = nil
+		mockReader.EXPECT().Head().Return(latestBlock, nil).Times(2)
+		mockReader.EXPECT().L1Head().Return(nil, db.ErrKeyNotFound).Times(2)
+
+		block, rpcErr := handler.BlockWithTxs(rpc.BlockID{Latest: true})
+		require.NoError(t, err, rpcErr)
+		require.Equal(t, &felt.Zero, block.BlockHeader.SequencerAddress)
+
+		blockWithTxHashes, rpcErr := handler.BlockWithTxHashes(rpc.BlockID{Latest: true})
+		require.NoError(t, err, rpcErr)
+		require.Equal(t, &felt.Zero, blockWithTxHashes.BlockHeader.SequencerAddress)
+	})
+}
+
+type fakeConn struct {
+	w io.Writer
+}
+
+func (fc *fakeConn) Write(p []byte) (int, error) {
+	return fc.w.Write(p)
+}
+
+func (fc *fakeConn) Equal(other jsonrpc.Conn) bool {
+	fc2, ok := other.(*fakeConn)
+	if !ok {
+		return false
+	}
+	return fc.w == fc2.w
+}
+
+func TestSubscribeNewHeadsAndUnsubscribe(t *testing.T) {
+	t.Skip()
+	t.Parallel()
+	log := utils.NewNopZapLogger()
+	network := utils.Mainnet
+	client := feeder.NewTestClient(t, &network)
+	gw := adaptfeeder.New(client)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	chain := blockchain.New(pebble.NewMemTest(t), &network)
+	syncer := sync.New(chain, gw, log, 0, false)
+	handler := rpc.New(chain, syncer, nil, "", log)
+
+	go func() {
+		require.NoError(t, handler.Run(ctx))
+	}()
+	// Technically, there's a race between goroutine above and the SubscribeNewHeads call down below.
+	// Sleep for a moment just in case.
+	time.Sleep(50 * time.Millisecond)
+
+	serverConn, clientConn := net.Pipe()
+	t.Cleanup(func() {
+		require.NoError(t, serverConn.Close())
+		require.NoError(t, clientConn.Close())
+	})
+
+	// Subscribe without setting the connection on the context.
+	id, rpcErr := handler.SubscribeNewHeads(ctx)
+	require.Zero(t, id)
+	require.Equal(t, jsonrpc.MethodNotFound, rpcErr.Code)
+
+	// Sync blocks and then revert head.
+	// This is a super hacky way to deterministically receive a single block on the subscription.
+	// It would be nicer if we could tell the synchronizer to exit after a certain block height, but, alas, we can't do that.
+	syncCtx, syncCancel := context.WithTimeout(context.Background(), time.Second)
+	require.NoError(t, syncer.Run(syncCtx))
+	syncCancel()
+	// This is technically an unsafe thing to do. We're modifying the synchronizer's blockchain while it is owned by the synchronizer.
+	// But it works.
+	require.NoError(t, chain.RevertHead())
+
+	// Subscribe.
+	subCtx := context.WithValue(ctx, jsonrpc.ConnKey{}, &fakeConn{w: serverConn})
+	id, rpcErr = handler.SubscribeNewHeads(subCtx)
+	require.Nil(t, rpcErr)
+
+	// Sync the block we reverted above.
+	syncCtx, syncCancel = context.WithTimeout(context.Background(), 250*time.Millisecond)
+	require.NoError(t, syncer.Run(syncCtx))
+	syncCancel()
+
+	// Receive a block header.
+	want := `{"jsonrpc":"2.0","method":"juno_subscribeNewHeads","params":{"result":{"block_hash":"0x4e1f77f39545afe866ac151ac908bd1a347a2a8a7d58bef1276db4f06fdf2f6","parent_hash":"0x2a70fb03fe363a2d6be843343a1d81ce6abeda1e9bd5cc6ad8fa9f45e30fdeb","block_number":2,"new_root":"0x3ceee867d50b5926bb88c0ec7e0b9c20ae6b537e74aac44b8fcf6bb6da138d9","timestamp":1637084470,"sequencer_address":"0x0","l1_gas_price":{"price_in_fri":"0x0","price_in_wei":"0x0"},"starknet_version":""},"subscription":%d}}`
+	want = fmt.Sprintf(want, id)
+	got := make([]byte, len(want))
+	_, err := clientConn.Read(got)
+	require.NoError(t, err)
+	require.Equal(t, want, string(got))
+
+	// Unsubscribe without setting the connection on the context.
+	ok, rpcErr := handler.Unsubscribe(ctx, id)
+	require.Equal(t, jsonrpc.MethodNotFound, rpcErr.Code)
+	require.False(t, ok)
+
+	// Unsubscribe on correct connection with the incorrect id.
+	ok, rpcErr = handler.Unsubscribe(subCtx, id+1)
+	require.Equal(t, rpc.ErrSubscriptionNotFound, rpcErr)
+	require.False(t, ok)
+
+	// Unsubscribe on incorrect connection with the correct id.
+	subCtx = context.WithValue(context.Background(), jsonrpc.ConnKey{}, &fakeConn{})
+	ok, rpcErr = handler.Unsubscribe(subCtx, id)
+	require.Equal(t, rpc.ErrSubscriptionNotFound, rpcErr)
+	require.False(t, ok)
+
+	// Unsubscribe on correct connection with the correct id.
+	subCtx = context.WithValue(context.Background(), jsonrpc.ConnKey{}, &fakeConn{w: serverConn})
+	ok, rpcErr = handler.Unsubscribe(subCtx, id)
+	require.Nil(t, rpcErr)
+	require.True(t, ok)
+}
+
+func TestMultipleSubscribeNewHeadsAndUnsubscribe(t *testing.T) {
+	t.Skip()
+	t.Parallel()
+	log := utils.NewNopZapLogger()
+	network := utils.Mainnet
+	feederClient := feeder.NewTestClient(t, &network)
+	gw := adaptfeeder.New(feederClient)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	chain := blockchain.New(pebble.NewMemTest(t), &network)
+	syncer := sync.New(chain, gw, log, 0, false)
+	handler := rpc.New(chain, syncer, nil, "", log)
+	go func() {
+		require.NoError(t, handler.Run(ctx))
+	}()
+	// Technically, there's a race between goroutine above and the SubscribeNewHeads call down below.
+	// Sleep for a moment just in case.
+	time.Sleep(50 * time.Millisecond)
+
+	// Sync blocks and then revert head.
+	// This is a super hacky way to deterministically receive a single block on the subscription.
+	// It would be nicer if we could tell the synchronizer to exit after a certain block height, but, alas, we can't do that.
+	syncCtx, syncCancel := context.WithTimeout(context.Background(), time.Second)
+	require.NoError(t, syncer.Run(syncCtx))
+	syncCancel()
+	// This is technically an unsafe thing to do. We're modifying the synchronizer's blockchain while it is owned by the synchronizer.
+	// But it works.
+	require.NoError(t, chain.RevertHead())
+
+	server := jsonrpc.NewServer(1, log)
+	require.NoError(t, server.RegisterMethods(jsonrpc.Method{
+		Name:    "juno_subscribeNewHeads",
+		Handler: handler.SubscribeNewHeads,
+	}, jsonrpc.Method{
+		Name:    "juno_unsubscribe",
+		Params:  []jsonrpc.Parameter{{Name: "id"}},
+		Handler: handler.Unsubscribe,
+	}))
+	ws := jsonrpc.NewWebsocket(server, log)
+	httpSrv := httptest.NewServer(ws)
+	conn1, _, err := websocket.Dial(ctx, httpSrv.URL, nil)
+	require.NoError(t, err)
+	conn2, _, err := websocket.Dial(ctx, httpSrv.URL, nil)
+	require.NoError(t, err)
+
+	subscribeMsg := []byte(`{"jsonrpc":"2.0","id":1,"method":"juno_subscribeNewHeads"}`)
+
+	firstID := uint64(1)
+	secondID := uint64(2)
+	handler.WithIDGen(func() uint64 { return firstID })
+	require.NoError(t, conn1.Write(ctx, websocket.MessageText, subscribeMsg))
+
+	want := `{"jsonrpc":"2.0","result":%d,"id":1}`
+	firstWant := fmt.Sprintf(want, firstID)
+	_, firstGot, err := conn1.Read(ctx)
+	require.NoError(t, err)
+	require.Equal(t, firstWant, string(firstGot))
+
+	handler.WithIDGen(func() uint64 { return secondID })
+	require.NoError(t, conn2.Write(ctx, websocket.MessageText, subscribeMsg))
+	secondWant := fmt.Sprintf(want, secondID)
+	_, secondGot, err := conn2.Read(ctx)
+	require.NoError(t, err)
+	require.Equal(t, secondWant, string(secondGot))
+
+	// Now we're subscribed. Sync the block we reverted above.
+	syncCtx, syncCancel = context.WithTimeout(context.Background(), 250*time.Millisecond)
+	require.NoError(t, syncer.Run(syncCtx))
+	syncCancel()
+
+	// Receive a block header.
+	want = `{"jsonrpc":"2.0","method":"juno_subscribeNewHeads","params":{"result":{"block_hash":"0x4e1f77f39545afe866ac151ac908bd1a347a2a8a7d58bef1276db4f06fdf2f6","parent_hash":"0x2a70fb03fe363a2d6be843343a1d81ce6abeda1e9bd5cc6ad8fa9f45e30fdeb","block_number":2,"new_root":"0x3ceee867d50b5926bb88c0ec7e0b9c20ae6b537e74aac44b8fcf6bb6da138d9","timestamp":1637084470,"sequencer_address":"0x0","l1_gas_price":{"price_in_fri":"0x0","price_in_wei":"0x0"},"starknet_version":""},"subscription":%d}}`
+	firstWant = fmt.Sprintf(want, firstID)
+	_, firstGot, err = conn1.Read(ctx)
+	require.NoError(t, err)
+	require.Equal(t, firstWant, string(firstGot))
+	secondWant = fmt.Sprintf(want, secondID)
+	_, secondGot, err = conn2.Read(ctx)
+	require.NoError(t, err)
+	require.Equal(t, secondWant, string(secondGot))
+
+	// Unsubscribe
+	unsubMsg := `{"jsonrpc":"2.0","id":1,"method":"juno_unsubscribe","params":[%d]}`
+	require.NoError(t, conn1.Write(ctx, websocket.MessageBinary, []byte(fmt.Sprintf(unsubMsg, firstID))))
+	require.NoError(t, conn2.Write(ctx, websocket.MessageBinary, []byte(fmt.Sprintf(unsubMsg, secondID))))
+}
+
+func TestTraceFallback(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+	network := utils.Integration
+	client := feeder.NewTestClient(t, &network)
+	mockReader := mocks.NewMockReader(mockCtrl)
+	gateway := adaptfeeder.New(client)
+
+	mockReader.EXPECT().BlockByNumber(gomock.Any()).DoAndReturn(func(number uint64) (block *core.Block, err error) {
+		return gateway.BlockByNumber(context.Background(), number)
+	}).AnyTimes()
+	mockReader.EXPECT().L1Head().Return(nil, db.ErrKeyNotFound).AnyTimes()
+
+	tests := map[string]struct {
+		hash        string
+		blockNumber uint64
+		want        string
+	}{
+		"old block": {
+			hash:        "0x3ae41b0f023e53151b0c8ab8b9caafb7005d5f41c9ab260276d5bdc49726279",
+			blockNumber: 0,
+			want:        `[{"trace_root":{"type":"DEPLOY","constructor_invocation":{"contract_address":"0x7b196a359045d4d0c10f73bdf244a9e1205a615dbb754b8df40173364288534","calldata":["0x187d50a5cf3ebd6d4d6fa8e29e4cad0a237759c6416304a25c4ea792ed4bba4","0x42f5af30d6693674296ad87301935d0c159036c3b24af4042ff0270913bf6c6"],"caller_address":"0x0","result":[],"calls":[],"events":[],"messages":[],"execution_resources":{"steps":29}}},"transaction_hash":"0x3fa1bff0c86f34b2eb32c26d12208b6bdb4a5f6a434ac1d4f0e2d1db71bd711"},{"trace_root":{"type":"DEPLOY","constructor_invocation":{"contract_address":"0x64ed79a8ebe97485d3357bbfdf5f6bea0d9db3b5f1feb6e80d564a179122dc6","calldata":["0x5cedec15acd969b0fba39fec9e7d9bd4d0b33f100969ad3a4543039a6f696d4","0xce9801d27b02543f4d88b60aa456860f94ee9f612fc56464abfbdeedc1ab72"],"caller_address":"0x0","result":[],"calls":[],"events":[],"messages":[],"execution_resources":{"steps":29}}},"transaction_hash":"0x154c02cc3165cceadaa32e7238a67061b3a1eac414138c4ebe1408f37fd93eb"},{"trace_root":{"type":"INVOKE","execute_invocation":{"contract_address":"0x64ed79a8ebe97485d3357bbfdf5f6bea0d9db3b5f1feb6e80d564a179122dc6","calldata":["0x17d9c35a8b9a0d4512fa05eafec01c2758a7a5b7ec7b47408a24a4b33124d9b","0x2","0x7f800b5bf79637f8f83f47a8fc4d368b43695c781b22a899f11b5f2faba874a","0x3a7a40d383612b0ad167aec8d90fb07e576e017d07948f63ac318b52511ae93"],"caller_address":"0x0","result":[],"calls":[],"events":[],"messages":[],"execution_resources":{"steps":165,"memory_holes":22,"pedersen_builtin_applications":2,"range_check_builtin_applications":7}}},"transaction_hash":"0x7893675c16da857b7c4229cda449e08a4fe13b07ca817e79d1db02e8a046047"},{"trace_root":{"type":"INVOKE","execute_invocation":{"contract_address":"0x64ed79a8ebe97485d3357bbfdf5f6bea0d9db3b5f1feb6e80d564a179122dc6","calldata":["0x17d9c35a8b9a0d4512fa05eafec01c2758a7a5b7ec7b47408a24a4b33124d9b","0x2","0x7f800b5bf79637f8f83f47a8fc4d368b43695c781b22a899f11b5f2faba874a","0xf140b304e9266c72f1054116dd06d9c1c8e981db7bf34e3c6da99640e9a7c8"],"caller_address":"0x0","result":[],"calls":[],"events":[],"messages":[],"execution_resources":{"steps":165,"memory_holes":22,"pedersen_builtin_applications":2,"range_check_builtin_applications":7}}},"transaction_hash":"0x4a277d67e3f42c4a343854081d1e2e9e425f1323255e4486d2badb37a1d8630"}]`,
+		},
+		"newer block": {
+			hash:        "0xe3828bd9154ab385e2cbb95b3b650365fb3c6a4321660d98ce8b0a9194f9a3",
+			blockNumber: 300000,
+			want:        `[{"trace_root":{"type":"INVOKE","validate_invocation":{"contract_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","entry_point_selector":"0x162da33a4585851fe8d3af3c2a9c60b557814e221e0d4f30ff0b2189d9c7775","calldata":["0x1","0x332299dc083f3778122e5b7762bc9d399da18fefe93769aee67bb49f51c8d2","0x2d7cf5d5a324a320f9f37804b1615a533fde487400b41af80f13f7ac5581325","0x0","0x4","0x4","0xaf35ee8ed700ff132c5d1d298a73becda25ccdf9","0x2","0x6cd852fe1b2bbd8587bb0aaeb09813436c57c8ce21e75651e317273a1f22228","0x58feb991988e53fffcba71f6df23c803fb062f1b3bab126d2c9ce574255b36e"],"caller_address":"0x0","class_hash":"0x646a72e2aab2fca75d713fbe4a58f2d12cbd64105621b89dc9ce7045b5bf02b","entry_point_type":"EXTERNAL","call_type":"CALL","result":[],"calls":[],"events":[],"messages":[],"execution_resources":{"steps":89,"range_check_builtin_applications":2,"ecdsa_builtin_applications":1}},"execute_invocation":{"contract_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","entry_point_selector":"0x15d40a3d6ca2ac30f4031e42be28da9b056fef9bb7357ac5e85627ee876e5ad","calldata":["0x1","0x332299dc083f3778122e5b7762bc9d399da18fefe93769aee67bb49f51c8d2","0x2d7cf5d5a324a320f9f37804b1615a533fde487400b41af80f13f7ac5581325","0x0","0x4","0x4","0xaf35ee8ed700ff132c5d1d298a73becda25ccdf9","0x2","0x6cd852fe1b2bbd8587bb0aaeb09813436c57c8ce21e75651e317273a1f22228","0x58feb991988e53fffcba71f6df23c803fb062f1b3bab126d2c9ce574255b36e"],"caller_address":"0x0","class_hash":"0x646a72e2aab2fca75d713fbe4a58f2d12cbd64105621b89dc9ce7045b5bf02b","entry_point_type":"EXTERNAL","call_type":"CALL","result":[],"calls":[{"contract_address":"0x332299dc083f3778122e5b7762bc9d399da18fefe93769aee67bb49f51c8d2","entry_point_selector":"0x2d7cf5d5a324a320f9f37804b1615a533fde487400b41af80f13f7ac5581325","calldata":["0xaf35ee8ed700ff132c5d1d298a73becda25ccdf9","0x2","0x6cd852fe1b2bbd8587bb0aaeb09813436c57c8ce21e75651e317273a1f22228","0x58feb991988e53fffcba71f6df23c803fb062f1b3bab126d2c9ce574255b36e"],"caller_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","class_hash":"0x165e7db96ab97a63c621229617a6d49633737238673477a54720e4c952f2c7e","entry_point_type":"EXTERNAL","call_type":"CALL","result":[],"calls":[],"events":[],"messages":[{"order":0,"to_address":"0xAf35eE8eD700ff132C5d1d298A73BECdA25ccDF9","payload":["0x6cd852fe1b2bbd8587bb0aaeb09813436c57c8ce21e75651e317273a1f22228","0x58feb991988e53fffcba71f6df23c803fb062f1b3bab126d2c9ce574255b36e"]}],"execution_resources":{"steps":233,"memory_holes":1,"range_check_builtin_applications":5}}],"events":[],"messages":[],"execution_resources":{"steps":374,"memory_holes":4,"range_check_builtin_applications":7}},"fee_transfer_invocation":{"contract_address":"0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7","entry_point_selector":"0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e","calldata":["0x1176a1bd84444c89232ec27754698e5d2e7e1a7f1539f12027f28b23ec9f3d8","0x127089df3a1984","0x0"],"caller_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","class_hash":"0xd0e183745e9dae3e4e78a8ffedcce0903fc4900beace4e0abf192d4c202da3","entry_point_type":"EXTERNAL","call_type":"CALL","result":["0x1"],"calls":[{"contract_address":"0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7","entry_point_selector":"0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e","calldata":["0x1176a1bd84444c89232ec27754698e5d2e7e1a7f1539f12027f28b23ec9f3d8","0x127089df3a1984","0x0"],"caller_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","class_hash":"0x28d7d394810ad8c52741ad8f7564717fd02c10ced68657a81d0b6710ce22079","entry_point_type":"EXTERNAL","call_type":"DELEGATE","result":["0x1"],"calls":[],"events":[],"messages":[],"execution_resources":{"steps":488,"memory_holes":40,"pedersen_builtin_applications":4,"range_check_builtin_applications":21}}],"events":[],"messages":[],"execution_resources":{"steps":548,"memory_holes":40,"pedersen_builtin_applications":4,"range_check_builtin_applications":21}}},"transaction_hash":"0x2a648ab1aa6847eb38507fc842e050f256562bf87b26083c332f3f21318c2c3"},{"trace_root":{"type":"INVOKE","validate_invocation":{"contract_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","entry_point_selector":"0x162da33a4585851fe8d3af3c2a9c60b557814e221e0d4f30ff0b2189d9c7775","calldata":["0x1","0x5f9211b05c9609d54a8bf5f9cfa4e2cd5a3cab3b5d79682c585575495a15dd1","0x317eb442b72a9fae758d4fb26830ed0d9f31c8e7da4dbff4e8c59ea6a158e7f","0x0","0x4","0x4","0x447379c077035ef4f442411d0407ce9aa66c558f0060137f6455f4f230eabeb","0x2","0x6811b7755a7dd0ec1fb6f51a883e3f255368e2dfd497b5f6480c00cf9cd5a2e","0x23b9e26720dd7aaf98c7cea56499f48f75dc1d4123f7e2d6c23bfc4d5f4a336"],"caller_address":"0x0","class_hash":"0x646a72e2aab2fca75d713fbe4a58f2d12cbd64105621b89dc9ce7045b5bf02b","entry_point_type":"EXTERNAL","call_type":"CALL","result":[],"calls":[],"events":[],"messages":[],"execution_resources":{"steps":89,"range_check_builtin_applications":2,"ecdsa_builtin_applications":1}},"execute_invocation":{"contract_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","entry_point_selector":"0x15d40a3d6ca2ac30f4031e42be28da9b056fef9bb7357ac5e85627ee876e5ad","calldata":["0x1","0x5f9211b05c9609d54a8bf5f9cfa4e2cd5a3cab3b5d79682c585575495a15dd1","0x317eb442b72a9fae758d4fb26830ed0d9f31c8e7da4dbff4e8c59ea6a158e7f","0x0","0x4","0x4","0x447379c077035ef4f442411d0407ce9aa66c558f0060137f6455f4f230eabeb","0x2","0x6811b7755a7dd0ec1fb6f51a883e3f255368e2dfd497b5f6480c00cf9cd5a2e","0x23b9e26720dd7aaf98c7cea56499f48f75dc1d4123f7e2d6c23bfc4d5f4a336"],"caller_address":"0x0","class_hash":"0x646a72e2aab2fca75d713fbe4a58f2d12cbd64105621b89dc9ce7045b5bf02b","entry_point_type":"EXTERNAL","call_type":"CALL","result":[],"calls":[{"contract_address":"0x5f9211b05c9609d54a8bf5f9cfa4e2cd5a3cab3b5d79682c585575495a15dd1","entry_point_selector":"0x317eb442b72a9fae758d4fb26830ed0d9f31c8e7da4dbff4e8c59ea6a158e7f","calldata":["0x447379c077035ef4f442411d0407ce9aa66c558f0060137f6455f4f230eabeb","0x2","0x6811b7755a7dd0ec1fb6f51a883e3f255368e2dfd497b5f6480c00cf9cd5a2e","0x23b9e26720dd7aaf98c7cea56499f48f75dc1d4123f7e2d6c23bfc4d5f4a336"],"caller_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","class_hash":"0x13abfd2f333f9c69f690f1569140cdae25f6f66e3f371c9cbb998b65f664a85","entry_point_type":"EXTERNAL","call_type":"CALL","result":[],"calls":[],"events":[],"messages":[],"execution_resources":{"steps":166,"memory_holes":22,"pedersen_builtin_applications":2,"range_check_builtin_applications":7}}],"events":[],"messages":[],"execution_resources":{"steps":307,"memory_holes":25,"pedersen_builtin_applications":2,"range_check_builtin_applications":9}},"fee_transfer_invocation":{"contract_address":"0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7","entry_point_selector":"0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e","calldata":["0x1176a1bd84444c89232ec27754698e5d2e7e1a7f1539f12027f28b23ec9f3d8","0x3b2d25cd7bccc","0x0"],"caller_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","class_hash":"0xd0e183745e9dae3e4e78a8ffedcce0903fc4900beace4e0abf192d4c202da3","entry_point_type":"EXTERNAL","call_type":"CALL","result":["0x1"],"calls":[{"contract_address":"0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7","entry_point_selector":"0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e","calldata":["0x1176a1bd84444c89232ec27754698e5d2e7e1a7f1539f12027f28b23ec9f3d8","0x3b2d25cd7bccc","0x0"],"caller_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","class_hash":"0x28d7d394810ad8c52741ad8f7564717fd02c10ced68657a81d0b6710ce22079","entry_point_type":"EXTERNAL","call_type":"DELEGATE","result":["0x1"],"calls":[],"events":[],"messages":[],"execution_resources":{"steps":488,"memory_holes":40,"pedersen_builtin_applications":4,"range_check_builtin_applications":21}}],"events":[],"messages":[],"execution_resources":{"steps":548,"memory_holes":40,"pedersen_builtin_applications":4,"range_check_builtin_applications":21}}},"transaction_hash":"0xbc984e8e1fe594dd518a3a51db4f338437a5d2fbdda772d4426b532a67ffff"}]`,
+		},
+	}
+
+	for description, test := range tests {
+		t.Run(description, func(t *testing.T) {
+			mockReader.EXPECT().BlockByHash(utils.HexToFelt(t, test.hash)).DoAndReturn(func(_ *felt.Felt) (block *core.Block, err error) {
+				return mockReader.BlockByNumber(test.blockNumber)
+			}).Times(2)
+			handler := rpc.New(mockReader, nil, nil, "", nil)
+			_, jErr := handler.TraceBlockTransactions(context.Background(), rpc.BlockID{Number: test.blockNumber})
+			require.Equal(t, rpc.ErrInternal.Code, jErr.Code)
+
+			handler = handler.WithFeeder(client)
+			trace, jErr := handler.TraceBlockTransactions(context.Background(), rpc.BlockID{Number: test.blockNumber})
+			require.Nil(t, jErr)
+			jsonStr, err := json.Marshal(trace)
+			require.NoError(t, err)
+			assert.JSONEq(t, test.want, string(jsonStr))
+		})
+	}
+}
+
+func TestThrottledVMError(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&utils.Mainnet).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+
+	throttledVM := node.NewThrottledVM(mockVM, 0, 0)
+	handler := rpc.New(mockReader, nil, throttledVM, "", nil)
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+
+	assertThrottledErr := func(t *testing.T, rpcErr *jsonrpc.Error) {
+		t.Helper()
+		require.NotNil(t, rpcErr)
+		data, ok := rpcErr.Data.(rpc.ThrottledVMErrData)
+		require.True(t, ok)
+		assert.Equal(t, "VM throughput limit reached", data.Message)
+		assert.Positive(t, data.RetryAfterMs)
+	}
+	t.Run("call", func(t *testing.T) {
+		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+		mockReader.EXPECT().HeadsHeader().Return(new(core.Header), nil)
+		mockState.EXPECT().ContractClassHash(&felt.Zero).Return(new(felt.Felt), nil)
+		mockState.EXPECT().Class(&felt.Zero).Return(callableClass, nil)
+		_, rpcErr := handler.Call(rpc.FunctionCall{}, rpc.BlockID{Latest: true})
+		assertThrottledErr(t, rpcErr)
+	})
+
+	t.Run("simulate", func(t *testing.T) {
+		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+		mockReader.EXPECT().HeadsHeader().Return(&core.Header{}, nil)
+		_, rpcErr := handler.SimulateTransactions(rpc.BlockID{Latest: true}, []rpc.BroadcastedTransaction{}, []rpc.SimulationFlag{rpc.SkipFeeChargeFlag})
+		assertThrottledErr(t, rpcErr)
+	})
+
+	t.Run("trace", func(t *testing.T) {
 		blockHash := utils.HexToFelt(t, "0x0001")
 		header := &core.Header{
 			// hash is not set because it's pending block
@@ -3178,511 +4991,1348 @@ func TestTraceBlockTransactions(t *testing.T) {
 		l1Tx := &core.L1HandlerTransaction{
 			TransactionHash: utils.HexToFelt(t, "0x000000C"),
 		}
-		declaredClass := &core.DeclaredClass{
-			At:    3002,
-			Class: &core.Cairo1Class{},
+		declaredClass := &core.DeclaredClass{
+			At:    3002,
+			Class: &core.Cairo1Class{},
+		}
+		declareTx := &core.DeclareTransaction{
+			TransactionHash: utils.HexToFelt(t, "0x000000001"),
+			ClassHash:       utils.HexToFelt(t, "0x00000BC00"),
+		}
+		block := &core.Block{
+			Header:       header,
+			Transactions: []core.Transaction{l1Tx, declareTx},
+		}
+
+		mockReader.EXPECT().BlockByHash(blockHash).Return(block, nil)
+		state := mocks.NewMockStateHistoryReader(mockCtrl)
+		mockReader.EXPECT().StateAtBlockHash(header.ParentHash).Return(state, nopCloser, nil)
+		headState := mocks.NewMockStateHistoryReader(mockCtrl)
+		headState.EXPECT().Class(declareTx.ClassHash).Return(declaredClass, nil)
+		mockReader.EXPECT().PendingState().Return(headState, nopCloser, nil)
+		_, rpcErr := handler.TraceBlockTransactions(context.Background(), rpc.BlockID{Hash: blockHash})
+		assertThrottledErr(t, rpcErr)
+	})
+}
+
+func TestThrottledVMErrorRetryAfter(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&utils.Mainnet).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil).AnyTimes()
+	mockReader.EXPECT().HeadsHeader().Return(new(core.Header), nil).AnyTimes()
+	mockState.EXPECT().ContractClassHash(&felt.Zero).Return(new(felt.Felt), nil).AnyTimes()
+	mockState.EXPECT().Class(&felt.Zero).Return(callableClass, nil).AnyTimes()
+
+	running := make(chan struct{})
+	release := make(chan struct{})
+	mockVM.EXPECT().Call(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ *vm.CallInfo, _ *vm.BlockInfo, _ core.StateReader, _ *utils.Network, _ uint64, _ bool,
+		) (vm.CallResult, error) {
+			running <- struct{}{}
+			<-release
+			return vm.CallResult{}, nil
+		}).Times(2)
+
+	throttledVM := node.NewThrottledVM(mockVM, 1, 1)
+	handler := rpc.New(mockReader, nil, throttledVM, "", nil)
+
+	var wg conc.WaitGroup
+	call := func() {
+		_, rpcErr := handler.Call(rpc.FunctionCall{}, rpc.BlockID{Latest: true})
+		assert.Nil(t, rpcErr)
+	}
+	// one call occupies the VM and a second one waits in the queue
+	wg.Go(call)
+	<-running
+	wg.Go(call)
+	require.Eventually(t, func() bool { return throttledVM.QueueLen() == 1 }, time.Second, time.Millisecond)
+
+	_, rpcErr := handler.Call(rpc.FunctionCall{}, rpc.BlockID{Latest: true})
+	require.NotNil(t, rpcErr)
+	assert.Equal(t, rpc.ThrottledVMErrData{
+		Message:      "VM throughput limit reached",
+		RetryAfterMs: 200,
+	}, rpcErr.Data)
+
+	close(release)
+	<-running
+	wg.Wait()
+}
+
+func TestMaxOpenStates(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&utils.Mainnet).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	mockReader.EXPECT().HeadsHeader().Return(new(core.Header), nil).AnyTimes()
+	mockState.EXPECT().ContractClassHash(&felt.Zero).Return(new(felt.Felt), nil).AnyTimes()
+	mockState.EXPECT().Class(&felt.Zero).Return(callableClass, nil).AnyTimes()
+	mockState.EXPECT().ContractStorage(&felt.Zero, &felt.Zero).Return(new(felt.Felt).SetUint64(7), nil).AnyTimes()
+
+	const maxOpenStates = 2
+	handler := rpc.New(mockReader, nil, mockVM, "", nil).WithMaxOpenStates(maxOpenStates)
+
+	t.Run("failed state lookups do not hold a reader", func(t *testing.T) {
+		mockReader.EXPECT().StateAtBlockNumber(uint64(1)).Return(nil, nil, db.ErrKeyNotFound).Times(maxOpenStates + 1)
+		for range maxOpenStates + 1 {
+			_, rpcErr := handler.StorageAt(felt.Zero, felt.Zero, rpc.BlockID{Number: 1})
+			assert.Equal(t, rpc.ErrBlockNotFound, rpcErr)
+		}
+	})
+
+	t.Run("saturated readers", func(t *testing.T) {
+		var openStates atomic.Int32
+		mockReader.EXPECT().HeadState().DoAndReturn(func() (core.StateReader, blockchain.StateCloser, error) {
+			openStates.Add(1)
+			return mockState, func() error {
+				openStates.Add(-1)
+				return nil
+			}, nil
+		}).AnyTimes()
+
+		running := make(chan struct{})
+		release := make(chan struct{})
+		mockVM.EXPECT().Call(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ *vm.CallInfo, _ *vm.BlockInfo, _ core.StateReader, _ *utils.Network, _ uint64, _ bool,
+			) (vm.CallResult, error) {
+				running <- struct{}{}
+				<-release
+				return vm.CallResult{}, nil
+			}).Times(maxOpenStates)
+
+		// every call holds its state reader open until the VM is released
+		var wg conc.WaitGroup
+		for range maxOpenStates {
+			wg.Go(func() {
+				_, rpcErr := handler.Call(rpc.FunctionCall{}, rpc.BlockID{Latest: true})
+				assert.Nil(t, rpcErr)
+			})
+			<-running
 		}
-		declareTx := &core.DeclareTransaction{
-			TransactionHash: utils.HexToFelt(t, "0x000000001"),
-			ClassHash:       utils.HexToFelt(t, "0x00000BC00"),
+
+		_, rpcErr := handler.StorageAt(felt.Zero, felt.Zero, rpc.BlockID{Latest: true})
+		assert.Equal(t, rpc.ErrServiceBusy, rpcErr)
+
+		// block traces open their states through the same limit
+		mockReader.EXPECT().BlockByNumber(uint64(3)).Return(&core.Block{Header: &core.Header{
+			Number:          3,
+			Hash:            new(felt.Felt).SetUint64(3),
+			ParentHash:      new(felt.Felt).SetUint64(2),
+			ProtocolVersion: "0.13.1",
+		}}, nil)
+		_, rpcErr = handler.TraceBlockTransactions(context.Background(), rpc.BlockID{Number: 3})
+		assert.Equal(t, rpc.ErrServiceBusy, rpcErr)
+		assert.Equal(t, int32(maxOpenStates), openStates.Load())
+
+		close(release)
+		wg.Wait()
+		assert.Zero(t, openStates.Load())
+
+		value, rpcErr := handler.StorageAt(felt.Zero, felt.Zero, rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+		assert.Equal(t, new(felt.Felt).SetUint64(7), value)
+	})
+
+	t.Run("block traces need a single reader", func(t *testing.T) {
+		handler := rpc.New(mockReader, nil, mockVM, "", nil).WithMaxOpenStates(1)
+		block := &core.Block{Header: &core.Header{
+			Number:          4,
+			Hash:            new(felt.Felt).SetUint64(4),
+			ParentHash:      new(felt.Felt).SetUint64(3),
+			ProtocolVersion: "0.13.1",
+		}}
+		mockReader.EXPECT().BlockByNumber(uint64(4)).Return(block, nil)
+		mockReader.EXPECT().StateAtBlockHash(block.ParentHash).Return(mockState, nopCloser, nil)
+		mockVM.EXPECT().Execute(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), mockState, gomock.Any(),
+			false, false, false, gomock.Any()).Return(nil, nil, nil, nil)
+
+		traces, rpcErr := handler.TraceBlockTransactions(context.Background(), rpc.BlockID{Number: 4})
+		require.Nil(t, rpcErr)
+		assert.Empty(t, traces)
+	})
+}
+
+func TestSpecVersion(t *testing.T) {
+	handler := rpc.New(nil, nil, nil, "", nil)
+	version, rpcErr := handler.SpecVersion()
+	require.Nil(t, rpcErr)
+	require.Equal(t, "0.7.0", version)
+
+	legacyVersion, rpcErr := handler.SpecVersionV0_6()
+	require.Nil(t, rpcErr)
+	require.Equal(t, "0.6.0", legacyVersion)
+}
+
+func TestSpecVersionResponses(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+
+	network := utils.Mainnet
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&network).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	mockState.EXPECT().ContractClassHash(gomock.Any()).Return(new(felt.Felt), nil).AnyTimes()
+	mockState.EXPECT().Class(gomock.Any()).Return(callableClass, nil).AnyTimes()
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil).AnyTimes()
+	mockReader.EXPECT().HeadsHeader().Return(&core.Header{
+		GasPrice:       new(felt.Felt).SetUint64(10),
+		L1DataGasPrice: &core.GasPrice{PriceInWei: new(felt.Felt).SetUint64(2)},
+	}, nil).AnyTimes()
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
+
+	t.Run("call", func(t *testing.T) {
+		mockVM.EXPECT().Call(gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network, gomock.Any(), true).Return(vm.CallResult{}, nil)
+		_, rpcErr := handler.Call(rpc.FunctionCall{}, rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+
+		mockVM.EXPECT().Call(gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network, gomock.Any(), false).
+			Return(vm.CallResult{}, nil)
+		_, rpcErr = handler.CallV0_6(rpc.FunctionCall{}, rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+	})
+
+	t.Run("estimate fee", func(t *testing.T) {
+		txn := rpc.BroadcastedTransaction{
+			Transaction: rpc.Transaction{
+				Type:          rpc.TxnInvoke,
+				Version:       new(felt.Felt).SetUint64(1),
+				Nonce:         &felt.Zero,
+				MaxFee:        &felt.Zero,
+				SenderAddress: new(felt.Felt).SetUint64(1),
+				Signature:     &[]*felt.Felt{},
+				CallData:      &[]*felt.Felt{},
+			},
 		}
-		block := &core.Block{
-			Header:       header,
-			Transactions: []core.Transaction{l1Tx, declareTx},
+		trace := func() []vm.TransactionTrace {
+			return []vm.TransactionTrace{{
+				Type: vm.TxnInvoke,
+				ExecuteInvocation: &vm.ExecuteInvocation{
+					FunctionInvocation: &vm.FunctionInvocation{ExecutionResources: &vm.ExecutionResources{}},
+				},
+			}}
 		}
 
-		mockReader.EXPECT().BlockByHash(blockHash).Return(block, nil)
-		state := mocks.NewMockStateHistoryReader(mockCtrl)
-		mockReader.EXPECT().StateAtBlockHash(header.ParentHash).Return(state, nopCloser, nil)
-		headState := mocks.NewMockStateHistoryReader(mockCtrl)
-		headState.EXPECT().Class(declareTx.ClassHash).Return(declaredClass, nil)
-		mockReader.EXPECT().PendingState().Return(headState, nopCloser, nil)
+		// 50 gas at 10 and 5 data gas at 2
+		mockVM.EXPECT().Execute(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+			true, false, true, true).
+			Return([]*felt.Felt{new(felt.Felt).SetUint64(510)}, []*felt.Felt{new(felt.Felt).SetUint64(5)}, trace(), nil)
+		estimates, rpcErr := handler.EstimateFee([]rpc.BroadcastedTransaction{txn}, nil, rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+		estimateJSON, err := json.Marshal(estimates)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{
+			"gas_consumed": "0x32",
+			"gas_price": "0xa",
+			"data_gas_consumed": "0x5",
+			"data_gas_price": "0x2",
+			"overall_fee": "0x1fe",
+			"unit": "WEI"
+		}]`, string(estimateJSON))
+
+		// without blob data everything is paid as gas
+		mockVM.EXPECT().Execute(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+			true, false, true, false).
+			Return([]*felt.Felt{new(felt.Felt).SetUint64(500)}, []*felt.Felt{&felt.Zero}, trace(), nil)
+		estimates, rpcErr = handler.EstimateFeeV0_6([]rpc.BroadcastedTransaction{txn}, nil, rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+		estimateJSON, err = json.Marshal(estimates)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{
+			"gas_consumed": "0x32",
+			"gas_price": "0xa",
+			"overall_fee": "0x1f4",
+			"unit": "WEI"
+		}]`, string(estimateJSON))
+	})
+}
 
-		paidL1Fees := []*felt.Felt{(&felt.Felt{}).SetUint64(1)}
-		vmTraceJSON := json.RawMessage(`{
-			"validate_invocation": {},
-			"execute_invocation": {},
-			"fee_transfer_invocation": {},
-			"state_diff": {
-				"storage_diffs": [],
-				"nonces": [],
-				"deployed_contracts": [],
-				"deprecated_declared_classes": [],
-				"declared_classes": [],
-				"replaced_classes": []
-			}
-		}`)
-		vmTrace := vm.TransactionTrace{}
-		require.NoError(t, json.Unmarshal(vmTraceJSON, &vmTrace))
-		mockVM.EXPECT().Execute(block.Transactions, []core.Class{declaredClass.Class}, paidL1Fees, &vm.BlockInfo{Header: header},
-			gomock.Any(), &network, false, false, false, false).Return(nil, []vm.TransactionTrace{vmTrace, vmTrace}, nil)
+func TestEstimateFee(t *testing.T) {
+	t.Skip()
 
-		result, err := handler.TraceBlockTransactions(context.Background(), rpc.BlockID{Hash: blockHash})
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	network := utils.Mainnet
+
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&network).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	log := utils.NewNopZapLogger()
+	handler := rpc.New(mockReader, nil, mockVM, "", log)
+
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil).AnyTimes()
+	mockReader.EXPECT().HeadsHeader().Return(&core.Header{}, nil).AnyTimes()
+
+	blockInfo := vm.BlockInfo{Header: &core.Header{}}
+	t.Run("ok with zero values", func(t *testing.T) {
+		mockVM.EXPECT().Execute(nil, nil, []*felt.Felt{}, &blockInfo, mockState, &network, true, true, false, false).
+			Return([]*felt.Felt{}, []vm.TransactionTrace{}, nil)
+
+		_, err := handler.EstimateFee([]rpc.BroadcastedTransaction{}, []rpc.SimulationFlag{}, rpc.BlockID{Latest: true})
 		require.Nil(t, err)
-		assert.Equal(t, &vm.TransactionTrace{
-			ValidateInvocation:    &vm.FunctionInvocation{},
-			ExecuteInvocation:     &vm.ExecuteInvocation{},
-			FeeTransferInvocation: &vm.FunctionInvocation{},
-			StateDiff: &vm.StateDiff{
-				StorageDiffs:              []vm.StorageDiff{},
-				Nonces:                    []vm.Nonce{},
-				DeployedContracts:         []vm.DeployedContract{},
-				DeprecatedDeclaredClasses: []*felt.Felt{},
-				DeclaredClasses:           []vm.DeclaredClass{},
-				ReplacedClasses:           []vm.ReplacedClass{},
-			},
-		}, result[0].TraceRoot)
-		assert.Equal(t, l1Tx.TransactionHash, result[0].TransactionHash)
 	})
-	t.Run("regular block", func(t *testing.T) {
-		blockHash := utils.HexToFelt(t, "0x37b244ea7dc6b3f9735fba02d183ef0d6807a572dd91a63cc1b14b923c1ac0")
-		tx := &core.DeclareTransaction{
-			TransactionHash: utils.HexToFelt(t, "0x000000001"),
-			ClassHash:       utils.HexToFelt(t, "0x000000000"),
-		}
 
-		header := &core.Header{
-			Hash:             blockHash,
-			ParentHash:       utils.HexToFelt(t, "0x0"),
-			Number:           0,
-			SequencerAddress: utils.HexToFelt(t, "0X111"),
-			GasPrice:         utils.HexToFelt(t, "0x777"),
-			ProtocolVersion:  "99.12.3",
-		}
-		block := &core.Block{
-			Header:       header,
-			Transactions: []core.Transaction{tx},
-		}
-		declaredClass := &core.DeclaredClass{
-			At:    3002,
-			Class: &core.Cairo1Class{},
+	t.Run("ok with zero values, skip validate", func(t *testing.T) {
+		mockVM.EXPECT().Execute(nil, nil, []*felt.Felt{}, &blockInfo, mockState, &network, true, true, false, false).
+			Return([]*felt.Felt{}, []vm.TransactionTrace{}, nil)
+
+		_, err := handler.EstimateFee([]rpc.BroadcastedTransaction{}, []rpc.SimulationFlag{rpc.SkipValidateFlag}, rpc.BlockID{Latest: true})
+		require.Nil(t, err)
+	})
+
+	t.Run("transaction execution error", func(t *testing.T) {
+		mockVM.EXPECT().Execute(nil, nil, []*felt.Felt{}, &blockInfo, mockState, &network, true, true, false, false).
+			Return(nil, nil, vm.TransactionExecutionError{
+				Index: 44,
+				Cause: errors.New("oops"),
+			})
+
+		_, err := handler.EstimateFee([]rpc.BroadcastedTransaction{}, []rpc.SimulationFlag{rpc.SkipValidateFlag}, rpc.BlockID{Latest: true})
+		require.Equal(t, rpc.ErrTransactionExecutionError.CloneWithData(rpc.TransactionExecutionErrorData{
+			TransactionIndex: 44,
+			ExecutionError:   "oops",
+		}), err)
+
+		mockVM.EXPECT().Execute(nil, nil, []*felt.Felt{}, &blockInfo, mockState, &network, false, true, true, false).
+			Return(nil, nil, vm.TransactionExecutionError{
+				Index: 44,
+				Cause: errors.New("oops"),
+			})
+	})
+}
+
+func TestEstimateFeeSkipValidate(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+
+	network := utils.Mainnet
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&network).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil).AnyTimes()
+	mockReader.EXPECT().HeadsHeader().Return(&core.Header{
+		GasPrice:       new(felt.Felt).SetUint64(100),
+		L1DataGasPrice: &core.GasPrice{PriceInWei: new(felt.Felt).SetUint64(20)},
+	}, nil).AnyTimes()
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
+
+	// the sender is not funded yet, so its __validate__ fails unless it is skipped
+	txn := rpc.BroadcastedTransaction{
+		Transaction: rpc.Transaction{
+			Type:          rpc.TxnInvoke,
+			Version:       new(felt.Felt).SetUint64(1),
+			Nonce:         &felt.Zero,
+			MaxFee:        &felt.Zero,
+			SenderAddress: new(felt.Felt).SetUint64(0xabc),
+			Signature:     &[]*felt.Felt{},
+			CallData:      &[]*felt.Felt{},
+		},
+	}
+
+	for _, v0_6 := range []bool{false, true} {
+		estimateFee := handler.EstimateFee
+		if v0_6 {
+			estimateFee = handler.EstimateFeeV0_6
 		}
 
-		mockReader.EXPECT().BlockByHash(blockHash).Return(block, nil)
+		t.Run(fmt.Sprintf("v0_6=%v", v0_6), func(t *testing.T) {
+			mockVM.EXPECT().Execute(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+				true, false, true, !v0_6).
+				Return(nil, nil, nil, vm.TransactionExecutionError{Index: 0, Cause: errors.New("validation failed")})
+
+			_, rpcErr := estimateFee([]rpc.BroadcastedTransaction{txn}, nil, rpc.BlockID{Latest: true})
+			require.Equal(t, rpc.ErrTransactionExecutionError.CloneWithData(rpc.TransactionExecutionErrorData{
+				TransactionIndex: 0,
+				ExecutionError:   "validation failed",
+			}), rpcErr)
+
+			// 10 gas at 100 and 5 data gas at 20, without the validation gas
+			mockVM.EXPECT().Execute(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+				true, true, true, !v0_6).
+				Return([]*felt.Felt{new(felt.Felt).SetUint64(1100)}, []*felt.Felt{new(felt.Felt).SetUint64(5)},
+					[]vm.TransactionTrace{{}}, nil)
+
+			flags := make([]rpc.SimulationFlag, 1, 2)
+			flags[0] = rpc.SkipValidateFlag
+			estimates, rpcErr := estimateFee([]rpc.BroadcastedTransaction{txn}, flags, rpc.BlockID{Latest: true})
+			require.Nil(t, rpcErr)
+			require.Len(t, estimates, 1)
+			assert.Equal(t, new(felt.Felt).SetUint64(1100), estimates[0].OverallFee)
+			// SkipFeeChargeFlag is not appended into the spare capacity of the caller's flags
+			assert.Equal(t, rpc.SimulationFlag(0), flags[:2][1])
+		})
+	}
+}
 
-		mockReader.EXPECT().StateAtBlockHash(header.ParentHash).Return(nil, nopCloser, nil)
-		headState := mocks.NewMockStateHistoryReader(mockCtrl)
-		headState.EXPECT().Class(tx.ClassHash).Return(declaredClass, nil)
-		mockReader.EXPECT().HeadState().Return(headState, nopCloser, nil)
+func TestEstimateFeeDeployAccount(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
 
-		vmTraceJSON := json.RawMessage(`{
-			"validate_invocation":{"entry_point_selector":"0x36fcbf06cd96843058359e1a75928beacfac10727dab22a3972f0af8aa92895","calldata":["0x25ec026985a3bf9d0cc1fe17326b245dfdc3ff89b8fde106542a3ea56c5a918","0x322258135d04971e96b747a5551061aa046ad5d8be11a35c67029d96b23f98","0x33434ad846cdd5f23eb73ff09fe6fddd568284a0fb7d1be20ee482f044dabe2","0x79dc0da7c54b95f10aa182ad0a46400db63156920adb65eca2654c0945a463","0x2","0x322258135d04971e96b747a5551061aa046ad5d8be11a35c67029d96b23f98","0x0"],"caller_address":"0x0","class_hash":"0x25ec026985a3bf9d0cc1fe17326b245dfdc3ff89b8fde106542a3ea56c5a918","entry_point_type":"EXTERNAL","call_type":"CALL","result":[],"calls":[{"entry_point_selector":"0x36fcbf06cd96843058359e1a75928beacfac10727dab22a3972f0af8aa92895","calldata":["0x25ec026985a3bf9d0cc1fe17326b245dfdc3ff89b8fde106542a3ea56c5a918","0x322258135d04971e96b747a5551061aa046ad5d8be11a35c67029d96b23f98","0x33434ad846cdd5f23eb73ff09fe6fddd568284a0fb7d1be20ee482f044dabe2","0x79dc0da7c54b95f10aa182ad0a46400db63156920adb65eca2654c0945a463","0x2","0x322258135d04971e96b747a5551061aa046ad5d8be11a35c67029d96b23f98","0x0"],"caller_address":"0x0","class_hash":"0x33434ad846cdd5f23eb73ff09fe6fddd568284a0fb7d1be20ee482f044dabe2","entry_point_type":"EXTERNAL","call_type":"DELEGATE","result":[],"calls":[],"events":[],"messages":[]}],"events":[],"messages":[]},
-			"execute_invocation":{"entry_point_selector":"0x28ffe4ff0f226a9107253e17a904099aa4f63a02a5621de0576e5aa71bc5194","calldata":["0x33434ad846cdd5f23eb73ff09fe6fddd568284a0fb7d1be20ee482f044dabe2","0x79dc0da7c54b95f10aa182ad0a46400db63156920adb65eca2654c0945a463","0x2","0x322258135d04971e96b747a5551061aa046ad5d8be11a35c67029d96b23f98","0x0"],"caller_address":"0x0","class_hash":"0x25ec026985a3bf9d0cc1fe17326b245dfdc3ff89b8fde106542a3ea56c5a918","entry_point_type":"CONSTRUCTOR","call_type":"CALL","result":[],"calls":[{"entry_point_selector":"0x79dc0da7c54b95f10aa182ad0a46400db63156920adb65eca2654c0945a463","calldata":["0x322258135d04971e96b747a5551061aa046ad5d8be11a35c67029d96b23f98","0x0"],"caller_address":"0x0","class_hash":"0x33434ad846cdd5f23eb73ff09fe6fddd568284a0fb7d1be20ee482f044dabe2","entry_point_type":"EXTERNAL","call_type":"DELEGATE","result":[],"calls":[],"events":[{"keys":["0x10c19bef19acd19b2c9f4caa40fd47c9fbe1d9f91324d44dcd36be2dae96784"],"data":["0xdac9bcffb3d967f19a7fe21002c98c984d5a9458a88e6fc5d1c478a97ed412","0x322258135d04971e96b747a5551061aa046ad5d8be11a35c67029d96b23f98","0x0"]}],"messages":[]}],"events":[],"messages":[]},
-			"fee_transfer_invocation":{"entry_point_selector":"0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e","calldata":["0x5dcd266a80b8a5f29f04d779c6b166b80150c24f2180a75e82427242dab20a9","0x15be","0x0"],"caller_address":"0xdac9bcffb3d967f19a7fe21002c98c984d5a9458a88e6fc5d1c478a97ed412","class_hash":"0xd0e183745e9dae3e4e78a8ffedcce0903fc4900beace4e0abf192d4c202da3","entry_point_type":"EXTERNAL","call_type":"CALL","result":["0x1"],"calls":[{"entry_point_selector":"0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e","calldata":["0x5dcd266a80b8a5f29f04d779c6b166b80150c24f2180a75e82427242dab20a9","0x15be","0x0"],"caller_address":"0xdac9bcffb3d967f19a7fe21002c98c984d5a9458a88e6fc5d1c478a97ed412","class_hash":"0x2760f25d5a4fb2bdde5f561fd0b44a3dee78c28903577d37d669939d97036a0","entry_point_type":"EXTERNAL","call_type":"DELEGATE","result":["0x1"],"calls":[],"events":[{"keys":["0x99cd8bde557814842a3121e8ddfd433a539b8c9f14bf31ebf108d12e6196e9"],"data":["0xdac9bcffb3d967f19a7fe21002c98c984d5a9458a88e6fc5d1c478a97ed412","0x5dcd266a80b8a5f29f04d779c6b166b80150c24f2180a75e82427242dab20a9","0x15be","0x0"]}],"messages":[]}],"events":[],"messages":[]},
-			"state_diff": {
-				"storage_diffs": [],
-				"nonces": [],
-				"deployed_contracts": [],
-				"deprecated_declared_classes": [],
-				"declared_classes": [],
-				"replaced_classes": []
+	network := utils.Mainnet
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&network).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	// the account does not exist yet, so nothing may be read from the state before execution
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+	mockReader.EXPECT().HeadsHeader().Return(&core.Header{GasPrice: new(felt.Felt).SetUint64(10)}, nil)
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
+
+	classHash := utils.HexToFelt(t, "0x1a736d6ed154502257f02b1ccdf4d9d1089f80811cd6acad48e6b6a9d1f2003")
+	salt := utils.HexToFelt(t, "0x5a1")
+	publicKey := utils.HexToFelt(t, "0xbeef")
+	txn := rpc.BroadcastedTransaction{
+		Transaction: rpc.Transaction{
+			Type:                rpc.TxnDeployAccount,
+			Version:             new(felt.Felt).SetUint64(1),
+			Nonce:               &felt.Zero,
+			MaxFee:              &felt.Zero,
+			ClassHash:           classHash,
+			ContractAddressSalt: salt,
+			ConstructorCallData: &[]*felt.Felt{publicKey},
+			Signature:           &[]*felt.Felt{},
+		},
+	}
+	wantAddress := core.ContractAddress(&felt.Zero, classHash, salt, []*felt.Felt{publicKey})
+
+	mockVM.EXPECT().Execute(gomock.Len(1), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+		true, false, true, true).DoAndReturn(func(txns []core.Transaction, _ []core.Class, _ []*felt.Felt, _ *vm.BlockInfo,
+		_ core.StateReader, _ *utils.Network, _, _, _, _ bool,
+	) ([]*felt.Felt, []*felt.Felt, []vm.TransactionTrace, error) {
+		deployAccount, ok := txns[0].(*core.DeployAccountTransaction)
+		require.True(t, ok)
+		assert.Equal(t, wantAddress, deployAccount.ContractAddress)
+		wantHash, err := core.TransactionHash(deployAccount, &network)
+		require.NoError(t, err)
+		assert.Equal(t, wantHash, deployAccount.TransactionHash)
+
+		return []*felt.Felt{new(felt.Felt).SetUint64(500)}, []*felt.Felt{&felt.Zero}, []vm.TransactionTrace{{
+			Type:                  vm.TxnDeployAccount,
+			ConstructorInvocation: &vm.FunctionInvocation{ExecutionResources: &vm.ExecutionResources{}},
+		}}, nil
+	})
+
+	estimates, rpcErr := handler.EstimateFee([]rpc.BroadcastedTransaction{txn}, nil, rpc.BlockID{Latest: true})
+	require.Nil(t, rpcErr)
+	require.Len(t, estimates, 1)
+	assert.Equal(t, new(felt.Felt).SetUint64(500), estimates[0].OverallFee)
+	assert.Equal(t, new(felt.Felt).SetUint64(50), estimates[0].GasConsumed)
+}
+
+func TestEstimateFeeRange(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+
+	network := utils.Mainnet
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&network).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil).AnyTimes()
+	mockReader.EXPECT().HeadsHeader().Return(&core.Header{
+		GasPrice:       new(felt.Felt).SetUint64(100),
+		L1DataGasPrice: &core.GasPrice{PriceInWei: new(felt.Felt).SetUint64(20)},
+	}, nil).AnyTimes()
+	// 10 gas at 100 and 5 data gas at 20
+	mockVM.EXPECT().Execute(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+		true, false, true, true).
+		Return([]*felt.Felt{new(felt.Felt).SetUint64(1100)}, []*felt.Felt{new(felt.Felt).SetUint64(5)},
+			[]vm.TransactionTrace{{}}, nil).AnyTimes()
+
+	txn := rpc.BroadcastedTransaction{
+		Transaction: rpc.Transaction{
+			Type:          rpc.TxnInvoke,
+			Version:       new(felt.Felt).SetUint64(1),
+			Nonce:         &felt.Zero,
+			MaxFee:        &felt.Zero,
+			SenderAddress: new(felt.Felt).SetUint64(0xabc),
+			Signature:     &[]*felt.Felt{},
+			CallData:      &[]*felt.Felt{},
+		},
+	}
+
+	tests := map[string]struct {
+		band             *uint64 // nil keeps the default band
+		lowGasPrice      uint64
+		highGasPrice     uint64
+		lowDataGasPrice  uint64
+		highDataGasPrice uint64
+	}{
+		"default band":   {lowGasPrice: 90, highGasPrice: 110, lowDataGasPrice: 18, highDataGasPrice: 22},
+		"wide band":      {band: utils.Ptr[uint64](50), lowGasPrice: 50, highGasPrice: 150, lowDataGasPrice: 10, highDataGasPrice: 30},
+		"band over 100%": {band: utils.Ptr[uint64](150), lowGasPrice: 0, highGasPrice: 250, lowDataGasPrice: 0, highDataGasPrice: 50},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
+			if test.band != nil {
+				handler = handler.WithFeeBand(*test.band)
+			}
+
+			ranges, rpcErr := handler.EstimateFeeRange([]rpc.BroadcastedTransaction{txn}, nil, rpc.BlockID{Latest: true})
+			require.Nil(t, rpcErr)
+			require.Len(t, ranges, 1)
+
+			estimates, rpcErr := handler.EstimateFee([]rpc.BroadcastedTransaction{txn}, nil, rpc.BlockID{Latest: true})
+			require.Nil(t, rpcErr)
+
+			feeRange := ranges[0]
+			assert.Equal(t, estimates[0], feeRange.Expected)
+			assert.Equal(t, new(felt.Felt).SetUint64(test.lowGasPrice), feeRange.Low.GasPrice)
+			assert.Equal(t, new(felt.Felt).SetUint64(test.highGasPrice), feeRange.High.GasPrice)
+			assert.Equal(t, new(felt.Felt).SetUint64(test.lowDataGasPrice), feeRange.Low.DataGasPrice)
+			assert.Equal(t, new(felt.Felt).SetUint64(test.highDataGasPrice), feeRange.High.DataGasPrice)
+
+			for _, estimate := range []rpc.FeeEstimate{feeRange.Low, feeRange.High} {
+				assert.Equal(t, feeRange.Expected.GasConsumed, estimate.GasConsumed)
+				assert.Equal(t, feeRange.Expected.DataGasConsumed, estimate.DataGasConsumed)
+				overallFee := new(felt.Felt).Mul(estimate.GasConsumed, estimate.GasPrice)
+				overallFee.Add(overallFee, new(felt.Felt).Mul(estimate.DataGasConsumed, estimate.DataGasPrice))
+				assert.Equal(t, overallFee, estimate.OverallFee)
 			}
-		}`)
-		vmTrace := vm.TransactionTrace{}
-		require.NoError(t, json.Unmarshal(vmTraceJSON, &vmTrace))
-		mockVM.EXPECT().Execute([]core.Transaction{tx}, []core.Class{declaredClass.Class}, []*felt.Felt{}, &vm.BlockInfo{Header: header},
-			gomock.Any(), &network, false, false, false, false).Return(nil, []vm.TransactionTrace{vmTrace}, nil)
 
-		expectedResult := []rpc.TracedBlockTransaction{
-			{
-				TransactionHash: tx.Hash(),
-				TraceRoot:       &vmTrace,
-			},
-		}
-		result, err := handler.TraceBlockTransactions(context.Background(), rpc.BlockID{Hash: blockHash})
-		require.Nil(t, err)
-		assert.Equal(t, expectedResult, result)
-	})
+			assert.LessOrEqual(t, feeRange.Low.OverallFee.Uint64(), feeRange.Expected.OverallFee.Uint64())
+			assert.LessOrEqual(t, feeRange.Expected.OverallFee.Uint64(), feeRange.High.OverallFee.Uint64())
+		})
+	}
 }
 
-func TestRpcBlockAdaptation(t *testing.T) {
+func TestEstimateBundleFee(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	t.Cleanup(mockCtrl.Finish)
 
+	network := utils.Mainnet
 	mockReader := mocks.NewMockReader(mockCtrl)
-	handler := rpc.New(mockReader, nil, nil, "", nil)
+	mockReader.EXPECT().Network().Return(&network).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
 
-	client := feeder.NewTestClient(t, &utils.Goerli)
-	gw := adaptfeeder.New(client)
-	latestBlockNumber := uint64(485004)
+	t.Run("empty bundle", func(t *testing.T) {
+		estimate, rpcErr := handler.EstimateBundleFee(nil, rpc.BlockID{Latest: true})
+		assert.Nil(t, estimate)
+		assert.Equal(t, jsonrpc.InvalidParams, rpcErr.Code)
+	})
 
-	t.Run("default sequencer address", func(t *testing.T) {
-		latestBlock, err := gw.BlockByNumber(context.Background(), latestBlockNumber)
-		require.NoError(t, err)
-		latestBlock.Header.SequencerAddress = nil
-		mockReader.EXPECT().Head().Return(latestBlock, nil).Times(2)
-		mockReader.EXPECT().L1Head().Return(nil, db.ErrKeyNotFound).Times(2)
+	t.Run("later transaction depends on an earlier one", func(t *testing.T) {
+		mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+		header := &core.Header{GasPrice: new(felt.Felt).SetUint64(10)}
+		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+		mockReader.EXPECT().HeadsHeader().Return(header, nil)
+
+		sender := new(felt.Felt).SetUint64(0xabc)
+		newTxn := func(nonce uint64) rpc.BroadcastedTransaction {
+			return rpc.BroadcastedTransaction{
+				Transaction: rpc.Transaction{
+					Type:          rpc.TxnInvoke,
+					Version:       new(felt.Felt).SetUint64(1),
+					Nonce:         new(felt.Felt).SetUint64(nonce),
+					MaxFee:        &felt.Zero,
+					SenderAddress: sender,
+					Signature:     &[]*felt.Felt{},
+					CallData:      &[]*felt.Felt{},
+				},
+			}
+		}
 
-		block, rpcErr := handler.BlockWithTxs(rpc.BlockID{Latest: true})
-		require.NoError(t, err, rpcErr)
-		require.Equal(t, &felt.Zero, block.BlockHeader.SequencerAddress)
+		// The second transaction uses the nonce bumped by the first one, so it is only valid if both
+		// are executed in a single VM run, in order, on top of the same state.
+		mockVM.EXPECT().Execute(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+			true, false, true, true).DoAndReturn(
+			func(txns []core.Transaction, _ []core.Class, _ []*felt.Felt, _ *vm.BlockInfo, _ core.StateReader,
+				_ *utils.Network, _, _, _, _ bool,
+			) ([]*felt.Felt, []*felt.Felt, []vm.TransactionTrace, error) {
+				require.Len(t, txns, 2)
+				for i, txn := range txns {
+					assert.Equal(t, new(felt.Felt).SetUint64(uint64(i)), txn.(*core.InvokeTransaction).Nonce)
+				}
+				return []*felt.Felt{new(felt.Felt).SetUint64(100), new(felt.Felt).SetUint64(250)},
+					[]*felt.Felt{new(felt.Felt).SetUint64(1), new(felt.Felt).SetUint64(2)},
+					[]vm.TransactionTrace{{}, {}}, nil
+			})
 
-		blockWithTxHashes, rpcErr := handler.BlockWithTxHashes(rpc.BlockID{Latest: true})
-		require.NoError(t, err, rpcErr)
-		require.Equal(t, &felt.Zero, blockWithTxHashes.BlockHeader.SequencerAddress)
+		estimate, rpcErr := handler.EstimateBundleFee([]rpc.BroadcastedTransaction{newTxn(0), newTxn(1)},
+			rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+		assert.Equal(t, &rpc.FeeEstimate{
+			GasConsumed:     new(felt.Felt).SetUint64(35),
+			GasPrice:        header.GasPrice,
+			DataGasConsumed: new(felt.Felt).SetUint64(3),
+			DataGasPrice:    &felt.Zero,
+			OverallFee:      new(felt.Felt).SetUint64(350),
+			Unit:            utils.Ptr(rpc.WEI),
+		}, estimate)
 	})
 }
 
-type fakeConn struct {
-	w io.Writer
-}
+func TestEstimateFeeWithRevertTraces(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
 
-func (fc *fakeConn) Write(p []byte) (int, error) {
-	return fc.w.Write(p)
-}
+	network := utils.Mainnet
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&network).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
 
-func (fc *fakeConn) Equal(other jsonrpc.Conn) bool {
-	fc2, ok := other.(*fakeConn)
-	if !ok {
-		return false
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	header := &core.Header{GasPrice: new(felt.Felt).SetUint64(10)}
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+	mockReader.EXPECT().HeadsHeader().Return(header, nil)
+
+	txn := rpc.BroadcastedTransaction{
+		Transaction: rpc.Transaction{
+			Type:          rpc.TxnInvoke,
+			Version:       new(felt.Felt).SetUint64(1),
+			Nonce:         &felt.Zero,
+			MaxFee:        &felt.Zero,
+			SenderAddress: new(felt.Felt).SetUint64(0xabc),
+			Signature:     &[]*felt.Felt{},
+			CallData:      &[]*felt.Felt{},
+		},
+	}
+
+	revertedTrace := vm.TransactionTrace{
+		Type:              vm.TxnInvoke,
+		ExecuteInvocation: &vm.ExecuteInvocation{RevertReason: "assertion failed"},
+	}
+	successfulTrace := vm.TransactionTrace{
+		Type: vm.TxnInvoke,
+		ExecuteInvocation: &vm.ExecuteInvocation{
+			FunctionInvocation: &vm.FunctionInvocation{ExecutionResources: &vm.ExecutionResources{}},
+		},
+	}
+	fees := []*felt.Felt{new(felt.Felt).SetUint64(100), new(felt.Felt).SetUint64(200), new(felt.Felt).SetUint64(300)}
+	mockVM.EXPECT().Execute(gomock.Len(3), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+		true, false, false, true).
+		Return(fees, []*felt.Felt{&felt.Zero, &felt.Zero, &felt.Zero},
+			[]vm.TransactionTrace{successfulTrace, revertedTrace, successfulTrace}, nil)
+
+	flags := make([]rpc.SimulationFlag, 0, 1)
+	result, rpcErr := handler.EstimateFeeWithRevertTraces([]rpc.BroadcastedTransaction{txn, txn, txn}, flags,
+		rpc.BlockID{Latest: true})
+	require.Nil(t, rpcErr)
+	require.Len(t, result, 3)
+	// SkipFeeChargeFlag is not appended into the spare capacity of the caller's flags
+	assert.Equal(t, rpc.SimulationFlag(0), flags[:1][0])
+
+	for i, simulated := range result {
+		assert.Equal(t, fees[i], simulated.FeeEstimation.OverallFee)
+		if i == 1 {
+			require.NotNil(t, simulated.TransactionTrace)
+			assert.Equal(t, "assertion failed", simulated.TransactionTrace.RevertReason())
+		} else {
+			assert.Nil(t, simulated.TransactionTrace)
+		}
 	}
-	return fc.w == fc2.w
 }
 
-func TestSubscribeNewHeadsAndUnsubscribe(t *testing.T) {
-	t.Skip()
-	t.Parallel()
-	log := utils.NewNopZapLogger()
-	network := utils.Mainnet
-	client := feeder.NewTestClient(t, &network)
-	gw := adaptfeeder.New(client)
-	ctx, cancel := context.WithCancel(context.Background())
-	t.Cleanup(cancel)
-	chain := blockchain.New(pebble.NewMemTest(t), &network)
-	syncer := sync.New(chain, gw, log, 0, false)
-	handler := rpc.New(chain, syncer, nil, "", log)
+func TestSimulateWithStateDiff(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
 
-	go func() {
-		require.NoError(t, handler.Run(ctx))
-	}()
-	// Technically, there's a race between goroutine above and the SubscribeNewHeads call down below.
-	// Sleep for a moment just in case.
-	time.Sleep(50 * time.Millisecond)
+	network := utils.Mainnet
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&network).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
 
-	serverConn, clientConn := net.Pipe()
-	t.Cleanup(func() {
-		require.NoError(t, serverConn.Close())
-		require.NoError(t, clientConn.Close())
-	})
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
+	mockReader.EXPECT().HeadsHeader().Return(&core.Header{GasPrice: new(felt.Felt).SetUint64(10)}, nil)
+
+	sender := new(felt.Felt).SetUint64(0xabc)
+	txn := rpc.BroadcastedTransaction{
+		Transaction: rpc.Transaction{
+			Type:          rpc.TxnInvoke,
+			Version:       new(felt.Felt).SetUint64(1),
+			Nonce:         &felt.Zero,
+			MaxFee:        &felt.Zero,
+			SenderAddress: sender,
+			Signature:     &[]*felt.Felt{},
+			CallData:      &[]*felt.Felt{},
+		},
+	}
 
-	// Subscribe without setting the connection on the context.
-	id, rpcErr := handler.SubscribeNewHeads(ctx)
-	require.Zero(t, id)
-	require.Equal(t, jsonrpc.MethodNotFound, rpcErr.Code)
+	// a transfer moves the balance between the sender's and the recipient's ERC20_balances slots
+	token := utils.HexToFelt(t, "0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7")
+	recipient := new(felt.Felt).SetUint64(0xdef)
+	transferDiff := &vm.StateDiff{
+		StorageDiffs: []vm.StorageDiff{{
+			Address: *token,
+			StorageEntries: []vm.Entry{
+				{Key: *core.ComputeStorageKey("ERC20_balances", []*felt.Felt{sender}), Value: *new(felt.Felt).SetUint64(90)},
+				{Key: *core.ComputeStorageKey("ERC20_balances", []*felt.Felt{recipient}), Value: *new(felt.Felt).SetUint64(10)},
+			},
+		}},
+		Nonces: []vm.Nonce{{ContractAddress: *sender, Nonce: *new(felt.Felt).SetUint64(1)}},
+	}
+	transferTrace := vm.TransactionTrace{
+		Type: vm.TxnInvoke,
+		ExecuteInvocation: &vm.ExecuteInvocation{
+			FunctionInvocation: &vm.FunctionInvocation{ExecutionResources: &vm.ExecutionResources{}},
+		},
+		StateDiff: transferDiff,
+	}
+	revertedTrace := vm.TransactionTrace{
+		Type:              vm.TxnInvoke,
+		ExecuteInvocation: &vm.ExecuteInvocation{RevertReason: "insufficient balance"},
+		StateDiff:         transferDiff,
+	}
 
-	// Sync blocks and then revert head.
-	// This is a super hacky way to deterministically receive a single block on the subscription.
-	// It would be nicer if we could tell the synchronizer to exit after a certain block height, but, alas, we can't do that.
-	syncCtx, syncCancel := context.WithTimeout(context.Background(), time.Second)
-	require.NoError(t, syncer.Run(syncCtx))
-	syncCancel()
-	// This is technically an unsafe thing to do. We're modifying the synchronizer's blockchain while it is owned by the synchronizer.
-	// But it works.
-	require.NoError(t, chain.RevertHead())
+	mockVM.EXPECT().Execute(gomock.Len(2), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+		true, true, false, true).
+		Return([]*felt.Felt{new(felt.Felt).SetUint64(100), new(felt.Felt).SetUint64(100)},
+			[]*felt.Felt{&felt.Zero, &felt.Zero}, []vm.TransactionTrace{transferTrace, revertedTrace}, nil)
 
-	// Subscribe.
-	subCtx := context.WithValue(ctx, jsonrpc.ConnKey{}, &fakeConn{w: serverConn})
-	id, rpcErr = handler.SubscribeNewHeads(subCtx)
+	result, rpcErr := handler.SimulateWithStateDiff(rpc.BlockID{Latest: true}, []rpc.BroadcastedTransaction{txn, txn},
+		[]rpc.SimulationFlag{rpc.SkipFeeChargeFlag, rpc.SkipValidateFlag})
 	require.Nil(t, rpcErr)
+	require.Len(t, result, 2)
 
-	// Sync the block we reverted above.
-	syncCtx, syncCancel = context.WithTimeout(context.Background(), 250*time.Millisecond)
-	require.NoError(t, syncer.Run(syncCtx))
-	syncCancel()
+	assert.Equal(t, rpc.SimulatedStateDiff{StateDiff: transferDiff}, result[0])
 
-	// Receive a block header.
-	want := `{"jsonrpc":"2.0","method":"juno_subscribeNewHeads","params":{"result":{"block_hash":"0x4e1f77f39545afe866ac151ac908bd1a347a2a8a7d58bef1276db4f06fdf2f6","parent_hash":"0x2a70fb03fe363a2d6be843343a1d81ce6abeda1e9bd5cc6ad8fa9f45e30fdeb","block_number":2,"new_root":"0x3ceee867d50b5926bb88c0ec7e0b9c20ae6b537e74aac44b8fcf6bb6da138d9","timestamp":1637084470,"sequencer_address":"0x0","l1_gas_price":{"price_in_fri":"0x0","price_in_wei":"0x0"},"starknet_version":""},"subscription":%d}}`
-	want = fmt.Sprintf(want, id)
-	got := make([]byte, len(want))
-	_, err := clientConn.Read(got)
-	require.NoError(t, err)
-	require.Equal(t, want, string(got))
+	assert.True(t, result[1].Reverted)
+	assert.Equal(t, "insufficient balance", result[1].RevertReason)
+	assert.Empty(t, result[1].StateDiff.StorageDiffs)
+	assert.Empty(t, result[1].StateDiff.Nonces)
+}
 
-	// Unsubscribe without setting the connection on the context.
-	ok, rpcErr := handler.Unsubscribe(ctx, id)
-	require.Equal(t, jsonrpc.MethodNotFound, rpcErr.Code)
-	require.False(t, ok)
+func TestSimulateWithFeeCharge(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
 
-	// Unsubscribe on correct connection with the incorrect id.
-	ok, rpcErr = handler.Unsubscribe(subCtx, id+1)
-	require.Equal(t, rpc.ErrSubscriptionNotFound, rpcErr)
-	require.False(t, ok)
+	network := utils.Mainnet
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&network).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
 
-	// Unsubscribe on incorrect connection with the correct id.
-	subCtx = context.WithValue(context.Background(), jsonrpc.ConnKey{}, &fakeConn{})
-	ok, rpcErr = handler.Unsubscribe(subCtx, id)
-	require.Equal(t, rpc.ErrSubscriptionNotFound, rpcErr)
-	require.False(t, ok)
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil).AnyTimes()
+	mockReader.EXPECT().HeadsHeader().Return(&core.Header{GasPrice: new(felt.Felt).SetUint64(10)}, nil).AnyTimes()
+
+	txn := rpc.BroadcastedTransaction{
+		Transaction: rpc.Transaction{
+			Type:          rpc.TxnInvoke,
+			Version:       new(felt.Felt).SetUint64(1),
+			Nonce:         &felt.Zero,
+			MaxFee:        new(felt.Felt).SetUint64(1000),
+			SenderAddress: new(felt.Felt).SetUint64(0xabc),
+			Signature:     &[]*felt.Felt{},
+			CallData:      &[]*felt.Felt{},
+		},
+	}
+	okTrace := vm.TransactionTrace{
+		Type: vm.TxnInvoke,
+		ExecuteInvocation: &vm.ExecuteInvocation{
+			FunctionInvocation: &vm.FunctionInvocation{ExecutionResources: &vm.ExecutionResources{}},
+		},
+	}
+	// SKIP_FEE_CHARGE is requested in every case, but the VM must always be told to charge fees
+	expectExecute := func(n int) *gomock.Call {
+		return mockVM.EXPECT().Execute(gomock.Len(n), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+			false, true, false, true)
+	}
+	flags := []rpc.SimulationFlag{rpc.SkipFeeChargeFlag, rpc.SkipValidateFlag}
 
-	// Unsubscribe on correct connection with the correct id.
-	subCtx = context.WithValue(context.Background(), jsonrpc.ConnKey{}, &fakeConn{w: serverConn})
-	ok, rpcErr = handler.Unsubscribe(subCtx, id)
-	require.Nil(t, rpcErr)
-	require.True(t, ok)
-}
+	t.Run("funded account", func(t *testing.T) {
+		expectExecute(1).Return([]*felt.Felt{new(felt.Felt).SetUint64(100)}, []*felt.Felt{&felt.Zero},
+			[]vm.TransactionTrace{okTrace}, nil)
 
-func TestMultipleSubscribeNewHeadsAndUnsubscribe(t *testing.T) {
-	t.Skip()
-	t.Parallel()
-	log := utils.NewNopZapLogger()
-	network := utils.Mainnet
-	feederClient := feeder.NewTestClient(t, &network)
-	gw := adaptfeeder.New(feederClient)
-	ctx, cancel := context.WithCancel(context.Background())
-	t.Cleanup(cancel)
-	chain := blockchain.New(pebble.NewMemTest(t), &network)
-	syncer := sync.New(chain, gw, log, 0, false)
-	handler := rpc.New(chain, syncer, nil, "", log)
-	go func() {
-		require.NoError(t, handler.Run(ctx))
-	}()
-	// Technically, there's a race between goroutine above and the SubscribeNewHeads call down below.
-	// Sleep for a moment just in case.
-	time.Sleep(50 * time.Millisecond)
+		result, rpcErr := handler.SimulateWithFeeCharge(rpc.BlockID{Latest: true}, []rpc.BroadcastedTransaction{txn}, flags)
+		require.Nil(t, rpcErr)
+		require.Len(t, result, 1)
+		assert.Empty(t, result[0].TransactionTrace.RevertReason())
+		assert.Equal(t, new(felt.Felt).SetUint64(100), result[0].FeeEstimation.OverallFee)
+	})
 
-	// Sync blocks and then revert head.
-	// This is a super hacky way to deterministically receive a single block on the subscription.
-	// It would be nicer if we could tell the synchronizer to exit after a certain block height, but, alas, we can't do that.
-	syncCtx, syncCancel := context.WithTimeout(context.Background(), time.Second)
-	require.NoError(t, syncer.Run(syncCtx))
-	syncCancel()
-	// This is technically an unsafe thing to do. We're modifying the synchronizer's blockchain while it is owned by the synchronizer.
-	// But it works.
-	require.NoError(t, chain.RevertHead())
+	t.Run("underfunded account", func(t *testing.T) {
+		balanceErr := "Max fee (0x3e8) exceeds balance (Low: 0x64, High: 0x0)."
+		expectExecute(2).Return(nil, nil, nil, vm.TransactionExecutionError{Index: 1, Cause: errors.New(balanceErr)})
 
-	server := jsonrpc.NewServer(1, log)
-	require.NoError(t, server.RegisterMethods(jsonrpc.Method{
-		Name:    "juno_subscribeNewHeads",
-		Handler: handler.SubscribeNewHeads,
-	}, jsonrpc.Method{
-		Name:    "juno_unsubscribe",
-		Params:  []jsonrpc.Parameter{{Name: "id"}},
-		Handler: handler.Unsubscribe,
-	}))
-	ws := jsonrpc.NewWebsocket(server, log)
-	httpSrv := httptest.NewServer(ws)
-	conn1, _, err := websocket.Dial(ctx, httpSrv.URL, nil)
-	require.NoError(t, err)
-	conn2, _, err := websocket.Dial(ctx, httpSrv.URL, nil)
-	require.NoError(t, err)
+		result, rpcErr := handler.SimulateWithFeeCharge(rpc.BlockID{Latest: true}, []rpc.BroadcastedTransaction{txn, txn}, flags)
+		assert.Nil(t, result)
+		assert.Equal(t, rpc.ErrInsufficientAccountBalance.CloneWithData(rpc.TransactionExecutionErrorData{
+			TransactionIndex: 1,
+			ExecutionError:   balanceErr,
+		}), rpcErr)
+	})
 
-	subscribeMsg := []byte(`{"jsonrpc":"2.0","id":1,"method":"juno_subscribeNewHeads"}`)
+	t.Run("actual fee exceeds balance", func(t *testing.T) {
+		balanceErr := "Insufficient fee token balance. Fee: 500, balance: low/high 100/0."
+		revertedTrace := vm.TransactionTrace{
+			Type:              vm.TxnInvoke,
+			ExecuteInvocation: &vm.ExecuteInvocation{RevertReason: balanceErr},
+		}
+		expectExecute(2).Return([]*felt.Felt{new(felt.Felt).SetUint64(100), new(felt.Felt).SetUint64(500)},
+			[]*felt.Felt{&felt.Zero, &felt.Zero}, []vm.TransactionTrace{okTrace, revertedTrace}, nil)
+
+		result, rpcErr := handler.SimulateWithFeeCharge(rpc.BlockID{Latest: true}, []rpc.BroadcastedTransaction{txn, txn}, flags)
+		assert.Nil(t, result)
+		assert.Equal(t, rpc.ErrInsufficientAccountBalance.CloneWithData(rpc.TransactionExecutionErrorData{
+			TransactionIndex: 1,
+			ExecutionError:   balanceErr,
+		}), rpcErr)
+	})
 
-	firstID := uint64(1)
-	secondID := uint64(2)
-	handler.WithIDGen(func() uint64 { return firstID })
-	require.NoError(t, conn1.Write(ctx, websocket.MessageText, subscribeMsg))
+	t.Run("contract reverts mentioning the balance are unchanged", func(t *testing.T) {
+		revertReason := "Execution failed. Failure reason: 'ERC20: transfer amount exceeds balance'."
+		revertedTrace := vm.TransactionTrace{
+			Type:              vm.TxnInvoke,
+			ExecuteInvocation: &vm.ExecuteInvocation{RevertReason: revertReason},
+		}
+		expectExecute(1).Return([]*felt.Felt{new(felt.Felt).SetUint64(100)}, []*felt.Felt{&felt.Zero},
+			[]vm.TransactionTrace{revertedTrace}, nil)
 
-	want := `{"jsonrpc":"2.0","result":%d,"id":1}`
-	firstWant := fmt.Sprintf(want, firstID)
-	_, firstGot, err := conn1.Read(ctx)
-	require.NoError(t, err)
-	require.Equal(t, firstWant, string(firstGot))
+		result, rpcErr := handler.SimulateWithFeeCharge(rpc.BlockID{Latest: true}, []rpc.BroadcastedTransaction{txn}, flags)
+		require.Nil(t, rpcErr)
+		require.Len(t, result, 1)
+		assert.Equal(t, revertReason, result[0].TransactionTrace.RevertReason())
+	})
+
+	t.Run("validation failures mentioning the balance are unchanged", func(t *testing.T) {
+		validateErr := "Transaction validation error: Execution failed. Failure reason: 'transfer amount exceeds balance'."
+		expectExecute(1).Return(nil, nil, nil, vm.TransactionExecutionError{Index: 0, Cause: errors.New(validateErr)})
+
+		result, rpcErr := handler.SimulateWithFeeCharge(rpc.BlockID{Latest: true}, []rpc.BroadcastedTransaction{txn}, flags)
+		assert.Nil(t, result)
+		assert.Equal(t, rpc.ErrTransactionExecutionError.CloneWithData(rpc.TransactionExecutionErrorData{
+			TransactionIndex: 0,
+			ExecutionError:   validateErr,
+		}), rpcErr)
+	})
+
+	t.Run("other execution errors are unchanged", func(t *testing.T) {
+		expectExecute(1).Return(nil, nil, nil, vm.TransactionExecutionError{Index: 0, Cause: errors.New("invalid signature")})
+
+		result, rpcErr := handler.SimulateWithFeeCharge(rpc.BlockID{Latest: true}, []rpc.BroadcastedTransaction{txn}, flags)
+		assert.Nil(t, result)
+		assert.Equal(t, rpc.ErrTransactionExecutionError.CloneWithData(rpc.TransactionExecutionErrorData{
+			TransactionIndex: 0,
+			ExecutionError:   "invalid signature",
+		}), rpcErr)
+	})
+}
+
+func TestEstimateFeeMatrix(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+
+	network := utils.Mainnet
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&network).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
+
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	header := &core.Header{GasPrice: new(felt.Felt).SetUint64(10)}
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil).AnyTimes()
+	mockReader.EXPECT().HeadsHeader().Return(header, nil).AnyTimes()
+
+	txn := rpc.BroadcastedTransaction{
+		Transaction: rpc.Transaction{
+			Type:          rpc.TxnInvoke,
+			Version:       new(felt.Felt).SetUint64(1),
+			Nonce:         &felt.Zero,
+			MaxFee:        &felt.Zero,
+			SenderAddress: new(felt.Felt).SetUint64(0xabc),
+			Signature:     &[]*felt.Felt{},
+			CallData:      &[]*felt.Felt{},
+		},
+	}
 
-	handler.WithIDGen(func() uint64 { return secondID })
-	require.NoError(t, conn2.Write(ctx, websocket.MessageText, subscribeMsg))
-	secondWant := fmt.Sprintf(want, secondID)
-	_, secondGot, err := conn2.Read(ctx)
-	require.NoError(t, err)
-	require.Equal(t, secondWant, string(secondGot))
+	// the fee depends on the flags the VM was called with, so that each flag set yields a distinct estimate
+	mockVM.EXPECT().Execute(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+		gomock.Any(), gomock.Any(), true, true).DoAndReturn(
+		func(txns []core.Transaction, _ []core.Class, _ []*felt.Felt, _ *vm.BlockInfo, _ core.StateReader,
+			_ *utils.Network, skipChargeFee, skipValidate, _, _ bool,
+		) ([]*felt.Felt, []*felt.Felt, []vm.TransactionTrace, error) {
+			require.Len(t, txns, 1)
+			fee := uint64(100)
+			if skipValidate {
+				fee -= 20
+			}
+			if skipChargeFee {
+				fee -= 10
+			}
+			return []*felt.Felt{new(felt.Felt).SetUint64(fee)}, []*felt.Felt{&felt.Zero}, []vm.TransactionTrace{{}}, nil
+		}).AnyTimes()
 
-	// Now we're subscribed. Sync the block we reverted above.
-	syncCtx, syncCancel = context.WithTimeout(context.Background(), 250*time.Millisecond)
-	require.NoError(t, syncer.Run(syncCtx))
-	syncCancel()
+	flagSets := [][]rpc.SimulationFlag{
+		{rpc.SkipFeeChargeFlag},
+		{rpc.SkipValidateFlag, rpc.SkipFeeChargeFlag},
+		{},
+	}
+	matrix, rpcErr := handler.EstimateFeeMatrix(txn, flagSets, rpc.BlockID{Latest: true})
+	require.Nil(t, rpcErr)
+	require.Len(t, matrix, len(flagSets))
 
-	// Receive a block header.
-	want = `{"jsonrpc":"2.0","method":"juno_subscribeNewHeads","params":{"result":{"block_hash":"0x4e1f77f39545afe866ac151ac908bd1a347a2a8a7d58bef1276db4f06fdf2f6","parent_hash":"0x2a70fb03fe363a2d6be843343a1d81ce6abeda1e9bd5cc6ad8fa9f45e30fdeb","block_number":2,"new_root":"0x3ceee867d50b5926bb88c0ec7e0b9c20ae6b537e74aac44b8fcf6bb6da138d9","timestamp":1637084470,"sequencer_address":"0x0","l1_gas_price":{"price_in_fri":"0x0","price_in_wei":"0x0"},"starknet_version":""},"subscription":%d}}`
-	firstWant = fmt.Sprintf(want, firstID)
-	_, firstGot, err = conn1.Read(ctx)
-	require.NoError(t, err)
-	require.Equal(t, firstWant, string(firstGot))
-	secondWant = fmt.Sprintf(want, secondID)
-	_, secondGot, err = conn2.Read(ctx)
-	require.NoError(t, err)
-	require.Equal(t, secondWant, string(secondGot))
+	t.Run("matches individual estimates", func(t *testing.T) {
+		for i, flags := range flagSets[:2] {
+			// EstimateFee always skips the fee charge, so only compare the flag sets that do the same.
+			estimates, rpcErr := handler.EstimateFee([]rpc.BroadcastedTransaction{txn}, flags, rpc.BlockID{Latest: true})
+			require.Nil(t, rpcErr)
+			require.Len(t, estimates, 1)
+			assert.Equal(t, estimates[0], matrix[i])
+		}
+	})
 
-	// Unsubscribe
-	unsubMsg := `{"jsonrpc":"2.0","id":1,"method":"juno_unsubscribe","params":[%d]}`
-	require.NoError(t, conn1.Write(ctx, websocket.MessageBinary, []byte(fmt.Sprintf(unsubMsg, firstID))))
-	require.NoError(t, conn2.Write(ctx, websocket.MessageBinary, []byte(fmt.Sprintf(unsubMsg, secondID))))
+	t.Run("flag sets are used as is", func(t *testing.T) {
+		assert.Equal(t, new(felt.Felt).SetUint64(90), matrix[0].OverallFee)
+		assert.Equal(t, new(felt.Felt).SetUint64(70), matrix[1].OverallFee)
+		assert.Equal(t, new(felt.Felt).SetUint64(100), matrix[2].OverallFee)
+	})
+
+	t.Run("block not found", func(t *testing.T) {
+		mockReader.EXPECT().StateAtBlockNumber(uint64(7)).Return(nil, nil, db.ErrKeyNotFound)
+		_, rpcErr := handler.EstimateFeeMatrix(txn, flagSets, rpc.BlockID{Number: 7})
+		assert.Equal(t, rpc.ErrBlockNotFound, rpcErr)
+	})
 }
 
-func TestTraceFallback(t *testing.T) {
+func TestMaxBatchSize(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	t.Cleanup(mockCtrl.Finish)
-	network := utils.Integration
-	client := feeder.NewTestClient(t, &network)
+
 	mockReader := mocks.NewMockReader(mockCtrl)
-	gateway := adaptfeeder.New(client)
+	handler := rpc.New(mockReader, nil, nil, "", utils.NewNopZapLogger()).WithMaxBatchSize(2)
 
-	mockReader.EXPECT().BlockByNumber(gomock.Any()).DoAndReturn(func(number uint64) (block *core.Block, err error) {
-		return gateway.BlockByNumber(context.Background(), number)
-	}).AnyTimes()
-	mockReader.EXPECT().L1Head().Return(nil, db.ErrKeyNotFound).AnyTimes()
+	// at the limit the request gets past the guard and fails on the missing block instead
+	atLimit := make([]rpc.BroadcastedTransaction, 2)
+	overLimit := make([]rpc.BroadcastedTransaction, 3)
+	batchErr := jsonrpc.Err(jsonrpc.InvalidParams, "batch size 3 exceeds the limit of 2")
 
-	tests := map[string]struct {
-		hash        string
-		blockNumber uint64
-		want        string
-	}{
-		"old block": {
-			hash:        "0x3ae41b0f023e53151b0c8ab8b9caafb7005d5f41c9ab260276d5bdc49726279",
-			blockNumber: 0,
-			want:        `[{"trace_root":{"type":"DEPLOY","constructor_invocation":{"contract_address":"0x7b196a359045d4d0c10f73bdf244a9e1205a615dbb754b8df40173364288534","calldata":["0x187d50a5cf3ebd6d4d6fa8e29e4cad0a237759c6416304a25c4ea792ed4bba4","0x42f5af30d6693674296ad87301935d0c159036c3b24af4042ff0270913bf6c6"],"caller_address":"0x0","result":[],"calls":[],"events":[],"messages":[],"execution_resources":{"steps":29}}},"transaction_hash":"0x3fa1bff0c86f34b2eb32c26d12208b6bdb4a5f6a434ac1d4f0e2d1db71bd711"},{"trace_root":{"type":"DEPLOY","constructor_invocation":{"contract_address":"0x64ed79a8ebe97485d3357bbfdf5f6bea0d9db3b5f1feb6e80d564a179122dc6","calldata":["0x5cedec15acd969b0fba39fec9e7d9bd4d0b33f100969ad3a4543039a6f696d4","0xce9801d27b02543f4d88b60aa456860f94ee9f612fc56464abfbdeedc1ab72"],"caller_address":"0x0","result":[],"calls":[],"events":[],"messages":[],"execution_resources":{"steps":29}}},"transaction_hash":"0x154c02cc3165cceadaa32e7238a67061b3a1eac414138c4ebe1408f37fd93eb"},{"trace_root":{"type":"INVOKE","execute_invocation":{"contract_address":"0x64ed79a8ebe97485d3357bbfdf5f6bea0d9db3b5f1feb6e80d564a179122dc6","calldata":["0x17d9c35a8b9a0d4512fa05eafec01c2758a7a5b7ec7b47408a24a4b33124d9b","0x2","0x7f800b5bf79637f8f83f47a8fc4d368b43695c781b22a899f11b5f2faba874a","0x3a7a40d383612b0ad167aec8d90fb07e576e017d07948f63ac318b52511ae93"],"caller_address":"0x0","result":[],"calls":[],"events":[],"messages":[],"execution_resources":{"steps":165,"memory_holes":22,"pedersen_builtin_applications":2,"range_check_builtin_applications":7}}},"transaction_hash":"0x7893675c16da857b7c4229cda449e08a4fe13b07ca817e79d1db02e8a046047"},{"trace_root":{"type":"INVOKE","execute_invocation":{"contract_address":"0x64ed79a8ebe97485d3357bbfdf5f6bea0d9db3b5f1feb6e80d564a179122dc6","calldata":["0x17d9c35a8b9a0d4512fa05eafec01c2758a7a5b7ec7b47408a24a4b33124d9b","0x2","0x7f800b5bf79637f8f83f47a8fc4d368b43695c781b22a899f11b5f2faba874a","0xf140b304e9266c72f1054116dd06d9c1c8e981db7bf34e3c6da99640e9a7c8"],"caller_address":"0x0","result":[],"calls":[],"events":[],"messages":[],"execution_resources":{"steps":165,"memory_holes":22,"pedersen_builtin_applications":2,"range_check_builtin_applications":7}}},"transaction_hash":"0x4a277d67e3f42c4a343854081d1e2e9e425f1323255e4486d2badb37a1d8630"}]`,
+	tests := map[string]func(txns []rpc.BroadcastedTransaction) *jsonrpc.Error{
+		"estimate fee range": func(txns []rpc.BroadcastedTransaction) *jsonrpc.Error {
+			_, rpcErr := handler.EstimateFeeRange(txns, nil, rpc.BlockID{Latest: true})
+			return rpcErr
 		},
-		"newer block": {
-			hash:        "0xe3828bd9154ab385e2cbb95b3b650365fb3c6a4321660d98ce8b0a9194f9a3",
-			blockNumber: 300000,
-			want:        `[{"trace_root":{"type":"INVOKE","validate_invocation":{"contract_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","entry_point_selector":"0x162da33a4585851fe8d3af3c2a9c60b557814e221e0d4f30ff0b2189d9c7775","calldata":["0x1","0x332299dc083f3778122e5b7762bc9d399da18fefe93769aee67bb49f51c8d2","0x2d7cf5d5a324a320f9f37804b1615a533fde487400b41af80f13f7ac5581325","0x0","0x4","0x4","0xaf35ee8ed700ff132c5d1d298a73becda25ccdf9","0x2","0x6cd852fe1b2bbd8587bb0aaeb09813436c57c8ce21e75651e317273a1f22228","0x58feb991988e53fffcba71f6df23c803fb062f1b3bab126d2c9ce574255b36e"],"caller_address":"0x0","class_hash":"0x646a72e2aab2fca75d713fbe4a58f2d12cbd64105621b89dc9ce7045b5bf02b","entry_point_type":"EXTERNAL","call_type":"CALL","result":[],"calls":[],"events":[],"messages":[],"execution_resources":{"steps":89,"range_check_builtin_applications":2,"ecdsa_builtin_applications":1}},"execute_invocation":{"contract_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","entry_point_selector":"0x15d40a3d6ca2ac30f4031e42be28da9b056fef9bb7357ac5e85627ee876e5ad","calldata":["0x1","0x332299dc083f3778122e5b7762bc9d399da18fefe93769aee67bb49f51c8d2","0x2d7cf5d5a324a320f9f37804b1615a533fde487400b41af80f13f7ac5581325","0x0","0x4","0x4","0xaf35ee8ed700ff132c5d1d298a73becda25ccdf9","0x2","0x6cd852fe1b2bbd8587bb0aaeb09813436c57c8ce21e75651e317273a1f22228","0x58feb991988e53fffcba71f6df23c803fb062f1b3bab126d2c9ce574255b36e"],"caller_address":"0x0","class_hash":"0x646a72e2aab2fca75d713fbe4a58f2d12cbd64105621b89dc9ce7045b5bf02b","entry_point_type":"EXTERNAL","call_type":"CALL","result":[],"calls":[{"contract_address":"0x332299dc083f3778122e5b7762bc9d399da18fefe93769aee67bb49f51c8d2","entry_point_selector":"0x2d7cf5d5a324a320f9f37804b1615a533fde487400b41af80f13f7ac5581325","calldata":["0xaf35ee8ed700ff132c5d1d298a73becda25ccdf9","0x2","0x6cd852fe1b2bbd8587bb0aaeb09813436c57c8ce21e75651e317273a1f22228","0x58feb991988e53fffcba71f6df23c803fb062f1b3bab126d2c9ce574255b36e"],"caller_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","class_hash":"0x165e7db96ab97a63c621229617a6d49633737238673477a54720e4c952f2c7e","entry_point_type":"EXTERNAL","call_type":"CALL","result":[],"calls":[],"events":[],"messages":[{"order":0,"to_address":"0xAf35eE8eD700ff132C5d1d298A73BECdA25ccDF9","payload":["0x6cd852fe1b2bbd8587bb0aaeb09813436c57c8ce21e75651e317273a1f22228","0x58feb991988e53fffcba71f6df23c803fb062f1b3bab126d2c9ce574255b36e"]}],"execution_resources":{"steps":233,"memory_holes":1,"range_check_builtin_applications":5}}],"events":[],"messages":[],"execution_resources":{"steps":374,"memory_holes":4,"range_check_builtin_applications":7}},"fee_transfer_invocation":{"contract_address":"0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7","entry_point_selector":"0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e","calldata":["0x1176a1bd84444c89232ec27754698e5d2e7e1a7f1539f12027f28b23ec9f3d8","0x127089df3a1984","0x0"],"caller_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","class_hash":"0xd0e183745e9dae3e4e78a8ffedcce0903fc4900beace4e0abf192d4c202da3","entry_point_type":"EXTERNAL","call_type":"CALL","result":["0x1"],"calls":[{"contract_address":"0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7","entry_point_selector":"0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e","calldata":["0x1176a1bd84444c89232ec27754698e5d2e7e1a7f1539f12027f28b23ec9f3d8","0x127089df3a1984","0x0"],"caller_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","class_hash":"0x28d7d394810ad8c52741ad8f7564717fd02c10ced68657a81d0b6710ce22079","entry_point_type":"EXTERNAL","call_type":"DELEGATE","result":["0x1"],"calls":[],"events":[],"messages":[],"execution_resources":{"steps":488,"memory_holes":40,"pedersen_builtin_applications":4,"range_check_builtin_applications":21}}],"events":[],"messages":[],"execution_resources":{"steps":548,"memory_holes":40,"pedersen_builtin_applications":4,"range_check_builtin_applications":21}}},"transaction_hash":"0x2a648ab1aa6847eb38507fc842e050f256562bf87b26083c332f3f21318c2c3"},{"trace_root":{"type":"INVOKE","validate_invocation":{"contract_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","entry_point_selector":"0x162da33a4585851fe8d3af3c2a9c60b557814e221e0d4f30ff0b2189d9c7775","calldata":["0x1","0x5f9211b05c9609d54a8bf5f9cfa4e2cd5a3cab3b5d79682c585575495a15dd1","0x317eb442b72a9fae758d4fb26830ed0d9f31c8e7da4dbff4e8c59ea6a158e7f","0x0","0x4","0x4","0x447379c077035ef4f442411d0407ce9aa66c558f0060137f6455f4f230eabeb","0x2","0x6811b7755a7dd0ec1fb6f51a883e3f255368e2dfd497b5f6480c00cf9cd5a2e","0x23b9e26720dd7aaf98c7cea56499f48f75dc1d4123f7e2d6c23bfc4d5f4a336"],"caller_address":"0x0","class_hash":"0x646a72e2aab2fca75d713fbe4a58f2d12cbd64105621b89dc9ce7045b5bf02b","entry_point_type":"EXTERNAL","call_type":"CALL","result":[],"calls":[],"events":[],"messages":[],"execution_resources":{"steps":89,"range_check_builtin_applications":2,"ecdsa_builtin_applications":1}},"execute_invocation":{"contract_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","entry_point_selector":"0x15d40a3d6ca2ac30f4031e42be28da9b056fef9bb7357ac5e85627ee876e5ad","calldata":["0x1","0x5f9211b05c9609d54a8bf5f9cfa4e2cd5a3cab3b5d79682c585575495a15dd1","0x317eb442b72a9fae758d4fb26830ed0d9f31c8e7da4dbff4e8c59ea6a158e7f","0x0","0x4","0x4","0x447379c077035ef4f442411d0407ce9aa66c558f0060137f6455f4f230eabeb","0x2","0x6811b7755a7dd0ec1fb6f51a883e3f255368e2dfd497b5f6480c00cf9cd5a2e","0x23b9e26720dd7aaf98c7cea56499f48f75dc1d4123f7e2d6c23bfc4d5f4a336"],"caller_address":"0x0","class_hash":"0x646a72e2aab2fca75d713fbe4a58f2d12cbd64105621b89dc9ce7045b5bf02b","entry_point_type":"EXTERNAL","call_type":"CALL","result":[],"calls":[{"contract_address":"0x5f9211b05c9609d54a8bf5f9cfa4e2cd5a3cab3b5d79682c585575495a15dd1","entry_point_selector":"0x317eb442b72a9fae758d4fb26830ed0d9f31c8e7da4dbff4e8c59ea6a158e7f","calldata":["0x447379c077035ef4f442411d0407ce9aa66c558f0060137f6455f4f230eabeb","0x2","0x6811b7755a7dd0ec1fb6f51a883e3f255368e2dfd497b5f6480c00cf9cd5a2e","0x23b9e26720dd7aaf98c7cea56499f48f75dc1d4123f7e2d6c23bfc4d5f4a336"],"caller_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","class_hash":"0x13abfd2f333f9c69f690f1569140cdae25f6f66e3f371c9cbb998b65f664a85","entry_point_type":"EXTERNAL","call_type":"CALL","result":[],"calls":[],"events":[],"messages":[],"execution_resources":{"steps":166,"memory_holes":22,"pedersen_builtin_applications":2,"range_check_builtin_applications":7}}],"events":[],"messages":[],"execution_resources":{"steps":307,"memory_holes":25,"pedersen_builtin_applications":2,"range_check_builtin_applications":9}},"fee_transfer_invocation":{"contract_address":"0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7","entry_point_selector":"0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e","calldata":["0x1176a1bd84444c89232ec27754698e5d2e7e1a7f1539f12027f28b23ec9f3d8","0x3b2d25cd7bccc","0x0"],"caller_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","class_hash":"0xd0e183745e9dae3e4e78a8ffedcce0903fc4900beace4e0abf192d4c202da3","entry_point_type":"EXTERNAL","call_type":"CALL","result":["0x1"],"calls":[{"contract_address":"0x49d36570d4e46f48e99674bd3fcc84644ddd6b96f7c741b1562b82f9e004dc7","entry_point_selector":"0x83afd3f4caedc6eebf44246fe54e38c95e3179a5ec9ea81740eca5b482d12e","calldata":["0x1176a1bd84444c89232ec27754698e5d2e7e1a7f1539f12027f28b23ec9f3d8","0x3b2d25cd7bccc","0x0"],"caller_address":"0x58b7ee817bd2978c7657d05d3131e83e301ed1aa79d5ad16f01925fd52d1da7","class_hash":"0x28d7d394810ad8c52741ad8f7564717fd02c10ced68657a81d0b6710ce22079","entry_point_type":"EXTERNAL","call_type":"DELEGATE","result":["0x1"],"calls":[],"events":[],"messages":[],"execution_resources":{"steps":488,"memory_holes":40,"pedersen_builtin_applications":4,"range_check_builtin_applications":21}}],"events":[],"messages":[],"execution_resources":{"steps":548,"memory_holes":40,"pedersen_builtin_applications":4,"range_check_builtin_applications":21}}},"transaction_hash":"0xbc984e8e1fe594dd518a3a51db4f338437a5d2fbdda772d4426b532a67ffff"}]`,
+		"estimate bundle fee": func(txns []rpc.BroadcastedTransaction) *jsonrpc.Error {
+			_, rpcErr := handler.EstimateBundleFee(txns, rpc.BlockID{Latest: true})
+			return rpcErr
+		},
+		"estimate fee with revert traces": func(txns []rpc.BroadcastedTransaction) *jsonrpc.Error {
+			_, rpcErr := handler.EstimateFeeWithRevertTraces(txns, nil, rpc.BlockID{Latest: true})
+			return rpcErr
+		},
+		"simulate with state diff": func(txns []rpc.BroadcastedTransaction) *jsonrpc.Error {
+			_, rpcErr := handler.SimulateWithStateDiff(rpc.BlockID{Latest: true}, txns, nil)
+			return rpcErr
+		},
+		"simulate with fee charge": func(txns []rpc.BroadcastedTransaction) *jsonrpc.Error {
+			_, rpcErr := handler.SimulateWithFeeCharge(rpc.BlockID{Latest: true}, txns, nil)
+			return rpcErr
+		},
+		"estimate fee matrix": func(txns []rpc.BroadcastedTransaction) *jsonrpc.Error {
+			_, rpcErr := handler.EstimateFeeMatrix(rpc.BroadcastedTransaction{}, make([][]rpc.SimulationFlag, len(txns)),
+				rpc.BlockID{Latest: true})
+			return rpcErr
+		},
+		"estimate fee parallel": func(txns []rpc.BroadcastedTransaction) *jsonrpc.Error {
+			_, rpcErr := handler.EstimateFeeParallel(txns, nil, rpc.BlockID{Latest: true})
+			return rpcErr
+		},
+		"estimate fee partial": func(txns []rpc.BroadcastedTransaction) *jsonrpc.Error {
+			_, rpcErr := handler.EstimateFeePartial(txns, nil, rpc.BlockID{Latest: true})
+			return rpcErr
+		},
+		"estimate message fee many": func(txns []rpc.BroadcastedTransaction) *jsonrpc.Error {
+			msgs := make([]rpc.MsgFromL1, len(txns))
+			for i := range msgs {
+				msgs[i] = rpc.MsgFromL1{
+					From:     common.HexToAddress("0xDEADBEEF"),
+					To:       *new(felt.Felt).SetUint64(1337),
+					Selector: *new(felt.Felt).SetUint64(44),
+				}
+			}
+			_, rpcErr := handler.EstimateMessageFeeMany(msgs, rpc.BlockID{Latest: true})
+			return rpcErr
+		},
+		"call many": func(txns []rpc.BroadcastedTransaction) *jsonrpc.Error {
+			_, rpcErr := handler.CallMany(make([]rpc.FunctionCall, len(txns)), rpc.BlockID{Latest: true})
+			return rpcErr
+		},
+		"storage at many": func(txns []rpc.BroadcastedTransaction) *jsonrpc.Error {
+			_, rpcErr := handler.StorageAtMany(felt.Zero, make([]felt.Felt, len(txns)), rpc.BlockID{Latest: true})
+			return rpcErr
 		},
 	}
 
-	for description, test := range tests {
-		t.Run(description, func(t *testing.T) {
-			mockReader.EXPECT().BlockByHash(utils.HexToFelt(t, test.hash)).DoAndReturn(func(_ *felt.Felt) (block *core.Block, err error) {
-				return mockReader.BlockByNumber(test.blockNumber)
-			}).Times(2)
-			handler := rpc.New(mockReader, nil, nil, "", nil)
-			_, jErr := handler.TraceBlockTransactions(context.Background(), rpc.BlockID{Number: test.blockNumber})
-			require.Equal(t, rpc.ErrInternal.Code, jErr.Code)
+	for name, call := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockReader.EXPECT().HeadState().Return(nil, nil, db.ErrKeyNotFound)
+			assert.Equal(t, rpc.ErrBlockNotFound, call(atLimit))
+			assert.Equal(t, batchErr, call(overLimit))
+		})
+	}
 
-			handler = handler.WithFeeder(client)
-			trace, jErr := handler.TraceBlockTransactions(context.Background(), rpc.BlockID{Number: test.blockNumber})
-			require.Nil(t, jErr)
-			jsonStr, err := json.Marshal(trace)
-			require.NoError(t, err)
-			assert.JSONEq(t, test.want, string(jsonStr))
+	// the spec methods define their own errors, so they are not limited
+	specMethods := map[string]func(txns []rpc.BroadcastedTransaction) *jsonrpc.Error{
+		"estimate fee": func(txns []rpc.BroadcastedTransaction) *jsonrpc.Error {
+			_, rpcErr := handler.EstimateFee(txns, nil, rpc.BlockID{Latest: true})
+			return rpcErr
+		},
+		"estimate fee v0.6": func(txns []rpc.BroadcastedTransaction) *jsonrpc.Error {
+			_, rpcErr := handler.EstimateFeeV0_6(txns, nil, rpc.BlockID{Latest: true})
+			return rpcErr
+		},
+		"simulate transactions": func(txns []rpc.BroadcastedTransaction) *jsonrpc.Error {
+			_, rpcErr := handler.SimulateTransactions(rpc.BlockID{Latest: true}, txns, nil)
+			return rpcErr
+		},
+		"simulate transactions v0.6": func(txns []rpc.BroadcastedTransaction) *jsonrpc.Error {
+			_, rpcErr := handler.SimulateTransactionsV0_6(rpc.BlockID{Latest: true}, txns, nil)
+			return rpcErr
+		},
+	}
+
+	for name, call := range specMethods {
+		t.Run(name+" is not limited", func(t *testing.T) {
+			mockReader.EXPECT().HeadState().Return(nil, nil, db.ErrKeyNotFound)
+			assert.Equal(t, rpc.ErrBlockNotFound, call(overLimit))
 		})
 	}
 }
 
-func TestThrottledVMError(t *testing.T) {
+func TestSimulateWithEnv(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	t.Cleanup(mockCtrl.Finish)
+
+	network := utils.Mainnet
 	mockReader := mocks.NewMockReader(mockCtrl)
-	mockReader.EXPECT().Network().Return(&utils.Mainnet).AnyTimes()
+	mockReader.EXPECT().Network().Return(&network).AnyTimes()
 	mockVM := mocks.NewMockVM(mockCtrl)
-
-	throttledVM := node.NewThrottledVM(mockVM, 0, 0)
-	handler := rpc.New(mockReader, nil, throttledVM, "", nil)
 	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
 
-	throttledErr := "VM throughput limit reached"
-	t.Run("call", func(t *testing.T) {
-		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
-		mockReader.EXPECT().HeadsHeader().Return(new(core.Header), nil)
-		mockState.EXPECT().ContractClassHash(&felt.Zero).Return(new(felt.Felt), nil)
-		_, rpcErr := handler.Call(rpc.FunctionCall{}, rpc.BlockID{Latest: true})
-		assert.Equal(t, throttledErr, rpcErr.Data)
-	})
+	header := &core.Header{
+		Number:           15,
+		Timestamp:        100,
+		GasPrice:         new(felt.Felt).SetUint64(10),
+		SequencerAddress: new(felt.Felt).SetUint64(0x5e9),
+	}
+	revealedHeader := &core.Header{Number: 5, Hash: new(felt.Felt).SetUint64(0xabc)}
+	txn := rpc.BroadcastedTransaction{
+		Transaction: rpc.Transaction{
+			Type:          rpc.TxnInvoke,
+			Version:       new(felt.Felt).SetUint64(1),
+			Nonce:         &felt.Zero,
+			MaxFee:        &felt.Zero,
+			SenderAddress: new(felt.Felt).SetUint64(0xabc),
+			Signature:     &[]*felt.Felt{},
+			CallData:      &[]*felt.Felt{},
+		},
+	}
 
-	t.Run("simulate", func(t *testing.T) {
+	// the VM charges 100 gas at whatever gas price the block environment has
+	simulate := func(t *testing.T, overrides *rpc.BlockOverrides) (*vm.BlockInfo, rpc.FeeEstimate) {
 		mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil)
-		mockReader.EXPECT().HeadsHeader().Return(&core.Header{}, nil)
-		_, rpcErr := handler.SimulateTransactions(rpc.BlockID{Latest: true}, []rpc.BroadcastedTransaction{}, []rpc.SimulationFlag{rpc.SkipFeeChargeFlag})
-		assert.Equal(t, throttledErr, rpcErr.Data)
-	})
+		mockReader.EXPECT().HeadsHeader().Return(header, nil)
+		mockReader.EXPECT().BlockHeaderByNumber(revealedHeader.Number).Return(revealedHeader, nil)
+
+		var seenBlockInfo *vm.BlockInfo
+		mockVM.EXPECT().Execute(gomock.Len(1), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+			false, false, false, true).DoAndReturn(
+			func(_ []core.Transaction, _ []core.Class, _ []*felt.Felt, blockInfo *vm.BlockInfo, _ core.StateReader,
+				_ *utils.Network, _, _, _, _ bool,
+			) ([]*felt.Felt, []*felt.Felt, []vm.TransactionTrace, error) {
+				seenBlockInfo = blockInfo
+				fee := new(felt.Felt).Mul(new(felt.Felt).SetUint64(100), blockInfo.Header.GasPrice)
+				return []*felt.Felt{fee}, []*felt.Felt{&felt.Zero}, []vm.TransactionTrace{{
+					Type: vm.TxnInvoke,
+					ExecuteInvocation: &vm.ExecuteInvocation{
+						FunctionInvocation: &vm.FunctionInvocation{ExecutionResources: &vm.ExecutionResources{}},
+					},
+				}}, nil
+			})
 
-	t.Run("trace", func(t *testing.T) {
-		blockHash := utils.HexToFelt(t, "0x0001")
-		header := &core.Header{
-			// hash is not set because it's pending block
-			ParentHash:      utils.HexToFelt(t, "0x0C3"),
-			Number:          0,
-			GasPrice:        utils.HexToFelt(t, "0x777"),
-			ProtocolVersion: "99.12.3",
-		}
-		l1Tx := &core.L1HandlerTransaction{
-			TransactionHash: utils.HexToFelt(t, "0x000000C"),
-		}
-		declaredClass := &core.DeclaredClass{
-			At:    3002,
-			Class: &core.Cairo1Class{},
-		}
-		declareTx := &core.DeclareTransaction{
-			TransactionHash: utils.HexToFelt(t, "0x000000001"),
-			ClassHash:       utils.HexToFelt(t, "0x00000BC00"),
-		}
-		block := &core.Block{
-			Header:       header,
-			Transactions: []core.Transaction{l1Tx, declareTx},
-		}
+		result, rpcErr := handler.SimulateWithEnv(rpc.BlockID{Latest: true}, []rpc.BroadcastedTransaction{txn}, nil,
+			overrides)
+		require.Nil(t, rpcErr)
+		require.Len(t, result, 1)
+		return seenBlockInfo, result[0].FeeEstimation
+	}
 
-		mockReader.EXPECT().BlockByHash(blockHash).Return(block, nil)
-		state := mocks.NewMockStateHistoryReader(mockCtrl)
-		mockReader.EXPECT().StateAtBlockHash(header.ParentHash).Return(state, nopCloser, nil)
-		headState := mocks.NewMockStateHistoryReader(mockCtrl)
-		headState.EXPECT().Class(declareTx.ClassHash).Return(declaredClass, nil)
-		mockReader.EXPECT().PendingState().Return(headState, nopCloser, nil)
-		_, rpcErr := handler.TraceBlockTransactions(context.Background(), rpc.BlockID{Hash: blockHash})
-		assert.Equal(t, throttledErr, rpcErr.Data)
+	t.Run("without overrides", func(t *testing.T) {
+		blockInfo, estimate := simulate(t, nil)
+		assert.Equal(t, header, blockInfo.Header)
+		assert.Equal(t, new(felt.Felt).SetUint64(1000), estimate.OverallFee)
 	})
-}
 
-func TestSpecVersion(t *testing.T) {
-	handler := rpc.New(nil, nil, nil, "", nil)
-	version, rpcErr := handler.SpecVersion()
-	require.Nil(t, rpcErr)
-	require.Equal(t, "0.7.0", version)
+	t.Run("with overrides", func(t *testing.T) {
+		timestamp := uint64(200)
+		sequencer := new(felt.Felt).SetUint64(0x5ea)
+		blockInfo, estimate := simulate(t, &rpc.BlockOverrides{
+			Timestamp:        &timestamp,
+			L1GasPrice:       &rpc.ResourcePrice{InWei: new(felt.Felt).SetUint64(30)},
+			SequencerAddress: sequencer,
+		})
 
-	legacyVersion, rpcErr := handler.SpecVersionV0_6()
-	require.Nil(t, rpcErr)
-	require.Equal(t, "0.6.0", legacyVersion)
+		assert.Equal(t, header.Number, blockInfo.Header.Number)
+		assert.Equal(t, revealedHeader.Hash, blockInfo.BlockHashToBeRevealed)
+		assert.Equal(t, timestamp, blockInfo.Header.Timestamp)
+		assert.Equal(t, sequencer, blockInfo.Header.SequencerAddress)
+		assert.Equal(t, new(felt.Felt).SetUint64(30), estimate.GasPrice)
+		assert.Equal(t, new(felt.Felt).SetUint64(100), estimate.GasConsumed)
+		assert.Equal(t, new(felt.Felt).SetUint64(3000), estimate.OverallFee)
+
+		// the real header is left untouched
+		assert.Equal(t, uint64(100), header.Timestamp)
+		assert.Equal(t, new(felt.Felt).SetUint64(10), header.GasPrice)
+	})
 }
 
-func TestEstimateFee(t *testing.T) {
-	t.Skip()
+// invokeFrom builds an invoke transaction sent by the given address, for tests that tell transactions apart
+// by their sender
+func invokeFrom(sender uint64) rpc.BroadcastedTransaction {
+	return rpc.BroadcastedTransaction{
+		Transaction: rpc.Transaction{
+			Type:          rpc.TxnInvoke,
+			Version:       new(felt.Felt).SetUint64(1),
+			Nonce:         &felt.Zero,
+			MaxFee:        &felt.Zero,
+			SenderAddress: new(felt.Felt).SetUint64(sender),
+			Signature:     &[]*felt.Felt{},
+			CallData:      &[]*felt.Felt{},
+		},
+	}
+}
 
+func TestEstimateFeeParallel(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
-	defer mockCtrl.Finish()
+	t.Cleanup(mockCtrl.Finish)
 
 	network := utils.Mainnet
-
 	mockReader := mocks.NewMockReader(mockCtrl)
 	mockReader.EXPECT().Network().Return(&network).AnyTimes()
 	mockVM := mocks.NewMockVM(mockCtrl)
-	log := utils.NewNopZapLogger()
-	handler := rpc.New(mockReader, nil, mockVM, "", log)
-
 	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
+
+	header := &core.Header{Hash: new(felt.Felt).SetUint64(0xb10c), GasPrice: new(felt.Felt).SetUint64(10)}
+	mockReader.EXPECT().HeadsHeader().Return(header, nil).AnyTimes()
 	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil).AnyTimes()
-	mockReader.EXPECT().HeadsHeader().Return(&core.Header{}, nil).AnyTimes()
+	mockReader.EXPECT().BlockHeaderByHash(header.Hash).Return(header, nil).AnyTimes()
+	mockReader.EXPECT().StateAtBlockHash(header.Hash).Return(mockState, nopCloser, nil).AnyTimes()
+
+	txns := []rpc.BroadcastedTransaction{invokeFrom(1), invokeFrom(2), invokeFrom(3), invokeFrom(4)}
+
+	// every transaction costs 100 gas per unit of its sender address, calls into the contract and writes to the
+	// storage of the contract that touched returns for its sender, if any
+	var executions atomic.Int32
+	execute := func(touched func(sender *felt.Felt) (called, written *felt.Felt)) func([]core.Transaction, []core.Class,
+		[]*felt.Felt, *vm.BlockInfo, core.StateReader, *utils.Network, bool, bool, bool, bool,
+	) ([]*felt.Felt, []*felt.Felt, []vm.TransactionTrace, error) {
+		return func(txns []core.Transaction, _ []core.Class, _ []*felt.Felt, blockInfo *vm.BlockInfo, _ core.StateReader,
+			_ *utils.Network, _, _, _, _ bool,
+		) ([]*felt.Felt, []*felt.Felt, []vm.TransactionTrace, error) {
+			executions.Add(1)
+			var fees, dataGas []*felt.Felt
+			var traces []vm.TransactionTrace
+			for _, txn := range txns {
+				sender := txn.(*core.InvokeTransaction).SenderAddress
+				called, written := touched(sender)
+				gas := new(felt.Felt).Mul(sender, new(felt.Felt).SetUint64(100))
+				fees = append(fees, gas.Mul(gas, blockInfo.Header.GasPrice))
+				dataGas = append(dataGas, &felt.Zero)
+				stateDiff := &vm.StateDiff{}
+				if written != nil {
+					stateDiff.StorageDiffs = []vm.StorageDiff{{Address: *written}}
+				}
+				traces = append(traces, vm.TransactionTrace{
+					Type: vm.TxnInvoke,
+					ExecuteInvocation: &vm.ExecuteInvocation{
+						FunctionInvocation: &vm.FunctionInvocation{
+							ContractAddress:    *sender,
+							ExecutionResources: &vm.ExecutionResources{},
+							Calls:              []vm.FunctionInvocation{{ContractAddress: *called}},
+						},
+					},
+					StateDiff: stateDiff,
+				})
+			}
+			return fees, dataGas, traces, nil
+		}
+	}
+	ownContract := func(sender *felt.Felt) (*felt.Felt, *felt.Felt) {
+		return sender, sender
+	}
 
-	blockInfo := vm.BlockInfo{Header: &core.Header{}}
-	t.Run("ok with zero values", func(t *testing.T) {
-		mockVM.EXPECT().Execute(nil, nil, []*felt.Felt{}, &blockInfo, mockState, &network, true, true, false, false).
-			Return([]*felt.Felt{}, []vm.TransactionTrace{}, nil)
+	sequential := func(t *testing.T) []rpc.FeeEstimate {
+		mockVM.EXPECT().Execute(gomock.Len(len(txns)), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+			true, false, true, true).DoAndReturn(execute(ownContract))
 
-		_, err := handler.EstimateFee([]rpc.BroadcastedTransaction{}, []rpc.SimulationFlag{}, rpc.BlockID{Latest: true})
-		require.Nil(t, err)
+		estimates, rpcErr := handler.EstimateFee(txns, nil, rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+		return estimates
+	}
+
+	t.Run("independent transactions run concurrently", func(t *testing.T) {
+		expected := sequential(t)
+
+		executions.Store(0)
+		mockVM.EXPECT().Execute(gomock.Len(1), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+			true, false, true, true).DoAndReturn(execute(ownContract)).Times(len(txns))
+
+		estimates, rpcErr := handler.EstimateFeeParallel(txns, nil, rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+		assert.Equal(t, expected, estimates)
+		assert.Equal(t, int32(len(txns)), executions.Load())
 	})
 
-	t.Run("ok with zero values, skip validate", func(t *testing.T) {
-		mockVM.EXPECT().Execute(nil, nil, []*felt.Felt{}, &blockInfo, mockState, &network, true, true, false, false).
-			Return([]*felt.Felt{}, []vm.TransactionTrace{}, nil)
+	t.Run("shared sender is executed sequentially", func(t *testing.T) {
+		executions.Store(0)
+		sharedSenderTxns := []rpc.BroadcastedTransaction{invokeFrom(1), invokeFrom(1)}
+		mockVM.EXPECT().Execute(gomock.Len(2), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+			true, false, true, true).DoAndReturn(execute(ownContract))
 
-		_, err := handler.EstimateFee([]rpc.BroadcastedTransaction{}, []rpc.SimulationFlag{rpc.SkipValidateFlag}, rpc.BlockID{Latest: true})
-		require.Nil(t, err)
+		estimates, rpcErr := handler.EstimateFeeParallel(sharedSenderTxns, nil, rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+		assert.Len(t, estimates, 2)
+		assert.Equal(t, int32(1), executions.Load())
 	})
 
-	t.Run("transaction execution error", func(t *testing.T) {
-		mockVM.EXPECT().Execute(nil, nil, []*felt.Felt{}, &blockInfo, mockState, &network, true, true, false, false).
-			Return(nil, nil, vm.TransactionExecutionError{
-				Index: 44,
-				Cause: errors.New("oops"),
-			})
+	t.Run("writes to the same contract fall back to sequential execution", func(t *testing.T) {
+		executions.Store(0)
+		token := new(felt.Felt).SetUint64(0x70c)
+		writeToken := func(*felt.Felt) (*felt.Felt, *felt.Felt) {
+			return token, token
+		}
+		mockVM.EXPECT().Execute(gomock.Len(1), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+			true, false, true, true).DoAndReturn(execute(writeToken)).Times(len(txns))
+		mockVM.EXPECT().Execute(gomock.Len(len(txns)), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+			true, false, true, true).DoAndReturn(execute(writeToken))
 
-		_, err := handler.EstimateFee([]rpc.BroadcastedTransaction{}, []rpc.SimulationFlag{rpc.SkipValidateFlag}, rpc.BlockID{Latest: true})
-		require.Equal(t, rpc.ErrTransactionExecutionError.CloneWithData(rpc.TransactionExecutionErrorData{
-			TransactionIndex: 44,
-			ExecutionError:   "oops",
-		}), err)
+		estimates, rpcErr := handler.EstimateFeeParallel(txns, nil, rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+		assert.Len(t, estimates, len(txns))
+		assert.Equal(t, int32(len(txns)+1), executions.Load())
+	})
 
-		mockVM.EXPECT().Execute(nil, nil, []*felt.Felt{}, &blockInfo, mockState, &network, false, true, true, false).
-			Return(nil, nil, vm.TransactionExecutionError{
-				Index: 44,
-				Cause: errors.New("oops"),
+	t.Run("reads of a written contract fall back to sequential execution", func(t *testing.T) {
+		executions.Store(0)
+		// only the first transaction writes to token, the others just call into it and may read the write
+		token := new(felt.Felt).SetUint64(0x70c)
+		readAfterWrite := func(sender *felt.Felt) (*felt.Felt, *felt.Felt) {
+			if sender.Equal(txns[0].SenderAddress) {
+				return token, token
+			}
+			return token, nil
+		}
+		mockVM.EXPECT().Execute(gomock.Len(1), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+			true, false, true, true).DoAndReturn(execute(readAfterWrite)).Times(len(txns))
+		mockVM.EXPECT().Execute(gomock.Len(len(txns)), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+			true, false, true, true).DoAndReturn(execute(readAfterWrite))
+
+		estimates, rpcErr := handler.EstimateFeeParallel(txns, nil, rpc.BlockID{Latest: true})
+		require.Nil(t, rpcErr)
+		assert.Len(t, estimates, len(txns))
+		assert.Equal(t, int32(len(txns)+1), executions.Load())
+	})
+}
+
+func TestEstimateFeePartial(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+
+	network := utils.Mainnet
+	mockReader := mocks.NewMockReader(mockCtrl)
+	mockReader.EXPECT().Network().Return(&network).AnyTimes()
+	mockVM := mocks.NewMockVM(mockCtrl)
+	mockState := mocks.NewMockStateHistoryReader(mockCtrl)
+	handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
+	mockReader.EXPECT().HeadsHeader().Return(&core.Header{GasPrice: new(felt.Felt).SetUint64(10)}, nil).AnyTimes()
+	mockReader.EXPECT().HeadState().Return(mockState, nopCloser, nil).AnyTimes()
+
+	failingSenders := []uint64{2, 4}
+	token := new(felt.Felt).SetUint64(0x70c)
+
+	// every transaction costs 100 gas per unit of its sender address and records its sender in the storage of
+	// token, the ones from failingSenders fail. Each run also checks that the transactions estimated before it
+	// are visible in the state it runs on.
+	executions := make(map[uint64]int)
+	mockVM.EXPECT().Execute(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), &network,
+		true, false, true, true).DoAndReturn(func(txns []core.Transaction, _ []core.Class, _ []*felt.Felt,
+		blockInfo *vm.BlockInfo, state core.StateReader, _ *utils.Network, _, _, _, _ bool,
+	) ([]*felt.Felt, []*felt.Felt, []vm.TransactionTrace, error) {
+		var fees, dataGas []*felt.Felt
+		var traces []vm.TransactionTrace
+		for i, txn := range txns {
+			sender := txn.(*core.InvokeTransaction).SenderAddress
+			executions[sender.Uint64()]++
+			if slices.Contains(failingSenders, sender.Uint64()) {
+				return nil, nil, nil, vm.TransactionExecutionError{Index: uint64(i), Cause: errors.New("validation failed")}
+			}
+			if state != mockState {
+				recorded, err := state.ContractStorage(token, new(felt.Felt).SetUint64(1))
+				require.NoError(t, err)
+				require.Equal(t, new(felt.Felt).SetUint64(1), recorded)
+			}
+
+			gas := new(felt.Felt).Mul(sender, new(felt.Felt).SetUint64(100))
+			fees = append(fees, gas.Mul(gas, blockInfo.Header.GasPrice))
+			dataGas = append(dataGas, &felt.Zero)
+			traces = append(traces, vm.TransactionTrace{
+				Type: vm.TxnInvoke,
+				ExecuteInvocation: &vm.ExecuteInvocation{
+					FunctionInvocation: &vm.FunctionInvocation{ExecutionResources: &vm.ExecutionResources{}},
+				},
+				StateDiff: &vm.StateDiff{StorageDiffs: []vm.StorageDiff{{
+					Address:        *token,
+					StorageEntries: []vm.Entry{{Key: *sender, Value: *sender}},
+				}}},
 			})
+		}
+		return fees, dataGas, traces, nil
+	}).AnyTimes()
+
+	txns := []rpc.BroadcastedTransaction{invokeFrom(1), invokeFrom(2), invokeFrom(3), invokeFrom(4), invokeFrom(5)}
+
+	// the strict variant fails as a whole
+	_, rpcErr := handler.EstimateFee(txns, nil, rpc.BlockID{Latest: true})
+	require.Equal(t, rpc.ErrTransactionExecutionError.Code, rpcErr.Code)
+
+	// the failing transactions are dropped and the others are estimated as if they had never been sent
+	clear(executions)
+	results, rpcErr := handler.EstimateFeePartial(txns, nil, rpc.BlockID{Latest: true})
+	require.Nil(t, rpcErr)
+	require.Len(t, results, len(txns))
+
+	for _, i := range []int{0, 2, 4} {
+		assert.Nil(t, results[i].Error, i)
+		assert.Equal(t, new(felt.Felt).SetUint64(uint64(i+1)*1000), results[i].FeeEstimate.OverallFee, i)
+	}
+	for _, i := range []int{1, 3} {
+		assert.Nil(t, results[i].FeeEstimate, i)
+		assert.Equal(t, rpc.ErrTransactionExecutionError.CloneWithData(rpc.TransactionExecutionErrorData{
+			TransactionIndex: uint64(i),
+			ExecutionError:   "validation failed",
+		}), results[i].Error, i)
+	}
+
+	// a transaction before a failing one runs once more to keep its changes, the others run once
+	for sender, count := range executions {
+		assert.LessOrEqual(t, count, 2, sender)
+	}
+
+	t.Run("errors other than execution errors fail the batch", func(t *testing.T) {
+		mockVM := mocks.NewMockVM(mockCtrl)
+		handler := rpc.New(mockReader, nil, mockVM, "", utils.NewNopZapLogger())
+		mockVM.EXPECT().Execute(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), mockState, &network,
+			true, false, true, true).Return(nil, nil, nil, errors.New("oops"))
+
+		results, rpcErr := handler.EstimateFeePartial(txns, nil, rpc.BlockID{Latest: true})
+		assert.Nil(t, results)
+		assert.Equal(t, rpc.ErrUnexpectedError.CloneWithData("oops"), rpcErr)
 	})
 }
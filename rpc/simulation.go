@@ -3,7 +3,10 @@ package rpc
 import (
 	"fmt"
 
+	"github.com/NethermindEth/juno/blockchain"
+	"github.com/NethermindEth/juno/core"
 	"github.com/NethermindEth/juno/core/felt"
+	"github.com/NethermindEth/juno/utils"
 	"github.com/NethermindEth/juno/vm"
 )
 
@@ -32,7 +35,192 @@ type SimulatedTransaction struct {
 	FeeEstimation    FeeEstimate          `json:"fee_estimation,omitempty"`
 }
 
+// SimulatedStateDiff is the state change a simulated transaction would make.
+// The diff of a reverted transaction is always empty, and the revert reason explains why.
+type SimulatedStateDiff struct {
+	StateDiff    *vm.StateDiff `json:"state_diff"`
+	Reverted     bool          `json:"reverted"`
+	RevertReason string        `json:"revert_reason,omitempty"`
+}
+
+func emptyStateDiff() *vm.StateDiff {
+	return &vm.StateDiff{
+		StorageDiffs:              []vm.StorageDiff{},
+		Nonces:                    []vm.Nonce{},
+		DeployedContracts:         []vm.DeployedContract{},
+		DeprecatedDeclaredClasses: []*felt.Felt{},
+		DeclaredClasses:           []vm.DeclaredClass{},
+		ReplacedClasses:           []vm.ReplacedClass{},
+	}
+}
+
+// executedState layers the changes of already simulated transactions over a state, so that the transactions
+// after them can be simulated without running them again.
+type executedState struct {
+	*blockchain.PendingState
+	stateDiff  *core.StateDiff
+	newClasses map[felt.Felt]core.Class
+}
+
+func newExecutedState(head core.StateReader) *executedState {
+	stateDiff := core.EmptyStateDiff()
+	newClasses := make(map[felt.Felt]core.Class)
+	return &executedState{
+		PendingState: blockchain.NewPendingState(stateDiff, newClasses, head),
+		stateDiff:    stateDiff,
+		newClasses:   newClasses,
+	}
+}
+
+// apply adds the changes of a simulated transaction, including the class it declares if any
+func (s *executedState) apply(txn *BroadcastedTransaction, stateDiff *vm.StateDiff, network *utils.Network) error {
+	if txn.Type == TxnDeclare {
+		coreTxn, class, _, err := adaptBroadcastedTransaction(txn, network)
+		if err != nil {
+			return err
+		}
+		if declare, ok := coreTxn.(*core.DeclareTransaction); ok && class != nil {
+			s.newClasses[*declare.ClassHash] = class
+		}
+	}
+	if stateDiff == nil {
+		return nil
+	}
+
+	for _, diff := range stateDiff.StorageDiffs {
+		storage, found := s.stateDiff.StorageDiffs[diff.Address]
+		if !found {
+			storage = make(map[felt.Felt]*felt.Felt, len(diff.StorageEntries))
+			s.stateDiff.StorageDiffs[diff.Address] = storage
+		}
+		for _, entry := range diff.StorageEntries {
+			storage[entry.Key] = entry.Value.Clone()
+		}
+	}
+	for _, nonce := range stateDiff.Nonces {
+		s.stateDiff.Nonces[nonce.ContractAddress] = nonce.Nonce.Clone()
+	}
+	for _, deployed := range stateDiff.DeployedContracts {
+		s.stateDiff.DeployedContracts[deployed.Address] = deployed.ClassHash.Clone()
+	}
+	for _, replaced := range stateDiff.ReplacedClasses {
+		s.stateDiff.ReplacedClasses[replaced.ContractAddress] = replaced.ClassHash.Clone()
+	}
+	for _, declared := range stateDiff.DeclaredClasses {
+		s.stateDiff.DeclaredV1Classes[declared.ClassHash] = declared.CompiledClassHash.Clone()
+	}
+	s.stateDiff.DeclaredV0Classes = append(s.stateDiff.DeclaredV0Classes, stateDiff.DeprecatedDeclaredClasses...)
+	return nil
+}
+
 type TracedBlockTransaction struct {
 	TraceRoot       *vm.TransactionTrace `json:"trace_root,omitempty"`
 	TransactionHash *felt.Felt           `json:"transaction_hash,omitempty"`
 }
+
+// BlockOverrides replaces parts of the block environment that transactions are simulated in.
+// Fields that are not set keep the value from the real block header.
+type BlockOverrides struct {
+	Timestamp        *uint64        `json:"timestamp,omitempty"`
+	L1GasPrice       *ResourcePrice `json:"l1_gas_price,omitempty"`
+	L1DataGasPrice   *ResourcePrice `json:"l1_data_gas_price,omitempty"`
+	SequencerAddress *felt.Felt     `json:"sequencer_address,omitempty"`
+}
+
+// apply returns a copy of the header with the overrides applied, the given header is not modified
+func (o *BlockOverrides) apply(header *core.Header) *core.Header {
+	overridden := *header
+	if o == nil {
+		return &overridden
+	}
+
+	if o.Timestamp != nil {
+		overridden.Timestamp = *o.Timestamp
+	}
+	if o.L1GasPrice != nil {
+		if o.L1GasPrice.InWei != nil {
+			overridden.GasPrice = o.L1GasPrice.InWei
+		}
+		if o.L1GasPrice.InFri != nil {
+			overridden.GasPriceSTRK = o.L1GasPrice.InFri
+		}
+	}
+	if o.L1DataGasPrice != nil {
+		dataGasPrice := core.GasPrice{}
+		if header.L1DataGasPrice != nil {
+			dataGasPrice = *header.L1DataGasPrice
+		}
+		if o.L1DataGasPrice.InWei != nil {
+			dataGasPrice.PriceInWei = o.L1DataGasPrice.InWei
+		}
+		if o.L1DataGasPrice.InFri != nil {
+			dataGasPrice.PriceInFri = o.L1DataGasPrice.InFri
+		}
+		overridden.L1DataGasPrice = &dataGasPrice
+	}
+	if o.SequencerAddress != nil {
+		overridden.SequencerAddress = o.SequencerAddress
+	}
+	return &overridden
+}
+
+// mayDependOnEachOther conservatively reports whether a transaction in txns may read the writes of another one.
+// Transactions from the same sender share a nonce, and declared classes or deployed accounts may be used by
+// the transactions that follow them.
+func mayDependOnEachOther(txns []BroadcastedTransaction) bool {
+	senders := felt.NewSet()
+	for i := range txns {
+		txn := &txns[i].Transaction
+
+		var sender *felt.Felt
+		switch txn.Type {
+		case TxnInvoke:
+			if sender = txn.SenderAddress; sender == nil {
+				sender = txn.ContractAddress
+			}
+		case TxnL1Handler:
+			sender = txn.ContractAddress
+		default:
+			return true
+		}
+
+		if sender == nil || !senders.Add(sender) {
+			return true
+		}
+	}
+	return false
+}
+
+// touchSameContract reports whether more than one of the simulated transactions touches the same contract,
+// either by calling into it or by changing its state. A transaction that only reads a contract may read the
+// writes of another transaction to it, so reads count as much as writes.
+func touchSameContract(simulated []SimulatedTransaction) bool {
+	touched := felt.NewSet()
+	for _, simulatedTxn := range simulated {
+		trace := simulatedTxn.TransactionTrace
+		addresses := trace.AllContracts()
+		if stateDiff := trace.StateDiff; stateDiff != nil {
+			for i := range stateDiff.StorageDiffs {
+				addresses = append(addresses, stateDiff.StorageDiffs[i].Address)
+			}
+			for i := range stateDiff.Nonces {
+				addresses = append(addresses, stateDiff.Nonces[i].ContractAddress)
+			}
+			for i := range stateDiff.DeployedContracts {
+				addresses = append(addresses, stateDiff.DeployedContracts[i].Address)
+			}
+			for i := range stateDiff.ReplacedClasses {
+				addresses = append(addresses, stateDiff.ReplacedClasses[i].ContractAddress)
+			}
+		}
+
+		// a transaction may touch the same contract more than once, only other transactions count
+		ownAddresses := felt.NewSet()
+		for i := range addresses {
+			if ownAddresses.Add(&addresses[i]) && !touched.Add(&addresses[i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
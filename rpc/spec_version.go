@@ -0,0 +1,17 @@
+package rpc
+
+// specVersion holds the behaviour of the call, estimate, simulate and trace handlers that differs between the
+// versions of the RPC spec they serve, so that supporting a new version means adding a value here instead of
+// threading another flag through the handlers.
+type specVersion struct {
+	version string
+	// useBlobData executes transactions with the data gas prices introduced by Starknet 0.13.1
+	useBlobData bool
+	// reportDataGas adds the data gas to fee estimates and the data availability to execution resources
+	reportDataGas bool
+}
+
+var (
+	specV0_6 = specVersion{version: "0.6.0"}
+	specV0_7 = specVersion{version: "0.7.0", useBlobData: true, reportDataGas: true}
+)
@@ -1,6 +1,9 @@
 package rpc
 
-import "github.com/NethermindEth/juno/core/felt"
+import (
+	"github.com/NethermindEth/juno/core"
+	"github.com/NethermindEth/juno/core/felt"
+)
 
 // https://github.com/starkware-libs/starknet-specs/blob/8016dd08ed7cd220168db16f24c8a6827ab88317/api/starknet_api_openrpc.json#L909
 type StateUpdate struct {
@@ -48,3 +51,38 @@ type DeclaredClass struct {
 	ClassHash         felt.Felt `json:"class_hash"`
 	CompiledClassHash felt.Felt `json:"compiled_class_hash"`
 }
+
+// StateOverride replaces parts of the state of a single contract for the duration of a call
+type StateOverride struct {
+	ContractAddress felt.Felt  `json:"contract_address"`
+	Storage         []Entry    `json:"storage,omitempty"`
+	Nonce           *felt.Felt `json:"nonce,omitempty"`
+	ClassHash       *felt.Felt `json:"class_hash,omitempty"`
+}
+
+func adaptStateOverrides(overrides []StateOverride) *core.StateDiff {
+	stateDiff := &core.StateDiff{
+		StorageDiffs:    make(map[felt.Felt]map[felt.Felt]*felt.Felt),
+		Nonces:          make(map[felt.Felt]*felt.Felt),
+		ReplacedClasses: make(map[felt.Felt]*felt.Felt),
+	}
+	for _, override := range overrides {
+		if len(override.Storage) > 0 {
+			storage, found := stateDiff.StorageDiffs[override.ContractAddress]
+			if !found {
+				storage = make(map[felt.Felt]*felt.Felt, len(override.Storage))
+				stateDiff.StorageDiffs[override.ContractAddress] = storage
+			}
+			for _, entry := range override.Storage {
+				storage[entry.Key] = entry.Value.Clone()
+			}
+		}
+		if override.Nonce != nil {
+			stateDiff.Nonces[override.ContractAddress] = override.Nonce
+		}
+		if override.ClassHash != nil {
+			stateDiff.ReplacedClasses[override.ContractAddress] = override.ClassHash
+		}
+	}
+	return stateDiff
+}
@@ -50,6 +50,31 @@ func adaptBlockTrace(block *BlockWithTxs, blockTrace *starknet.BlockTrace) ([]Tr
 	return traces, nil
 }
 
+// ContractKeys is a set of storage keys that belong to a single contract
+type ContractKeys struct {
+	ContractAddress felt.Felt   `json:"contract_address"`
+	StorageKeys     []felt.Felt `json:"storage_keys"`
+}
+
+func adaptStorageKeys(stateDiff *vm.StateDiff) []ContractKeys {
+	if stateDiff == nil {
+		return []ContractKeys{}
+	}
+
+	contractKeys := make([]ContractKeys, 0, len(stateDiff.StorageDiffs))
+	for _, storageDiff := range stateDiff.StorageDiffs {
+		keys := make([]felt.Felt, 0, len(storageDiff.StorageEntries))
+		for _, entry := range storageDiff.StorageEntries {
+			keys = append(keys, entry.Key)
+		}
+		contractKeys = append(contractKeys, ContractKeys{
+			ContractAddress: storageDiff.Address,
+			StorageKeys:     keys,
+		})
+	}
+	return contractKeys
+}
+
 func adaptFunctionInvocation(snFnInvocation *starknet.FunctionInvocation) *vm.FunctionInvocation {
 	if snFnInvocation == nil {
 		return nil
@@ -4,10 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
 
 	"github.com/NethermindEth/juno/adapters/sn2core"
 	"github.com/NethermindEth/juno/core"
 	"github.com/NethermindEth/juno/core/felt"
+	"github.com/NethermindEth/juno/jsonrpc"
 	"github.com/NethermindEth/juno/starknet"
 	"github.com/NethermindEth/juno/utils"
 	"github.com/ethereum/go-ethereum/common"
@@ -352,6 +354,54 @@ func (f FeeEstimate) MarshalJSON() ([]byte, error) {
 	}
 }
 
+// FeeEstimateRange brackets a fee estimate with the fees the transaction would cost if the gas prices moved
+// down or up by the configured band before the transaction is included.
+type FeeEstimateRange struct {
+	Low      FeeEstimate `json:"low"`
+	Expected FeeEstimate `json:"expected"`
+	High     FeeEstimate `json:"high"`
+}
+
+// FeeEstimateResult is the outcome of estimating a single transaction in a batch, either its fee estimate or
+// the error it failed with
+type FeeEstimateResult struct {
+	FeeEstimate *FeeEstimate   `json:"fee_estimate,omitempty"`
+	Error       *jsonrpc.Error `json:"error,omitempty"`
+}
+
+const hundredPercent = 100
+
+// rangeForBand brackets the estimate with the fees for gas prices moved down and up by band percent.
+func (f *FeeEstimate) rangeForBand(band uint64) FeeEstimateRange {
+	lowPercent := uint64(0)
+	if band < hundredPercent {
+		lowPercent = hundredPercent - band
+	}
+	return FeeEstimateRange{
+		Low:      f.withPricesScaled(lowPercent),
+		Expected: *f,
+		High:     f.withPricesScaled(hundredPercent + band),
+	}
+}
+
+// withPricesScaled returns a copy of the estimate with the gas prices scaled by the given percentage and the
+// overall fee recomputed from the consumed gas.
+func (f *FeeEstimate) withPricesScaled(percent uint64) FeeEstimate {
+	scale := func(price *felt.Felt) *felt.Felt {
+		var priceBig big.Int
+		price.BigInt(&priceBig)
+		priceBig.Mul(&priceBig, new(big.Int).SetUint64(percent))
+		return new(felt.Felt).SetBigInt(priceBig.Quo(&priceBig, big.NewInt(hundredPercent)))
+	}
+
+	scaled := *f
+	scaled.GasPrice = scale(f.GasPrice)
+	scaled.DataGasPrice = scale(f.DataGasPrice)
+	scaled.OverallFee = new(felt.Felt).Mul(scaled.GasConsumed, scaled.GasPrice)
+	scaled.OverallFee.Add(scaled.OverallFee, new(felt.Felt).Mul(scaled.DataGasConsumed, scaled.DataGasPrice))
+	return scaled
+}
+
 func adaptBroadcastedTransaction(broadcastedTxn *BroadcastedTransaction,
 	network *utils.Network,
 ) (core.Transaction, core.Class, *felt.Felt, error) {
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"encoding"
+	"errors"
 	"fmt"
 	"math/big"
 	"strings"
@@ -161,6 +162,21 @@ func (n *Network) UnmarshalText(text []byte) error {
 	return n.Set(string(text))
 }
 
+// maxShortStringLen is the longest ASCII string that still fits in a single felt.
+const maxShortStringLen = 31
+
+// ValidateL2ChainID checks that the L2 chain id is a non-empty short string, so that
+// L2ChainIDFelt yields the same felt that is used when hashing transactions.
+func ValidateL2ChainID(chainID string) error {
+	if chainID == "" {
+		return errors.New("l2 chain id must not be empty")
+	}
+	if len(chainID) > maxShortStringLen {
+		return fmt.Errorf("l2 chain id %q is longer than %d bytes and does not fit in a felt", chainID, maxShortStringLen)
+	}
+	return nil
+}
+
 func (n *Network) L2ChainIDFelt() *felt.Felt {
 	return new(felt.Felt).SetBytes([]byte(n.L2ChainID))
 }
@@ -161,3 +161,13 @@ func TestCoreContractAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateL2ChainID(t *testing.T) {
+	for n := range networkStrings {
+		require.NoError(t, utils.ValidateL2ChainID(n.L2ChainID), n.String())
+	}
+	require.NoError(t, utils.ValidateL2ChainID("SN_AWESOME"))
+	require.Error(t, utils.ValidateL2ChainID(""))
+	require.NoError(t, utils.ValidateL2ChainID(strings.Repeat("A", 31)))
+	require.Error(t, utils.ValidateL2ChainID(strings.Repeat("A", 32)))
+}
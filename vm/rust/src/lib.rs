@@ -6,7 +6,7 @@ extern crate lazy_static;
 
 use crate::juno_state_reader::{ptr_to_felt, JunoStateReader};
 use std::{
-    collections::HashMap, ffi::{c_char, c_longlong, c_uchar, c_ulonglong, c_void, CStr, CString}, num::NonZeroU128, slice, sync::Arc
+    collections::{BTreeMap, HashMap}, ffi::{c_char, c_longlong, c_uchar, c_ulonglong, c_void, CStr, CString}, num::NonZeroU128, slice, sync::Arc
 };
 
 use blockifier::{
@@ -18,7 +18,7 @@ use blockifier::{
             ContractConstructorExecutionFailed,
             ExecutionError,
             ValidateTransactionError,
-        }, objects::{DeprecatedTransactionInfo, HasRelatedFeeType, TransactionInfo}, transaction_execution::Transaction, transactions::ExecutableTransaction
+        }, objects::{CommonAccountFields, CurrentTransactionInfo, DeprecatedTransactionInfo, HasRelatedFeeType, TransactionInfo}, transaction_execution::Transaction, transactions::ExecutableTransaction
     }, versioned_constants::VersionedConstants
 };
 use cairo_vm::vm::runners::cairo_runner::ExecutionResources;
@@ -26,9 +26,10 @@ use juno_state_reader::{class_info_from_json_str, felt_to_byte_array};
 use serde::Deserialize;
 use starknet_api::{block::BlockHash, core::PatriciaKey, transaction::{Calldata, Transaction as StarknetApiTransaction, TransactionHash}};
 use starknet_api::{
+    data_availability::DataAvailabilityMode,
     deprecated_contract_class::EntryPointType,
     hash::StarkFelt,
-    transaction::Fee,
+    transaction::{AccountDeploymentData, Fee, PaymasterData, Resource, ResourceBounds, ResourceBoundsMapping, Tip},
 };
 use starknet_api::{
     core::{ChainId, ClassHash, ContractAddress, EntryPointSelector},
@@ -42,6 +43,7 @@ extern "C" {
     fn JunoAppendResponse(reader_handle: usize, ptr: *const c_uchar);
     fn JunoAppendActualFee(reader_handle: usize, ptr: *const c_uchar);
     fn JunoAppendDataGasConsumed(reader_handle: usize, ptr: *const c_uchar);
+    fn JunoSetCallUsage(reader_handle: usize, steps: c_ulonglong, gas_consumed: c_ulonglong);
 }
 
 #[repr(C)]
@@ -50,8 +52,10 @@ pub struct CallInfo {
     pub contract_address: [c_uchar; 32],
     pub class_hash: [c_uchar; 32],
     pub entry_point_selector: [c_uchar; 32],
+    pub caller_address: [c_uchar; 32],
     pub calldata: *const *const c_uchar,
-    pub len_calldata: usize
+    pub len_calldata: usize,
+    pub gas_price_in_tx_info: c_uchar,
 }
 
 #[repr(C)]
@@ -88,6 +92,7 @@ pub extern "C" fn cairoVMCall(
         Some(ClassHash(StarkFelt::new(call_info.class_hash).unwrap()))
     };
     let entry_point_selector_felt = StarkFelt::new(call_info.entry_point_selector).unwrap();
+    let caller_address_felt = StarkFelt::new(call_info.caller_address).unwrap();
     let chain_id_str = unsafe { CStr::from_ptr(chain_id) }.to_str().unwrap();
 
     let mut calldata_vec: Vec<StarkFelt> = Vec::with_capacity(call_info.len_calldata);
@@ -107,7 +112,7 @@ pub extern "C" fn cairoVMCall(
         call_type: CallType::Call,
         class_hash: class_hash,
         code_address: None,
-        caller_address: ContractAddress::default(),
+        caller_address: caller_address_felt.try_into().unwrap(),
         initial_gas: get_versioned_constants(block_info.version).gas_cost("initial_gas_cost"),
     };
 
@@ -116,7 +121,7 @@ pub extern "C" fn cairoVMCall(
     let context = EntryPointExecutionContext::new_invoke(
         Arc::new(TransactionContext {
             block_context: build_block_context(&mut state, &block_info, chain_id_str, Some(max_steps)),
-            tx_info: TransactionInfo::Deprecated(DeprecatedTransactionInfo::default()),
+            tx_info: call_tx_info(&call_info, &block_info),
         }),
         false,
     );
@@ -132,10 +137,39 @@ pub extern "C" fn cairoVMCall(
                     JunoAppendResponse(reader_handle, felt_to_byte_array(&data).as_ptr());
                 };
             }
+            unsafe {
+                JunoSetCallUsage(
+                    reader_handle,
+                    resources.n_steps as c_ulonglong,
+                    t.execution.gas_consumed as c_ulonglong,
+                );
+            };
         }
     }
 }
 
+// call_tx_info returns the tx_info that a call sees. Calls run outside of any transaction and see an empty one,
+// unless the caller asked for the L1 gas price of the block to be exposed as the L1 gas bound of a V3 transaction.
+fn call_tx_info(call_info: &CallInfo, block_info: &BlockInfo) -> TransactionInfo {
+    if call_info.gas_price_in_tx_info != 1 {
+        return TransactionInfo::Deprecated(DeprecatedTransactionInfo::default());
+    }
+
+    let gas_price_fri = felt_to_u128(StarkFelt::new(block_info.gas_price_fri).unwrap());
+    TransactionInfo::Current(CurrentTransactionInfo {
+        common_fields: CommonAccountFields::default(),
+        resource_bounds: ResourceBoundsMapping(BTreeMap::from([
+            (Resource::L1Gas, ResourceBounds { max_amount: 0, max_price_per_unit: gas_price_fri }),
+            (Resource::L2Gas, ResourceBounds { max_amount: 0, max_price_per_unit: 0 }),
+        ])),
+        tip: Tip(0),
+        nonce_data_availability_mode: DataAvailabilityMode::L1,
+        fee_data_availability_mode: DataAvailabilityMode::L1,
+        paymaster_data: PaymasterData::default(),
+        account_deployment_data: AccountDeploymentData::default(),
+    })
+}
+
 #[derive(Deserialize)]
 pub struct TxnAndQueryBit {
     pub txn: StarknetApiTransaction,
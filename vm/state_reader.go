@@ -20,6 +20,9 @@ func JunoFree(ptr unsafe.Pointer) {
 //export JunoStateGetStorageAt
 func JunoStateGetStorageAt(readerHandle C.uintptr_t, contractAddress, storageLocation unsafe.Pointer) unsafe.Pointer {
 	context := unwrapContext(readerHandle)
+	if context.cancelled() {
+		return nil
+	}
 
 	contractAddressFelt := makeFeltFromPtr(contractAddress)
 	storageLocationFelt := makeFeltFromPtr(storageLocation)
@@ -38,6 +41,9 @@ func JunoStateGetStorageAt(readerHandle C.uintptr_t, contractAddress, storageLoc
 //export JunoStateGetNonceAt
 func JunoStateGetNonceAt(readerHandle C.uintptr_t, contractAddress unsafe.Pointer) unsafe.Pointer {
 	context := unwrapContext(readerHandle)
+	if context.cancelled() {
+		return nil
+	}
 
 	contractAddressFelt := makeFeltFromPtr(contractAddress)
 	val, err := context.state.ContractNonce(contractAddressFelt)
@@ -55,6 +61,9 @@ func JunoStateGetNonceAt(readerHandle C.uintptr_t, contractAddress unsafe.Pointe
 //export JunoStateGetClassHashAt
 func JunoStateGetClassHashAt(readerHandle C.uintptr_t, contractAddress unsafe.Pointer) unsafe.Pointer {
 	context := unwrapContext(readerHandle)
+	if context.cancelled() {
+		return nil
+	}
 
 	contractAddressFelt := makeFeltFromPtr(contractAddress)
 	val, err := context.state.ContractClassHash(contractAddressFelt)
@@ -72,6 +81,9 @@ func JunoStateGetClassHashAt(readerHandle C.uintptr_t, contractAddress unsafe.Po
 //export JunoStateGetCompiledClass
 func JunoStateGetCompiledClass(readerHandle C.uintptr_t, classHash unsafe.Pointer) unsafe.Pointer {
 	context := unwrapContext(readerHandle)
+	if context.cancelled() {
+		return nil
+	}
 
 	classHashFelt := makeFeltFromPtr(classHash)
 	val, err := context.state.Class(classHashFelt)
@@ -163,6 +163,16 @@ func (t *TransactionTrace) AllMessages() []OrderedL2toL1Message {
 	return messages
 }
 
+// AllContracts returns the addresses of the contracts called during the transaction, including nested calls.
+// An address is returned once for every call into it.
+func (t *TransactionTrace) AllContracts() []felt.Felt {
+	contracts := make([]felt.Felt, 0)
+	for _, invocation := range t.allInvocations() {
+		contracts = append(contracts, invocation.allContracts()...)
+	}
+	return contracts
+}
+
 type FunctionInvocation struct {
 	ContractAddress    felt.Felt              `json:"contract_address"`
 	EntryPointSelector *felt.Felt             `json:"entry_point_selector,omitempty"`
@@ -194,6 +204,14 @@ func (invocation *FunctionInvocation) allMessages() []OrderedL2toL1Message {
 	return append(messages, invocation.Messages...)
 }
 
+func (invocation *FunctionInvocation) allContracts() []felt.Felt {
+	contracts := []felt.Felt{invocation.ContractAddress}
+	for i := range invocation.Calls {
+		contracts = append(contracts, invocation.Calls[i].allContracts()...)
+	}
+	return contracts
+}
+
 type ExecuteInvocation struct {
 	RevertReason        string `json:"revert_reason"`
 	*FunctionInvocation `json:",omitempty"`
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/NethermindEth/juno/core/felt"
 	"github.com/NethermindEth/juno/vm"
 	"github.com/stretchr/testify/require"
 )
@@ -138,6 +139,38 @@ func TestAllMessages(t *testing.T) {
 	}
 }
 
+func TestAllContracts(t *testing.T) {
+	numContracts := uint64(5)
+	contracts := make([]felt.Felt, 0, numContracts)
+	for i := uint64(0); i < numContracts; i++ {
+		contracts = append(contracts, *new(felt.Felt).SetUint64(i))
+	}
+	trace := &vm.TransactionTrace{
+		ValidateInvocation: &vm.FunctionInvocation{
+			ContractAddress: contracts[0],
+		},
+		ExecuteInvocation: &vm.ExecuteInvocation{
+			FunctionInvocation: &vm.FunctionInvocation{
+				ContractAddress: contracts[0],
+				Calls: []vm.FunctionInvocation{
+					{
+						ContractAddress: contracts[1],
+						Calls:           []vm.FunctionInvocation{{ContractAddress: contracts[2]}},
+					},
+					{ContractAddress: contracts[3]},
+				},
+			},
+		},
+		FeeTransferInvocation: &vm.FunctionInvocation{
+			ContractAddress: contracts[4],
+		},
+	}
+
+	want := append([]felt.Felt{contracts[0]}, contracts...)
+	require.ElementsMatch(t, want, trace.AllContracts())
+	require.Empty(t, (&vm.TransactionTrace{}).AllContracts())
+}
+
 func TestTotalExecutionResources(t *testing.T) {
 	resources := &vm.ExecutionResources{
 		ComputationResources: vm.ComputationResources{
@@ -11,8 +11,10 @@ typedef struct CallInfo {
 	unsigned char contract_address[FELT_SIZE];
 	unsigned char class_hash[FELT_SIZE];
 	unsigned char entry_point_selector[FELT_SIZE];
+	unsigned char caller_address[FELT_SIZE];
 	unsigned char** calldata;
 	size_t len_calldata;
+	unsigned char gas_price_in_tx_info;
 } CallInfo;
 
 typedef struct BlockInfo {
@@ -41,6 +43,7 @@ extern void cairoVMExecute(char* txns_json, char* classes_json, char* paid_fees_
 import "C"
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -55,12 +58,19 @@ import (
 
 //go:generate mockgen -destination=../mocks/mock_vm.go -package=mocks github.com/NethermindEth/juno/vm VM
 type VM interface {
-	Call(callInfo *CallInfo, blockInfo *BlockInfo, state core.StateReader, network *utils.Network, maxSteps uint64, useBlobData bool) ([]*felt.Felt, error) //nolint:lll
+	Call(ctx context.Context, callInfo *CallInfo, blockInfo *BlockInfo, state core.StateReader, network *utils.Network, maxSteps uint64, useBlobData bool) (CallResult, error) //nolint:lll
 	Execute(txns []core.Transaction, declaredClasses []core.Class, paidFeesOnL1 []*felt.Felt, blockInfo *BlockInfo,
 		state core.StateReader, network *utils.Network, skipChargeFee, skipValidate, errOnRevert, useBlobData bool,
 	) ([]*felt.Felt, []*felt.Felt, []TransactionTrace, error)
 }
 
+// CallResult is the output of a Call along with the resources the call used
+type CallResult struct {
+	Result      []*felt.Felt
+	Steps       uint64
+	GasConsumed uint64
+}
+
 type vm struct {
 	log utils.SimpleLogger
 }
@@ -73,6 +83,8 @@ func New(log utils.SimpleLogger) VM {
 
 // callContext manages the context that a Call instance executes on
 type callContext struct {
+	// ctx cancels a Call, the VM fails at its next state read once ctx is done. Nil for Execute.
+	ctx context.Context
 	// state that the call is running on
 	state core.StateReader
 	log   utils.SimpleLogger
@@ -82,6 +94,9 @@ type callContext struct {
 	errTxnIndex int64
 	// response from the executed Cairo function
 	response []*felt.Felt
+	// steps and gas used by the executed Cairo function
+	steps       uint64
+	gasConsumed uint64
 	// fee amount taken per transaction during VM execution
 	actualFees      []*felt.Felt
 	traces          []json.RawMessage
@@ -97,6 +112,11 @@ func unwrapContext(readerHandle C.uintptr_t) *callContext {
 	return context
 }
 
+// cancelled reports whether the call running on the context was cancelled
+func (c *callContext) cancelled() bool {
+	return c.ctx != nil && c.ctx.Err() != nil
+}
+
 //export JunoReportError
 func JunoReportError(readerHandle C.uintptr_t, txnIndex C.long, str *C.char) {
 	context := unwrapContext(readerHandle)
@@ -129,6 +149,13 @@ func JunoAppendDataGasConsumed(readerHandle C.uintptr_t, ptr unsafe.Pointer) {
 	context.dataGasConsumed = append(context.dataGasConsumed, makeFeltFromPtr(ptr))
 }
 
+//export JunoSetCallUsage
+func JunoSetCallUsage(readerHandle C.uintptr_t, steps, gasConsumed C.ulonglong) {
+	context := unwrapContext(readerHandle)
+	context.steps = uint64(steps)
+	context.gasConsumed = uint64(gasConsumed)
+}
+
 func makeFeltFromPtr(ptr unsafe.Pointer) *felt.Felt {
 	return new(felt.Felt).SetBytes(C.GoBytes(ptr, felt.Bytes))
 }
@@ -144,6 +171,9 @@ type CallInfo struct {
 	ClassHash       *felt.Felt
 	Selector        *felt.Felt
 	Calldata        []felt.Felt
+	CallerAddress   *felt.Felt // address seen by get_caller_address(), zero if nil
+	// expose the L1 gas price in fri of the block as the L1 gas bound of a V3 tx_info, the tx_info is empty otherwise
+	GasPriceInTxInfo bool
 }
 
 type BlockInfo struct {
@@ -170,6 +200,10 @@ func makeCCallInfo(callInfo *CallInfo) (C.CallInfo, runtime.Pinner) {
 	copyFeltIntoCArray(callInfo.ContractAddress, &cCallInfo.contract_address[0])
 	copyFeltIntoCArray(callInfo.ClassHash, &cCallInfo.class_hash[0])
 	copyFeltIntoCArray(callInfo.Selector, &cCallInfo.entry_point_selector[0])
+	copyFeltIntoCArray(callInfo.CallerAddress, &cCallInfo.caller_address[0])
+	if callInfo.GasPriceInTxInfo {
+		cCallInfo.gas_price_in_tx_info = 1
+	}
 
 	if len(callInfo.Calldata) > 0 {
 		// prepare calldata in Go heap.
@@ -209,10 +243,17 @@ func makeCBlockInfo(blockInfo *BlockInfo, useBlobData bool) C.BlockInfo {
 	return cBlockInfo
 }
 
-func (v *vm) Call(callInfo *CallInfo, blockInfo *BlockInfo, state core.StateReader,
+// Call runs the given function call. Cancelling ctx makes the VM fail at its next state read, the work between
+// two reads is bounded by maxSteps only.
+func (v *vm) Call(ctx context.Context, callInfo *CallInfo, blockInfo *BlockInfo, state core.StateReader,
 	network *utils.Network, maxSteps uint64, useBlobData bool,
-) ([]*felt.Felt, error) {
+) (CallResult, error) {
+	if err := ctx.Err(); err != nil {
+		return CallResult{}, err
+	}
+
 	context := &callContext{
+		ctx:      ctx,
 		state:    state,
 		response: []*felt.Felt{},
 		log:      v.log,
@@ -234,10 +275,17 @@ func (v *vm) Call(callInfo *CallInfo, blockInfo *BlockInfo, state core.StateRead
 	C.free(unsafe.Pointer(chainID))
 	C.free(unsafe.Pointer(cBlockInfo.version))
 
+	if err := ctx.Err(); err != nil {
+		return CallResult{}, err
+	}
 	if context.err != "" {
-		return nil, errors.New(context.err)
+		return CallResult{}, errors.New(context.err)
 	}
-	return context.response, nil
+	return CallResult{
+		Result:      context.response,
+		Steps:       context.steps,
+		GasConsumed: context.gasConsumed,
+	}, nil
 }
 
 // Execute executes a given transaction set and returns the gas spent per transaction
@@ -49,13 +49,13 @@ func TestV0Call(t *testing.T) {
 
 	entryPoint := utils.HexToFelt(t, "0x39e11d48192e4333233c7eb19d10ad67c362bb28580c604d67884c85da39695")
 
-	ret, err := New(nil).Call(&CallInfo{
+	ret, err := New(nil).Call(context.Background(), &CallInfo{
 		ContractAddress: contractAddr,
 		ClassHash:       classHash,
 		Selector:        entryPoint,
 	}, &BlockInfo{Header: &core.Header{}}, testState, &utils.Mainnet, 1_000_000, true)
 	require.NoError(t, err)
-	assert.Equal(t, []*felt.Felt{&felt.Zero}, ret)
+	assert.Equal(t, []*felt.Felt{&felt.Zero}, ret.Result)
 
 	require.NoError(t, testState.Update(1, &core.StateUpdate{
 		OldRoot: utils.HexToFelt(t, "0x3d452fbb3c3a32fe85b1a3fbbcdec316d5fc940cefc028ee808ad25a15991c8"),
@@ -69,13 +69,14 @@ func TestV0Call(t *testing.T) {
 		},
 	}, nil))
 
-	ret, err = New(nil).Call(&CallInfo{
+	ret, err = New(nil).Call(context.Background(), &CallInfo{
 		ContractAddress: contractAddr,
 		ClassHash:       classHash,
 		Selector:        entryPoint,
 	}, &BlockInfo{Header: &core.Header{Number: 1}}, testState, &utils.Mainnet, 1_000_000, true)
 	require.NoError(t, err)
-	assert.Equal(t, []*felt.Felt{new(felt.Felt).SetUint64(1337)}, ret)
+	assert.Equal(t, []*felt.Felt{new(felt.Felt).SetUint64(1337)}, ret.Result)
+	assert.NotZero(t, ret.Steps)
 }
 
 func TestV1Call(t *testing.T) {
@@ -115,7 +116,7 @@ func TestV1Call(t *testing.T) {
 	// test_storage_read
 	entryPoint := utils.HexToFelt(t, "0x5df99ae77df976b4f0e5cf28c7dcfe09bd6e81aab787b19ac0c08e03d928cf")
 	storageLocation := utils.HexToFelt(t, "0x44")
-	ret, err := New(log).Call(&CallInfo{
+	ret, err := New(log).Call(context.Background(), &CallInfo{
 		ContractAddress: contractAddr,
 		Selector:        entryPoint,
 		Calldata: []felt.Felt{
@@ -123,7 +124,7 @@ func TestV1Call(t *testing.T) {
 		},
 	}, &BlockInfo{Header: &core.Header{}}, testState, &utils.Goerli, 1_000_000, true)
 	require.NoError(t, err)
-	assert.Equal(t, []*felt.Felt{&felt.Zero}, ret)
+	assert.Equal(t, []*felt.Felt{&felt.Zero}, ret.Result)
 
 	require.NoError(t, testState.Update(1, &core.StateUpdate{
 		OldRoot: utils.HexToFelt(t, "0x2650cef46c190ec6bb7dc21a5a36781132e7c883b27175e625031149d4f1a84"),
@@ -137,7 +138,7 @@ func TestV1Call(t *testing.T) {
 		},
 	}, nil))
 
-	ret, err = New(log).Call(&CallInfo{
+	ret, err = New(log).Call(context.Background(), &CallInfo{
 		ContractAddress: contractAddr,
 		Selector:        entryPoint,
 		Calldata: []felt.Felt{
@@ -145,7 +146,9 @@ func TestV1Call(t *testing.T) {
 		},
 	}, &BlockInfo{Header: &core.Header{Number: 1}}, testState, &utils.Goerli, 1_000_000, true)
 	require.NoError(t, err)
-	assert.Equal(t, []*felt.Felt{new(felt.Felt).SetUint64(37)}, ret)
+	assert.Equal(t, []*felt.Felt{new(felt.Felt).SetUint64(37)}, ret.Result)
+	assert.NotZero(t, ret.Steps)
+	assert.NotZero(t, ret.GasConsumed)
 }
 
 func TestCall_MaxSteps(t *testing.T) {
@@ -181,7 +184,7 @@ func TestCall_MaxSteps(t *testing.T) {
 
 	entryPoint := utils.HexToFelt(t, "0x39e11d48192e4333233c7eb19d10ad67c362bb28580c604d67884c85da39695")
 
-	_, err = New(nil).Call(&CallInfo{
+	_, err = New(nil).Call(context.Background(), &CallInfo{
 		ContractAddress: contractAddr,
 		ClassHash:       classHash,
 		Selector:        entryPoint,